@@ -2,10 +2,12 @@ module ocelot/ci-runner
 
 go 1.21.6
 
-require github.com/mattn/go-shellwords v1.0.12
+require (
+	github.com/mattn/go-shellwords v1.0.12
+	github.com/spf13/cobra v1.8.0
+)
 
 require (
 	github.com/inconshreveable/mousetrap v1.1.0 // indirect
-	github.com/spf13/cobra v1.8.0 // indirect
 	github.com/spf13/pflag v1.0.5 // indirect
 )
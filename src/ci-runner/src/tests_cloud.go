@@ -23,6 +23,7 @@ var backendBusinessInternalDir = backendDir + "/business/internal"
 var backendToolsDir = backendDir + "/config"
 var backendSecurityInternalDir = backendDir + "/security/internal"
 var hubDir = srcDir + "/hub"
+var ocelotctlDir = srcDir + "/ocelotctl"
 
 const BackendModeProduction = "production"
 const BackendModeDependenciesMocked = "dependencies-mocked"
@@ -50,6 +51,8 @@ func testBackendCore() {
 	ExecuteInDir(backendBusinessInternalDir, "go test -v -count=1 ./...")
 	ExecuteInDir(backendToolsDir, "go test -v -count=1 ./...")
 	ExecuteInDir(backendSecurityInternalDir, "go test -v -count=1 ./...")
+	ExecuteInDir(ocelotctlDir, "go vet ./...")
+	ExecuteInDir(ocelotctlDir, "go test -v -count=1 ./...")
 }
 
 func TestBackendComponent(fast bool) {
@@ -0,0 +1,197 @@
+package internal
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+type AccessLogEntry struct {
+	Timestamp time.Time `json:"timestamp"`
+	Host      string    `json:"host"`
+	Stack     string    `json:"stack"`
+	Method    string    `json:"method"`
+	Path      string    `json:"path"`
+	Status    int       `json:"status"`
+	Bytes     int64     `json:"bytes"`
+	LatencyMs float64   `json:"latencyMs"`
+	ClientIp  string    `json:"clientIp"`
+	User      string    `json:"user,omitempty"`
+}
+
+// AccessLog records the requests passing the app proxy as JSON lines in a file, which is rotated when it
+// exceeds its maximum size. The most recent requests of each stack are additionally kept in memory.
+type AccessLog struct {
+	mu          sync.Mutex
+	filePath    string
+	file        *os.File
+	fileSize    int64
+	stdout      io.Writer
+	recent      map[string][]AccessLogEntry
+	maxFileSize int64
+	maxBackups  int
+}
+
+// ProvideAccessLog opens the log file in append mode. If stdout is not nil, entries are also written to it.
+func ProvideAccessLog(filePath string, stdout io.Writer) *AccessLog {
+	accessLog := &AccessLog{
+		filePath:    filePath,
+		stdout:      stdout,
+		recent:      make(map[string][]AccessLogEntry),
+		maxFileSize: AccessLogMaxFileSize,
+		maxBackups:  AccessLogMaxBackups,
+	}
+	if err := accessLog.openFile(); err != nil {
+		Logger.Fatal("failed to open access log %s: %v", filePath, err)
+	}
+	return accessLog
+}
+
+func (a *AccessLog) Record(entry AccessLogEntry) {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		Logger.Error("failed to serialize access log entry: %v", err)
+		return
+	}
+	line = append(line, '\n')
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.addRecentEntry(entry)
+	if a.stdout != nil {
+		a.stdout.Write(line)
+	}
+	if a.fileSize+int64(len(line)) > a.maxFileSize && a.fileSize > 0 {
+		if err = a.rotate(); err != nil {
+			Logger.Error("failed to rotate access log: %v", err)
+		}
+	}
+	if a.file == nil {
+		return
+	}
+	written, err := a.file.Write(line)
+	a.fileSize += int64(written)
+	if err != nil {
+		Logger.Error("failed to write access log: %v", err)
+	}
+}
+
+// GetRecentEntries returns the latest requests to a stack, newest first.
+func (a *AccessLog) GetRecentEntries(stackName string) []AccessLogEntry {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	entries := a.recent[stackName]
+	result := make([]AccessLogEntry, 0, len(entries))
+	for i := len(entries) - 1; i >= 0; i-- {
+		result = append(result, entries[i])
+	}
+	return result
+}
+
+func (a *AccessLog) Close() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.file == nil {
+		return nil
+	}
+	err := a.file.Close()
+	a.file = nil
+	return err
+}
+
+func (a *AccessLog) addRecentEntry(entry AccessLogEntry) {
+	entries := append(a.recent[entry.Stack], entry)
+	if len(entries) > AccessLogRecentEntriesPerStack {
+		entries = entries[len(entries)-AccessLogRecentEntriesPerStack:]
+	}
+	a.recent[entry.Stack] = entries
+}
+
+func (a *AccessLog) openFile() error {
+	if err := os.MkdirAll(filepath.Dir(a.filePath), 0700); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(a.filePath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	a.file = file
+	a.fileSize = info.Size()
+	return nil
+}
+
+// rotate renames "access.log" to "access.log.1", "access.log.1" to "access.log.2" and so on. The oldest
+// backup is overwritten.
+func (a *AccessLog) rotate() error {
+	if a.file != nil {
+		a.file.Close()
+		a.file = nil
+	}
+	for i := a.maxBackups - 1; i >= 1; i-- {
+		os.Rename(fmt.Sprintf("%s.%d", a.filePath, i), fmt.Sprintf("%s.%d", a.filePath, i+1))
+	}
+	if a.maxBackups > 0 {
+		if err := os.Rename(a.filePath, a.filePath+".1"); err != nil {
+			return err
+		}
+	} else if err := os.Remove(a.filePath); err != nil {
+		return err
+	}
+	return a.openFile()
+}
+
+// responseRecorder captures status and size of a response for the access log. WebSockets and streamed
+// responses require the flusher and hijacker of the underlying response writer.
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int64
+}
+
+func newResponseRecorder(w http.ResponseWriter) *responseRecorder {
+	return &responseRecorder{ResponseWriter: w}
+}
+
+func (r *responseRecorder) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *responseRecorder) Write(data []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	written, err := r.ResponseWriter.Write(data)
+	r.bytes += int64(written)
+	return written, err
+}
+
+func (r *responseRecorder) Flush() {
+	http.NewResponseController(r.ResponseWriter).Flush()
+}
+
+func (r *responseRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	connection, buffer, err := http.NewResponseController(r.ResponseWriter).Hijack()
+	if err == nil && r.status == 0 {
+		r.status = http.StatusSwitchingProtocols
+	}
+	return connection, buffer, err
+}
+
+func (r *responseRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
@@ -0,0 +1,75 @@
+package internal
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/ocelot-cloud/shared/assert"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func createAccessLog(t *testing.T) *AccessLog {
+	accessLog := ProvideAccessLog(filepath.Join(t.TempDir(), "logs", AccessLogFileName), nil)
+	t.Cleanup(func() { accessLog.Close() })
+	return accessLog
+}
+
+func TestAccessLogIsWrittenAsJsonLines(t *testing.T) {
+	stdout := &bytes.Buffer{}
+	accessLogPath := filepath.Join(t.TempDir(), AccessLogFileName)
+	accessLog := ProvideAccessLog(accessLogPath, stdout)
+	defer accessLog.Close()
+
+	entry := AccessLogEntry{Timestamp: time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC), Host: "gitea.localhost", Stack: "gitea", Method: "GET", Path: "/", Status: 200, Bytes: 42, LatencyMs: 1.5, ClientIp: "10.0.0.1"}
+	accessLog.Record(entry)
+
+	content, err := os.ReadFile(accessLogPath)
+	assert.Nil(t, err)
+	assert.Equal(t, stdout.String(), string(content))
+	var loggedEntry AccessLogEntry
+	assert.Nil(t, json.Unmarshal(content, &loggedEntry))
+	assert.Equal(t, entry, loggedEntry)
+}
+
+func TestAccessLogIsRotated(t *testing.T) {
+	accessLogPath := filepath.Join(t.TempDir(), AccessLogFileName)
+	accessLog := ProvideAccessLog(accessLogPath, nil)
+	defer accessLog.Close()
+	accessLog.maxFileSize = 300
+	accessLog.maxBackups = 2
+
+	for i := 0; i < 10; i++ {
+		accessLog.Record(AccessLogEntry{Stack: "gitea", Path: fmt.Sprintf("/page-%d", i)})
+	}
+
+	for _, path := range []string{accessLogPath, accessLogPath + ".1", accessLogPath + ".2"} {
+		info, err := os.Stat(path)
+		assert.Nil(t, err)
+		assert.True(t, info.Size() <= 300)
+	}
+	_, err := os.Stat(accessLogPath + ".3")
+	assert.True(t, os.IsNotExist(err))
+
+	content, err := os.ReadFile(accessLogPath)
+	assert.Nil(t, err)
+	assert.True(t, strings.Contains(string(content), "/page-9"))
+}
+
+func TestRecentEntriesAreLimitedPerStack(t *testing.T) {
+	accessLog := createAccessLog(t)
+	for i := 0; i < AccessLogRecentEntriesPerStack+5; i++ {
+		accessLog.Record(AccessLogEntry{Stack: "gitea", Path: fmt.Sprintf("/page-%d", i)})
+	}
+	accessLog.Record(AccessLogEntry{Stack: "wiki", Path: "/"})
+
+	entries := accessLog.GetRecentEntries("gitea")
+	assert.Equal(t, AccessLogRecentEntriesPerStack, len(entries))
+	assert.Equal(t, fmt.Sprintf("/page-%d", AccessLogRecentEntriesPerStack+4), entries[0].Path)
+	assert.Equal(t, "/page-5", entries[len(entries)-1].Path)
+	assert.Equal(t, 1, len(accessLog.GetRecentEntries("wiki")))
+	assert.Equal(t, 0, len(accessLog.GetRecentEntries("not-existing-stack")))
+}
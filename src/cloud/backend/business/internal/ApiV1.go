@@ -0,0 +1,633 @@
+package internal
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/gorilla/mux"
+	"io"
+	"net/http"
+	"ocelot/backend/config"
+	"ocelot/backend/security"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const apiV1Prefix = "/api/v1"
+
+const (
+	errorCodeNotFound          = "not_found"
+	errorCodeForbidden         = "forbidden"
+	errorCodeMethodNotAllowed  = "method_not_allowed"
+	errorCodeInvalidRequest    = "invalid_request"
+	errorCodeInvalidState      = "invalid_state"
+	errorCodeDockerUnavailable = "docker_unavailable"
+	errorCodeComposeFailed     = "compose_failed"
+	errorCodeDownloadFailed    = "download_failed"
+	errorCodeTimeout           = "timeout"
+	errorCodeOperationFailed   = "operation_failed"
+)
+
+// apiRoute describes an endpoint of the versioned API. The routes are registered and documented in the OpenAPI
+// document from the same description, so that the document can not get out of sync with the API.
+type apiRoute struct {
+	method      string
+	path        string
+	operationId string
+	summary     string
+	status      int
+	queryParams []apiQueryParameter
+	request     any
+	response    any
+	// contentType is the type of the response if it is not JSON, its schema is then the one of the response.
+	contentType string
+	isPublic    bool
+	handler     http.HandlerFunc
+}
+
+type apiQueryParameter struct {
+	name        string
+	kind        string
+	description string
+}
+
+// stackHandlerFunc handles requests to the path of a stack, which is known to exist.
+type stackHandlerFunc func(w http.ResponseWriter, r *http.Request, stackName string)
+
+func getApiV1Routes(stackService StackService, accessLog *AccessLog, tokenHandlers security.TokenHandlers) []apiRoute {
+	return []apiRoute{
+		{method: "GET", path: "/stacks", operationId: "listStacks", summary: "Lists all stacks with their current state", status: http.StatusOK, response: []tools.ResponsePayloadDto{},
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				writeJson(w, http.StatusOK, getStackOverview(stackService))
+			}},
+		{method: "GET", path: "/stacks/{name}", operationId: "getStack", summary: "Shows the details of a stack", status: http.StatusOK, response: tools.StackDetailDto{},
+			handler: withExistingStack(stackService, func(w http.ResponseWriter, r *http.Request, stackName string) {
+				writeJson(w, http.StatusOK, getStackDetail(stackService, stackName))
+			})},
+		{method: "POST", path: "/stacks/{name}/deploy", operationId: "deployStack", summary: "Deploys a stack and the stacks it depends on", status: http.StatusNoContent,
+			handler: withExistingStack(stackService, func(w http.ResponseWriter, r *http.Request, stackName string) {
+				runStackAction(w, r, "Deploying", stackName, stackService.DeployStack(stackName))
+			})},
+		{method: "POST", path: "/stacks/{name}/stop", operationId: "stopStack", summary: "Stops a stack", status: http.StatusNoContent,
+			queryParams: []apiQueryParameter{{name: "cascade", kind: "boolean", description: "Stops the running stacks depending on this stack first"}},
+			handler: withExistingStack(stackService, func(w http.ResponseWriter, r *http.Request, stackName string) {
+				stop := stackService.StopStack
+				if r.URL.Query().Get("cascade") == "true" {
+					stop = stackService.StopStackWithDependents
+				}
+				runStackAction(w, r, "Stopping", stackName, stop(stackName))
+			})},
+		{method: "POST", path: "/stacks/{name}/restart", operationId: "restartStack", summary: "Restarts the containers of a stack", status: http.StatusNoContent,
+			handler: withExistingStack(stackService, func(w http.ResponseWriter, r *http.Request, stackName string) {
+				runStackAction(w, r, "Restarting", stackName, stackService.RestartStack(stackName))
+			})},
+		{method: "POST", path: "/stacks/{name}/recreate", operationId: "recreateStack", summary: "Recreates the containers of a stack", status: http.StatusNoContent,
+			queryParams: []apiQueryParameter{{name: "pull", kind: "boolean", description: "Pulls the images before recreating the containers"}},
+			handler: withExistingStack(stackService, func(w http.ResponseWriter, r *http.Request, stackName string) {
+				runStackAction(w, r, "Recreating", stackName, stackService.RecreateStack(stackName, r.URL.Query().Get("pull") == "true"))
+			})},
+		{method: "POST", path: "/stacks/{name}/upgrade", operationId: "upgradeStack", summary: "Starts the upgrade of a stack to the latest images", status: http.StatusAccepted,
+			handler: withExistingStack(stackService, func(w http.ResponseWriter, r *http.Request, stackName string) {
+				if err := stackService.UpgradeStack(stackName); err != nil {
+					Logger.Warn("error when trying to upgrade stack, %s", err.Error())
+					writeStackError(w, r, err)
+					return
+				}
+				w.WriteHeader(http.StatusAccepted)
+			})},
+		{method: "GET", path: "/stacks/{name}/secrets", operationId: "getStackSecrets", summary: "Reveals the generated secrets of a stack", status: http.StatusOK, response: []tools.StackSecretDto{},
+			handler: withExistingStack(stackService, func(w http.ResponseWriter, r *http.Request, stackName string) {
+				secrets, err := stackService.GetStackSecrets(stackName)
+				if err != nil {
+					Logger.Error("error when reading secrets of stack '%s': %s", stackName, err.Error())
+					writeStackError(w, r, err)
+					return
+				}
+				Logger.Info("Secrets of stack '%s' were revealed", stackName)
+				w.Header().Set("Cache-Control", "no-store")
+				writeJson(w, http.StatusOK, toStackSecretDtos(secrets))
+			})},
+		{method: "GET", path: "/stacks/{name}/versions", operationId: "getStackVersions", summary: "Lists the deployed versions of a stack", status: http.StatusOK, response: []tools.StackVersionDto{},
+			handler: withExistingStack(stackService, func(w http.ResponseWriter, r *http.Request, stackName string) {
+				versions, err := stackService.GetStackVersions(stackName)
+				if err != nil {
+					writeStackError(w, r, err)
+					return
+				}
+				writeJson(w, http.StatusOK, toStackVersionDtos(versions))
+			})},
+		{method: "GET", path: "/stacks/{name}/stats", operationId: "getStackStats", summary: "Shows the resource usage of a stack", status: http.StatusOK, response: tools.StackStatsDto{},
+			handler: withExistingStack(stackService, func(w http.ResponseWriter, r *http.Request, stackName string) {
+				stats, err := stackService.GetStackStats(stackName)
+				if err != nil {
+					writeStackError(w, r, err)
+					return
+				}
+				writeJson(w, http.StatusOK, toStackStatsDto(stackName, stats, stackService.GetStackStatsHistory(stackName)))
+			})},
+		{method: "GET", path: "/stacks/{name}/incidents", operationId: "getStackIncidents", summary: "Lists the incidents recorded by the watchdog", status: http.StatusOK, response: []tools.StackIncidentDto{},
+			handler: withExistingStack(stackService, func(w http.ResponseWriter, r *http.Request, stackName string) {
+				incidents, err := stackService.GetStackIncidents(stackName)
+				if err != nil {
+					writeStackError(w, r, err)
+					return
+				}
+				writeJson(w, http.StatusOK, toStackIncidentDtos(incidents))
+			})},
+		{method: "GET", path: "/stacks/{name}/access-log", operationId: "getStackAccessLog", summary: "Lists the latest requests to a stack, newest first", status: http.StatusOK, response: []tools.AccessLogEntryDto{},
+			handler: withExistingStack(stackService, func(w http.ResponseWriter, r *http.Request, stackName string) {
+				writeJson(w, http.StatusOK, toAccessLogEntryDtos(accessLog.GetRecentEntries(stackName)))
+			})},
+		{method: "GET", path: "/stacks/{name}/logs", operationId: "getStackLogs", summary: "Shows the container logs of a stack", status: http.StatusOK, response: "", contentType: "text/plain",
+			queryParams: []apiQueryParameter{
+				{name: "follow", kind: "boolean", description: "Keeps the connection open and streams new lines"},
+				{name: "tail", kind: "integer", description: "Number of lines to show from the end of the logs, all lines if not given"},
+			},
+			handler: withExistingStack(stackService, func(w http.ResponseWriter, r *http.Request, stackName string) {
+				streamStackLogs(w, r, stackService, stackName)
+			})},
+		{method: "GET", path: "/stacks/{name}/backups", operationId: "getStackBackups", summary: "Lists the backups of a stack, newest first", status: http.StatusOK, response: []tools.StackBackupDto{},
+			handler: withExistingStack(stackService, func(w http.ResponseWriter, r *http.Request, stackName string) {
+				backups, err := stackService.GetStackBackups(stackName)
+				if err != nil {
+					writeStackError(w, r, err)
+					return
+				}
+				writeJson(w, http.StatusOK, toStackBackupDtos(backups))
+			})},
+		{method: "POST", path: "/stacks/{name}/backups", operationId: "backupStack", summary: "Backs up the volumes of a stack, a running stack is stopped meanwhile", status: http.StatusCreated, response: tools.StackBackupDto{},
+			handler: withExistingStack(stackService, func(w http.ResponseWriter, r *http.Request, stackName string) {
+				backup, err := stackService.BackupStack(stackName)
+				if err != nil {
+					writeStackError(w, r, err)
+					return
+				}
+				writeJson(w, http.StatusCreated, toStackBackupDto(backup))
+			})},
+		{method: "POST", path: "/stacks/{name}/backups/{backup}/restore", operationId: "restoreStack", summary: "Restores the volumes of a stack from a backup", status: http.StatusNoContent,
+			handler: withExistingStack(stackService, func(w http.ResponseWriter, r *http.Request, stackName string) {
+				runStackAction(w, r, "Restoring", stackName, stackService.RestoreStack(stackName, mux.Vars(r)["backup"]))
+			})},
+		{method: "POST", path: "/bulk/deploy", operationId: "deployStacks", summary: "Deploys several stacks, all stopped stacks which are supposed to run if none are given", status: http.StatusOK, request: tools.BulkOperationDto{}, response: []tools.BulkOperationResultDto{},
+			handler: createBulkOperationApiHandler(stackService, BulkDeploy)},
+		{method: "POST", path: "/bulk/stop", operationId: "stopStacks", summary: "Stops several stacks, all stacks if none are given", status: http.StatusOK, request: tools.BulkOperationDto{}, response: []tools.BulkOperationResultDto{},
+			handler: createBulkOperationApiHandler(stackService, BulkStop)},
+		{method: "POST", path: "/bulk/restart", operationId: "restartStacks", summary: "Restarts several stacks, all stacks if none are given", status: http.StatusOK, request: tools.BulkOperationDto{}, response: []tools.BulkOperationResultDto{},
+			handler: createBulkOperationApiHandler(stackService, BulkRestart)},
+		{method: "GET", path: "/updates", operationId: "listUpdates", summary: "Lists the images with available updates", status: http.StatusOK, response: []tools.StackUpdateDto{},
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				writeJson(w, http.StatusOK, getStackUpdateDtos(stackService))
+			}},
+		{method: "GET", path: "/instances", operationId: "listInstances", summary: "Lists the stacks created from templates", status: http.StatusOK, response: []tools.StackInstanceDto{},
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				writeJson(w, http.StatusOK, getStackInstanceDtos(stackService))
+			}},
+		{method: "POST", path: "/instances", operationId: "createInstance", summary: "Creates a stack from a template", status: http.StatusCreated, request: tools.StackInstanceDto{},
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				var instance tools.StackInstanceDto
+				if !decodeApiRequest(w, r, &instance) {
+					return
+				}
+				if err := stackService.CreateStackInstance(instance.Name, instance.Template); err != nil {
+					Logger.Warn("error when trying to create instance, %s", err.Error())
+					writeStackError(w, r, err)
+					return
+				}
+				w.WriteHeader(http.StatusCreated)
+			}},
+		{method: "DELETE", path: "/instances/{name}", operationId: "deleteInstance", summary: "Deletes a stack created from a template together with its volumes, secrets and backups", status: http.StatusNoContent,
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				if err := stackService.DeleteStackInstance(mux.Vars(r)["name"]); err != nil {
+					Logger.Warn("error when trying to delete instance, %s", err.Error())
+					writeStackError(w, r, err)
+					return
+				}
+				w.WriteHeader(http.StatusNoContent)
+			}},
+		{method: "GET", path: "/domains", operationId: "listDomains", summary: "Lists the custom domains of the stacks", status: http.StatusOK, response: []tools.StackDomainDto{},
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				writeJson(w, http.StatusOK, getStackDomainDtos(stackService))
+			}},
+		{method: "POST", path: "/domains", operationId: "addDomain", summary: "Assigns a custom domain to a stack", status: http.StatusCreated, request: tools.StackDomainDto{},
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				var domain tools.StackDomainDto
+				if !decodeApiRequest(w, r, &domain) {
+					return
+				}
+				if err := stackService.AddStackDomain(domain.Domain, domain.Stack); err != nil {
+					Logger.Warn("error when trying to add domain, %s", err.Error())
+					writeStackError(w, r, err)
+					return
+				}
+				w.WriteHeader(http.StatusCreated)
+			}},
+		{method: "DELETE", path: "/domains/{domain}", operationId: "removeDomain", summary: "Removes a custom domain", status: http.StatusNoContent,
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				if err := stackService.RemoveStackDomain(mux.Vars(r)["domain"]); err != nil {
+					Logger.Warn("error when trying to remove domain, %s", err.Error())
+					writeStackError(w, r, err)
+					return
+				}
+				w.WriteHeader(http.StatusNoContent)
+			}},
+		{method: "GET", path: "/system", operationId: "getSystemInfo", summary: "Shows the resource usage of the host", status: http.StatusOK, response: tools.SystemInfoDto{},
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				writeJson(w, http.StatusOK, toSystemInfoDto(stackService.GetSystemInfo()))
+			}},
+		{method: "GET", path: "/webhooks", operationId: "listWebhooks", summary: "Lists the webhooks notified about events", status: http.StatusOK, response: []tools.WebhookDto{},
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				webhooks, err := stackService.GetWebhooks()
+				if err != nil {
+					writeStackError(w, r, err)
+					return
+				}
+				webhookDtos := make([]tools.WebhookDto, 0)
+				for _, webhook := range webhooks {
+					webhookDtos = append(webhookDtos, toWebhookDto(webhook))
+				}
+				writeJson(w, http.StatusOK, webhookDtos)
+			}},
+		{method: "POST", path: "/webhooks", operationId: "createWebhook", summary: "Registers a webhook, the secret is only shown once", status: http.StatusCreated, request: tools.CreateWebhookDto{}, response: tools.CreatedWebhookDto{},
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				var request tools.CreateWebhookDto
+				if !decodeApiRequest(w, r, &request) {
+					return
+				}
+				webhook, err := stackService.CreateWebhook(request.Url, request.Secret, request.Events)
+				if err != nil {
+					Logger.Warn("error when trying to create webhook, %s", err.Error())
+					writeStackError(w, r, err)
+					return
+				}
+				w.Header().Set("Cache-Control", "no-store")
+				writeJson(w, http.StatusCreated, tools.CreatedWebhookDto{WebhookDto: toWebhookDto(webhook), Secret: webhook.Secret})
+			}},
+		{method: "DELETE", path: "/webhooks/{id}", operationId: "deleteWebhook", summary: "Deletes a webhook and its delivery log", status: http.StatusNoContent,
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				id, ok := parseWebhookId(w, r)
+				if !ok {
+					return
+				}
+				if err := stackService.DeleteWebhook(id); err != nil {
+					writeStackError(w, r, err)
+					return
+				}
+				w.WriteHeader(http.StatusNoContent)
+			}},
+		{method: "GET", path: "/webhooks/{id}/deliveries", operationId: "getWebhookDeliveries", summary: "Lists the latest delivery attempts of a webhook, newest first", status: http.StatusOK, response: []tools.WebhookDeliveryDto{},
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				id, ok := parseWebhookId(w, r)
+				if !ok {
+					return
+				}
+				deliveries, err := stackService.GetWebhookDeliveries(id)
+				if err != nil {
+					writeStackError(w, r, err)
+					return
+				}
+				writeJson(w, http.StatusOK, toWebhookDeliveryDtos(deliveries))
+			}},
+		{method: "GET", path: "/email/subscriptions", operationId: "listEmailSubscriptions", summary: "Lists which users receive which alerts by email", status: http.StatusOK, response: []tools.EmailSubscriptionDto{},
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				subscriptions, err := stackService.GetEmailSubscriptions()
+				if err != nil {
+					writeStackError(w, r, err)
+					return
+				}
+				writeJson(w, http.StatusOK, toEmailSubscriptionDtos(subscriptions))
+			}},
+		{method: "PUT", path: "/email/subscriptions/{user}", operationId: "setEmailSubscription", summary: "Subscribes a user to alerts by email, all alerts if no events are given", status: http.StatusNoContent, request: tools.EmailSubscriptionDto{},
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				var subscription tools.EmailSubscriptionDto
+				if !decodeApiRequest(w, r, &subscription) {
+					return
+				}
+				user := mux.Vars(r)["user"]
+				if !security.UserExists(user) {
+					writeStackError(w, r, newStackError(ErrNotFound, "", "user '%s' does not exist", user))
+					return
+				}
+				if err := stackService.SetEmailSubscription(user, subscription.Email, subscription.Events); err != nil {
+					Logger.Warn("error when trying to save email subscription, %s", err.Error())
+					writeStackError(w, r, err)
+					return
+				}
+				w.WriteHeader(http.StatusNoContent)
+			}},
+		{method: "DELETE", path: "/email/subscriptions/{user}", operationId: "deleteEmailSubscription", summary: "Unsubscribes a user from all alerts by email", status: http.StatusNoContent,
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				if err := stackService.DeleteEmailSubscription(mux.Vars(r)["user"]); err != nil {
+					writeStackError(w, r, err)
+					return
+				}
+				w.WriteHeader(http.StatusNoContent)
+			}},
+		{method: "POST", path: "/email/test", operationId: "sendTestEmail", summary: "Sends a test email to check the SMTP configuration, API tokens can only send it to subscribed addresses", status: http.StatusNoContent, request: tools.TestEmailDto{},
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				var request tools.TestEmailDto
+				if !decodeApiRequest(w, r, &request) {
+					return
+				}
+				if !security.IsAdmin(r) {
+					isSubscribed, err := isSubscribedEmail(stackService, request.Email)
+					if err != nil {
+						writeStackError(w, r, err)
+						return
+					} else if !isSubscribed {
+						writeApiError(w, http.StatusForbidden, errorCodeForbidden, "API tokens can only send test emails to subscribed addresses")
+						return
+					}
+				}
+				if err := stackService.SendTestEmail(request.Email); err != nil {
+					writeStackError(w, r, err)
+					return
+				}
+				w.WriteHeader(http.StatusNoContent)
+			}},
+		{method: "GET", path: "/tokens", operationId: "listApiTokens", summary: "Lists the API tokens, only available with a session", status: http.StatusOK, response: []tools.ApiTokenDto{},
+			handler: tokenHandlers.List},
+		{method: "POST", path: "/tokens", operationId: "createApiToken", summary: "Creates an API token, the token is only shown once", status: http.StatusCreated, request: tools.CreateApiTokenDto{}, response: tools.CreatedApiTokenDto{},
+			handler: tokenHandlers.Create},
+		{method: "DELETE", path: "/tokens/{id}", operationId: "revokeApiToken", summary: "Revokes an API token", status: http.StatusNoContent,
+			handler: tokenHandlers.Revoke},
+	}
+}
+
+// registerApiV1 registers the routes below /api/v1. Requests which match no route are answered by the subrouter
+// with a JSON error, so that they do not fall through to the frontend resources. The methods are dispatched by
+// the route itself, since mux reports a method mismatch as not found when other routes are registered after it.
+func registerApiV1(router *mux.Router, routes []apiRoute, secure func(http.Handler) http.Handler) {
+	v1 := router.PathPrefix(apiV1Prefix).Subrouter()
+	v1.NotFoundHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeApiError(w, http.StatusNotFound, errorCodeNotFound, "No such endpoint: "+r.URL.Path)
+	})
+
+	paths := make([]string, 0)
+	handlersByPath := make(map[string]map[string]http.Handler)
+	for _, route := range withOpenApiDocument(routes) {
+		var handler http.Handler = route.handler
+		if !route.isPublic {
+			handler = secure(handler)
+		}
+		if handlersByPath[route.path] == nil {
+			paths = append(paths, route.path)
+			handlersByPath[route.path] = make(map[string]http.Handler)
+		}
+		handlersByPath[route.path][route.method] = handler
+	}
+	for _, path := range paths {
+		v1.Handle(path, dispatchByMethod(handlersByPath[path], secure))
+	}
+}
+
+// dispatchByMethod answers unsupported methods with 405. Preflight requests are answered by the CORS policy of
+// the security module instead, if cross origin requests are allowed.
+func dispatchByMethod(handlers map[string]http.Handler, secure func(http.Handler) http.Handler) http.Handler {
+	allowedMethods := make([]string, 0)
+	for method := range handlers {
+		allowedMethods = append(allowedMethods, method)
+	}
+	sort.Strings(allowedMethods)
+	methodNotAllowed := secure(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Allow", strings.Join(allowedMethods, ", "))
+		writeApiError(w, http.StatusMethodNotAllowed, errorCodeMethodNotAllowed, "Method not allowed: "+r.Method)
+	}))
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if handler, ok := handlers[r.Method]; ok {
+			handler.ServeHTTP(w, r)
+		} else {
+			methodNotAllowed.ServeHTTP(w, r)
+		}
+	})
+}
+
+// withOpenApiDocument adds the endpoint serving the OpenAPI document, which describes all routes including itself.
+func withOpenApiDocument(routes []apiRoute) []apiRoute {
+	routes = append(routes[:len(routes):len(routes)], apiRoute{method: "GET", path: "/openapi.json", operationId: "getOpenApiDocument", summary: "Describes this API", status: http.StatusOK, isPublic: true})
+	document, err := json.Marshal(generateOpenApiDocument(routes))
+	if err != nil {
+		Logger.Fatal("error when generating the OpenAPI document: %v", err)
+	}
+	routes[len(routes)-1].handler = func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(document)
+	}
+	return routes
+}
+
+func withExistingStack(stackService StackService, handler stackHandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		stackName := mux.Vars(r)["name"]
+		if !stackService.StackExists(stackName) {
+			writeApiError(w, http.StatusNotFound, errorCodeNotFound, "Stack not found: "+stackName)
+			return
+		}
+		handler(w, r, stackName)
+	}
+}
+
+func runStackAction(w http.ResponseWriter, r *http.Request, action string, stackName string, err error) {
+	if err != nil {
+		Logger.Warn("%s stack '%s' failed: %s", action, stackName, err.Error())
+		writeStackError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func createBulkOperationApiHandler(stackService StackService, operation string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var request tools.BulkOperationDto
+		if r.ContentLength != 0 && !decodeApiRequest(w, r, &request) {
+			return
+		}
+
+		results, err := stackService.RunBulkOperation(operation, request.Stacks)
+		if err != nil {
+			writeStackError(w, r, err)
+			return
+		}
+		writeJson(w, http.StatusOK, toBulkOperationResultDtos(results))
+	}
+}
+
+func getStackDetail(stackService StackService, stackName string) tools.StackDetailDto {
+	stackDetails := stackService.GetStackStateInfo()[stackName]
+	detail := tools.StackDetailDto{
+		Name:            stackName,
+		State:           stackDetails.State.String(),
+		UrlPath:         stackDetails.Path,
+		Template:        stackService.GetStackInstances()[stackName],
+		UpdateAvailable: len(stackService.GetAvailableUpdates()[stackName]) > 0,
+		Domains:         make([]string, 0),
+		DependsOn:       append(make([]string, 0), stackService.GetStackConfig(stackName).DependsOn...),
+	}
+	for domain, domainStackName := range stackService.GetStackDomains() {
+		if domainStackName == stackName {
+			detail.Domains = append(detail.Domains, domain)
+		}
+	}
+	sort.Strings(detail.Domains)
+	return detail
+}
+
+// streamStackLogs sends the logs as plain text. Every write is flushed, so that followed logs arrive without delay.
+// Errors can only be reported as long as nothing was sent yet.
+func streamStackLogs(w http.ResponseWriter, r *http.Request, stackService StackService, stackName string) {
+	options := LogOptions{Follow: r.URL.Query().Get("follow") == "true"}
+	if tail := r.URL.Query().Get("tail"); tail != "" {
+		var err error
+		if options.Tail, err = strconv.Atoi(tail); err != nil || options.Tail < 0 {
+			writeApiError(w, http.StatusBadRequest, errorCodeInvalidRequest, "Invalid number of lines: "+tail)
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	output := &flushingWriter{responseController: http.NewResponseController(w), writer: w}
+	err := stackService.StreamStackLogs(r.Context(), stackName, options, output)
+	if err != nil && !output.hasWritten {
+		writeStackError(w, r, err)
+	} else if err != nil {
+		Logger.Warn("streaming the logs of stack '%s' failed: %s", stackName, err.Error())
+	}
+}
+
+type flushingWriter struct {
+	responseController *http.ResponseController
+	writer             io.Writer
+	hasWritten         bool
+}
+
+func (f *flushingWriter) Write(data []byte) (int, error) {
+	f.hasWritten = true
+	written, err := f.writer.Write(data)
+	if err == nil {
+		// A failing flush is noticed by the next write, since the connection is gone then.
+		_ = f.responseController.Flush()
+	}
+	return written, err
+}
+
+func toStackBackupDto(backup StackBackup) tools.StackBackupDto {
+	return tools.StackBackupDto{Id: backup.Id, Timestamp: backup.Timestamp, Reason: backup.Reason}
+}
+
+func toStackBackupDtos(backups []StackBackup) []tools.StackBackupDto {
+	backupDtos := make([]tools.StackBackupDto, 0)
+	for _, backup := range backups {
+		backupDtos = append(backupDtos, toStackBackupDto(backup))
+	}
+	return backupDtos
+}
+
+func toWebhookDto(webhook Webhook) tools.WebhookDto {
+	events := webhook.Events
+	if events == nil {
+		events = []string{}
+	}
+	return tools.WebhookDto{Id: webhook.Id, Url: webhook.Url, Events: events, CreatedAt: webhook.CreatedAt}
+}
+
+func toWebhookDeliveryDtos(deliveries []WebhookDelivery) []tools.WebhookDeliveryDto {
+	deliveryDtos := make([]tools.WebhookDeliveryDto, 0)
+	for _, delivery := range deliveries {
+		deliveryDtos = append(deliveryDtos, tools.WebhookDeliveryDto{Id: delivery.Id, DeliveryId: delivery.DeliveryId, Event: delivery.Event, Attempt: delivery.Attempt,
+			StatusCode: delivery.StatusCode, Error: delivery.Error, IsSuccessful: delivery.IsSuccessful, Timestamp: delivery.Timestamp})
+	}
+	return deliveryDtos
+}
+
+func toEmailSubscriptionDtos(subscriptions []EmailSubscription) []tools.EmailSubscriptionDto {
+	subscriptionDtos := make([]tools.EmailSubscriptionDto, 0)
+	for _, subscription := range subscriptions {
+		events := subscription.Events
+		if events == nil {
+			events = []string{}
+		}
+		subscriptionDtos = append(subscriptionDtos, tools.EmailSubscriptionDto{User: subscription.User, Email: subscription.Email, Events: events})
+	}
+	return subscriptionDtos
+}
+
+func isSubscribedEmail(stackService StackService, email string) (bool, error) {
+	subscriptions, err := stackService.GetEmailSubscriptions()
+	if err != nil {
+		return false, err
+	}
+	for _, subscription := range subscriptions {
+		if subscription.Email == email {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func parseWebhookId(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		writeApiError(w, http.StatusBadRequest, errorCodeInvalidRequest, "invalid webhook id")
+		return 0, false
+	}
+	return id, true
+}
+
+func decodeApiRequest(w http.ResponseWriter, r *http.Request, request any) bool {
+	if err := json.NewDecoder(r.Body).Decode(request); err != nil {
+		writeApiError(w, http.StatusBadRequest, errorCodeInvalidRequest, "Failed to decode JSON: "+err.Error())
+		return false
+	}
+	return true
+}
+
+func writeJson(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+// writeStackError answers with the status matching the kind of the error. The captured docker output is only
+// included for admins, since it may reveal details of the host which API tokens have no need for.
+func writeStackError(w http.ResponseWriter, r *http.Request, err error) {
+	status, code := getErrorStatus(err)
+	errorDto := tools.ErrorDto{Code: code, Message: err.Error()}
+	if security.IsAdmin(r) {
+		errorDto.Output = getStackErrorOutput(err)
+	}
+	writeJson(w, status, errorDto)
+}
+
+func getErrorStatus(err error) (int, string) {
+	switch {
+	case errors.Is(err, ErrInvalidRequest):
+		return http.StatusBadRequest, errorCodeInvalidRequest
+	case errors.Is(err, ErrNotFound):
+		return http.StatusNotFound, errorCodeNotFound
+	case errors.Is(err, ErrInvalidState):
+		return http.StatusConflict, errorCodeInvalidState
+	case errors.Is(err, ErrDockerUnavailable):
+		return http.StatusServiceUnavailable, errorCodeDockerUnavailable
+	case errors.Is(err, ErrDownloadFailed):
+		return http.StatusServiceUnavailable, errorCodeDownloadFailed
+	case errors.Is(err, ErrTimeout):
+		return http.StatusGatewayTimeout, errorCodeTimeout
+	case errors.Is(err, ErrComposeFailed):
+		return http.StatusInternalServerError, errorCodeComposeFailed
+	default:
+		return http.StatusInternalServerError, errorCodeOperationFailed
+	}
+}
+
+func writeApiError(w http.ResponseWriter, status int, code string, message string) {
+	writeJson(w, status, tools.ErrorDto{Code: code, Message: message})
+}
+
+// markAsDeprecated keeps an endpoint of the unversioned API working, but points clients to its successor.
+func markAsDeprecated(successorPath string, handlerFunc http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Deprecation", "true")
+		w.Header().Set("Link", "<"+apiV1Prefix+successorPath+">; rel=\"successor-version\"")
+		handlerFunc(w, r)
+	}
+}
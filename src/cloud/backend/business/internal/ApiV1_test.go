@@ -0,0 +1,240 @@
+package internal
+
+import (
+	"encoding/json"
+	"github.com/gorilla/mux"
+	"github.com/ocelot-cloud/shared/assert"
+	"net/http"
+	"net/http/httptest"
+	"ocelot/backend/config"
+	"ocelot/backend/security"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func createTokenHandlers() security.TokenHandlers {
+	securityModule := security.ProvideSecurityModule(mux.NewRouter(), &tools.GlobalConfig{})
+	securityModule.UseDatabase(ProvideInMemoryDatabase())
+	return securityModule.GetTokenHandlers()
+}
+
+func createApiV1Router(t *testing.T, stackService StackService) *mux.Router {
+	router := mux.NewRouter()
+	accessLog := ProvideAccessLog(filepath.Join(t.TempDir(), AccessLogFileName), nil)
+	registerApiV1(router, getApiV1Routes(stackService, accessLog, createTokenHandlers()), func(h http.Handler) http.Handler { return h })
+	router.PathPrefix("/").HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	})
+	return router
+}
+
+func sendApiRequest(router *mux.Router, method string, path string, body string) *httptest.ResponseRecorder {
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(method, path, strings.NewReader(body)))
+	return recorder
+}
+
+func decodeApiResponse[T any](t *testing.T, recorder *httptest.ResponseRecorder) T {
+	var response T
+	assert.Nil(t, json.NewDecoder(recorder.Body).Decode(&response))
+	return response
+}
+
+func TestApiV1DeploysAndStopsStack(t *testing.T) {
+	router := createApiV1Router(t, createStackService())
+
+	assert.Equal(t, http.StatusNoContent, sendApiRequest(router, "POST", "/api/v1/stacks/nginx-default/deploy", "").Code)
+	recorder := sendApiRequest(router, "GET", "/api/v1/stacks/nginx-default", "")
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	detail := decodeApiResponse[tools.StackDetailDto](t, recorder)
+	assert.Equal(t, tools.NginxDefault, detail.Name)
+	assert.Equal(t, "Available", detail.State)
+
+	assert.Equal(t, http.StatusNoContent, sendApiRequest(router, "POST", "/api/v1/stacks/nginx-default/stop", "").Code)
+	detail = decodeApiResponse[tools.StackDetailDto](t, sendApiRequest(router, "GET", "/api/v1/stacks/nginx-default", ""))
+	assert.Equal(t, "Uninitialized", detail.State)
+}
+
+func TestApiV1ListsStacksSortedByName(t *testing.T) {
+	router := createApiV1Router(t, createStackService())
+
+	recorder := sendApiRequest(router, "GET", "/api/v1/stacks", "")
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	stacks := decodeApiResponse[[]tools.ResponsePayloadDto](t, recorder)
+	assert.True(t, len(stacks) > 1)
+	for i := 1; i < len(stacks); i++ {
+		assert.True(t, stacks[i-1].Name < stacks[i].Name)
+	}
+}
+
+func TestApiV1AnswersWithJsonErrors(t *testing.T) {
+	router := createApiV1Router(t, createStackService())
+
+	recorder := sendApiRequest(router, "POST", "/api/v1/stacks/not-existing/deploy", "")
+	assert.Equal(t, http.StatusNotFound, recorder.Code)
+	assert.Equal(t, tools.ErrorDto{Code: errorCodeNotFound, Message: "Stack not found: not-existing"}, decodeApiResponse[tools.ErrorDto](t, recorder))
+
+	recorder = sendApiRequest(router, "POST", "/api/v1/stacks/nginx-default/stop", "")
+	assert.Equal(t, http.StatusConflict, recorder.Code)
+	assert.Equal(t, errorCodeInvalidState, decodeApiResponse[tools.ErrorDto](t, recorder).Code)
+
+	recorder = sendApiRequest(router, "DELETE", "/api/v1/stacks/nginx-default", "")
+	assert.Equal(t, http.StatusMethodNotAllowed, recorder.Code)
+	assert.Equal(t, "GET", recorder.Header().Get("Allow"))
+	assert.Equal(t, errorCodeMethodNotAllowed, decodeApiResponse[tools.ErrorDto](t, recorder).Code)
+
+	recorder = sendApiRequest(router, "GET", "/api/v1/not-existing", "")
+	assert.Equal(t, http.StatusNotFound, recorder.Code)
+	assert.Equal(t, errorCodeNotFound, decodeApiResponse[tools.ErrorDto](t, recorder).Code)
+
+	recorder = sendApiRequest(router, "POST", "/api/v1/instances", "{invalid")
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+	assert.Equal(t, errorCodeInvalidRequest, decodeApiResponse[tools.ErrorDto](t, recorder).Code)
+}
+
+func TestApiV1ManagesInstancesAndDomains(t *testing.T) {
+	router := createApiV1Router(t, createStackService())
+
+	assert.Equal(t, http.StatusCreated, sendApiRequest(router, "POST", "/api/v1/instances", `{"name": "nginx-team-a", "template": "nginx-default"}`).Code)
+	assert.Equal(t, http.StatusCreated, sendApiRequest(router, "POST", "/api/v1/domains", `{"domain": "team-a.example.com", "stack": "nginx-team-a"}`).Code)
+	detail := decodeApiResponse[tools.StackDetailDto](t, sendApiRequest(router, "GET", "/api/v1/stacks/nginx-team-a", ""))
+	assert.Equal(t, tools.NginxDefault, detail.Template)
+	assert.Equal(t, []string{"team-a.example.com"}, detail.Domains)
+
+	assert.Equal(t, http.StatusNoContent, sendApiRequest(router, "DELETE", "/api/v1/domains/team-a.example.com", "").Code)
+	assert.Equal(t, http.StatusNoContent, sendApiRequest(router, "DELETE", "/api/v1/instances/nginx-team-a", "").Code)
+	assert.Equal(t, http.StatusNotFound, sendApiRequest(router, "GET", "/api/v1/stacks/nginx-team-a", "").Code)
+}
+
+func TestOpenApiDocumentDescribesAllRoutes(t *testing.T) {
+	stackService := createStackService()
+	router := createApiV1Router(t, stackService)
+
+	recorder := sendApiRequest(router, "GET", "/api/v1/openapi.json", "")
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	var document struct {
+		Paths      map[string]map[string]any `json:"paths"`
+		Components struct {
+			Schemas map[string]struct {
+				Required   []string       `json:"required"`
+				Properties map[string]any `json:"properties"`
+			} `json:"schemas"`
+		} `json:"components"`
+	}
+	assert.Nil(t, json.NewDecoder(recorder.Body).Decode(&document))
+
+	for _, route := range getApiV1Routes(stackService, nil, security.TokenHandlers{}) {
+		_, ok := document.Paths[route.path][strings.ToLower(route.method)]
+		assert.True(t, ok)
+	}
+	detailSchema := document.Components.Schemas["StackDetailDto"]
+	assert.Equal(t, 7, len(detailSchema.Properties))
+	assert.Equal(t, []string{"name", "state", "urlPath", "updateAvailable", "domains", "dependsOn"}, detailSchema.Required)
+	assert.Equal(t, []string{"code", "message"}, document.Components.Schemas["ErrorDto"].Required)
+	assert.NotNil(t, document.Paths["/tokens/{id}"]["delete"])
+}
+
+func TestApiV1ManagesTokens(t *testing.T) {
+	router := createApiV1Router(t, createStackService())
+
+	recorder := sendApiRequest(router, "POST", "/api/v1/tokens", `{"name": "ci", "scopes": ["read"]}`)
+	assert.Equal(t, http.StatusCreated, recorder.Code)
+	token := decodeApiResponse[tools.CreatedApiTokenDto](t, recorder)
+	tokens := decodeApiResponse[[]tools.ApiTokenDto](t, sendApiRequest(router, "GET", "/api/v1/tokens", ""))
+	assert.Equal(t, 1, len(tokens))
+	assert.Equal(t, token.Id, tokens[0].Id)
+	assert.Equal(t, "ci", tokens[0].Name)
+	assert.Equal(t, http.StatusNoContent, sendApiRequest(router, "DELETE", "/api/v1/tokens/"+strconv.FormatInt(token.Id, 10), "").Code)
+	assert.Equal(t, http.StatusMethodNotAllowed, sendApiRequest(router, "PUT", "/api/v1/tokens", "").Code)
+}
+
+func TestDeprecatedEndpointPointsToSuccessor(t *testing.T) {
+	handler := markAsDeprecated("/stacks/{name}/deploy", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	recorder := httptest.NewRecorder()
+	handler(recorder, httptest.NewRequest("POST", "/api/stacks/deploy", nil))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, "true", recorder.Header().Get("Deprecation"))
+	assert.Equal(t, `</api/v1/stacks/{name}/deploy>; rel="successor-version"`, recorder.Header().Get("Link"))
+}
+
+func TestApiV1ShowsLogsOfStack(t *testing.T) {
+	router := createApiV1Router(t, createStackService())
+	assert.Equal(t, http.StatusNoContent, sendApiRequest(router, "POST", "/api/v1/stacks/nginx-default/deploy", "").Code)
+
+	recorder := sendApiRequest(router, "GET", "/api/v1/stacks/nginx-default/logs?tail=1", "")
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, "text/plain; charset=utf-8", recorder.Header().Get("Content-Type"))
+	assert.Equal(t, "nginx-default-1  | nginx-default is ready\n", recorder.Body.String())
+
+	assert.Equal(t, http.StatusBadRequest, sendApiRequest(router, "GET", "/api/v1/stacks/nginx-default/logs?tail=-1", "").Code)
+	assert.Equal(t, http.StatusNotFound, sendApiRequest(router, "GET", "/api/v1/stacks/not-existing/logs", "").Code)
+}
+
+func TestApiV1BacksUpAndRestoresStack(t *testing.T) {
+	useTemporaryDataDir(t)
+	router := createApiV1Router(t, createStackService())
+
+	recorder := sendApiRequest(router, "POST", "/api/v1/stacks/nginx-default/backups", "")
+	assert.Equal(t, http.StatusCreated, recorder.Code)
+	backup := decodeApiResponse[tools.StackBackupDto](t, recorder)
+	assert.Equal(t, BackupReasonManual, backup.Reason)
+
+	backups := decodeApiResponse[[]tools.StackBackupDto](t, sendApiRequest(router, "GET", "/api/v1/stacks/nginx-default/backups", ""))
+	assert.Equal(t, []tools.StackBackupDto{backup}, backups)
+	assert.Equal(t, http.StatusNoContent, sendApiRequest(router, "POST", "/api/v1/stacks/nginx-default/backups/"+backup.Id+"/restore", "").Code)
+	assert.Equal(t, http.StatusNotFound, sendApiRequest(router, "POST", "/api/v1/stacks/nginx-default/backups/unknown/restore", "").Code)
+}
+
+func TestApiV1ManagesWebhooks(t *testing.T) {
+	router := createApiV1Router(t, createStackService())
+
+	recorder := sendApiRequest(router, "POST", "/api/v1/webhooks", `{"url": "https://example.org/hook", "events": ["backup.failed"]}`)
+	assert.Equal(t, http.StatusCreated, recorder.Code)
+	webhook := decodeApiResponse[tools.CreatedWebhookDto](t, recorder)
+	assert.NotEqual(t, "", webhook.Secret)
+	assert.Equal(t, []string{EventBackupFailed}, webhook.Events)
+	assert.Equal(t, http.StatusBadRequest, sendApiRequest(router, "POST", "/api/v1/webhooks", `{"url": "not-a-url"}`).Code)
+
+	recorder = sendApiRequest(router, "GET", "/api/v1/webhooks", "")
+	assert.False(t, strings.Contains(recorder.Body.String(), webhook.Secret))
+	assert.Equal(t, []tools.WebhookDto{webhook.WebhookDto}, decodeApiResponse[[]tools.WebhookDto](t, recorder))
+
+	webhookPath := "/api/v1/webhooks/" + strconv.FormatInt(webhook.Id, 10)
+	recorder = sendApiRequest(router, "GET", webhookPath+"/deliveries", "")
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, 0, len(decodeApiResponse[[]tools.WebhookDeliveryDto](t, recorder)))
+	assert.Equal(t, http.StatusNoContent, sendApiRequest(router, "DELETE", webhookPath, "").Code)
+	assert.Equal(t, http.StatusNotFound, sendApiRequest(router, "DELETE", webhookPath, "").Code)
+	assert.Equal(t, http.StatusBadRequest, sendApiRequest(router, "GET", "/api/v1/webhooks/abc/deliveries", "").Code)
+}
+
+func TestApiV1ManagesEmailSubscriptions(t *testing.T) {
+	router := createApiV1Router(t, createStackService())
+
+	assert.Equal(t, http.StatusNoContent, sendApiRequest(router, "PUT", "/api/v1/email/subscriptions/admin", `{"email": "admin@example.org", "events": ["stack.down"]}`).Code)
+	assert.Equal(t, http.StatusBadRequest, sendApiRequest(router, "PUT", "/api/v1/email/subscriptions/admin", `{"email": "no-address"}`).Code)
+	recorder := sendApiRequest(router, "GET", "/api/v1/email/subscriptions", "")
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	expectedSubscription := tools.EmailSubscriptionDto{User: "admin", Email: "admin@example.org", Events: []string{EventStackDown}}
+	assert.Equal(t, []tools.EmailSubscriptionDto{expectedSubscription}, decodeApiResponse[[]tools.EmailSubscriptionDto](t, recorder))
+	assert.Equal(t, http.StatusConflict, sendApiRequest(router, "POST", "/api/v1/email/test", `{"email": "admin@example.org"}`).Code)
+
+	assert.Equal(t, http.StatusNoContent, sendApiRequest(router, "DELETE", "/api/v1/email/subscriptions/admin", "").Code)
+	assert.Equal(t, http.StatusNotFound, sendApiRequest(router, "DELETE", "/api/v1/email/subscriptions/admin", "").Code)
+	assert.Equal(t, http.StatusNotFound, sendApiRequest(router, "PUT", "/api/v1/email/subscriptions/alice", `{"email": "alice@example.org"}`).Code)
+}
+
+func TestOnlyAdminsCanSendTestEmailsToAnyAddress(t *testing.T) {
+	stackService := createStackService()
+	router := createApiV1Router(t, stackService)
+	adminRouter := mux.NewRouter()
+	registerApiV1(adminRouter, getApiV1Routes(stackService, nil, security.TokenHandlers{}), security.ProvideSecurityModule(mux.NewRouter(), &tools.GlobalConfig{}).ApplyAuthMiddlewares)
+
+	assert.Equal(t, http.StatusForbidden, sendApiRequest(router, "POST", "/api/v1/email/test", `{"email": "someone@example.org"}`).Code)
+	assert.Equal(t, http.StatusConflict, sendApiRequest(adminRouter, "POST", "/api/v1/email/test", `{"email": "someone@example.org"}`).Code)
+}
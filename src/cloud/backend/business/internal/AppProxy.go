@@ -0,0 +1,458 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/prometheus/client_golang/prometheus"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"ocelot/backend/config"
+	"ocelot/backend/security"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// AppProxy is the entry point of all requests. Requests to the ocelot host are handled by the router, all
+// other requests are forwarded to the stack the host name belongs to, either a subdomain of the root domain
+// like "gitea.example.org" or a custom domain assigned to the stack. In the path routing mode, the stacks are
+// instead reachable under a path prefix of the ocelot host like "example.org/apps/gitea/".
+type AppProxy struct {
+	config       *tools.GlobalConfig
+	stackService StackService
+	router       http.Handler
+	transport    *http.Transport
+	limiter      *ProxyLimiter
+	// defaultLimits apply to the stacks which don't override them in their app.yml.
+	defaultLimits LimitConfig
+	accessLog     *AccessLog
+	// securityModule resolves the users of the requests for the access log.
+	securityModule *security.SecurityModule
+	// dashboardFilter restricts access to the ocelot host, the access to apps is configured per stack.
+	dashboardFilter IpFilter
+	trustedProxies  []*net.IPNet
+	mu              sync.Mutex
+	// proxies are kept for the lifetime of the application, so that connections to the apps are reused.
+	proxies map[string]*httputil.ReverseProxy
+	// stackFilters are the parsed access configurations of the stacks, they are parsed again when changed.
+	stackFilters map[string]stackFilter
+	// stackStates are shown on the error pages. They are cached, since anyone can cause failed requests and
+	// looking up the states queries docker.
+	stackStatesMu        sync.Mutex
+	stackStates          map[string]StackDetails
+	stackStatesFetchedAt time.Time
+	stackStatesCacheTime time.Duration
+}
+
+type stackFilter struct {
+	access AccessConfig
+	filter IpFilter
+	err    error
+}
+
+func ProvideAppProxy(config *tools.GlobalConfig, stackService StackService, router http.Handler, accessLog *AccessLog, securityModule *security.SecurityModule) *AppProxy {
+	dashboardFilter, err := parseIpFilter(AccessConfig{Allow: config.DashboardAllowList, Deny: config.DashboardDenyList})
+	if err != nil {
+		Logger.Fatal("invalid access configuration of the dashboard: %v", err)
+	}
+	trustedProxies, err := parseNetworks(config.TrustedProxies)
+	if err != nil {
+		Logger.Fatal("invalid trusted proxies: %v", err)
+	}
+	appProxy := &AppProxy{
+		config:               config,
+		stackService:         stackService,
+		router:               router,
+		transport:            createProxyTransport(),
+		limiter:              ProvideProxyLimiter(),
+		defaultLimits:        LimitConfig(config.LimitDefaults).withDefaults(NoLimits),
+		accessLog:            accessLog,
+		securityModule:       securityModule,
+		dashboardFilter:      dashboardFilter,
+		trustedProxies:       trustedProxies,
+		proxies:              make(map[string]*httputil.ReverseProxy),
+		stackFilters:         make(map[string]stackFilter),
+		stackStatesCacheTime: ProxyErrorStateCacheTime,
+	}
+	registerScrapeCollector("ocelot_proxy_limited_requests_total", "Number of proxied requests rejected due to limits by stack and limit.", prometheus.CounterValue, []string{"stack", "limit"}, appProxy.collectLimitCounters)
+	return appProxy
+}
+
+func (p *AppProxy) collectLimitCounters(emit func(value float64, labelValues ...string)) {
+	for stackName, counters := range p.limiter.GetCounters() {
+		emit(float64(counters.RateLimited), stackName, "rate")
+		emit(float64(counters.ClientRateLimited), stackName, "client_rate")
+		emit(float64(counters.ConcurrencyLimited), stackName, "concurrency")
+		emit(float64(counters.BodyTooLarge), stackName, "body_size")
+	}
+}
+
+// createProxyTransport limits the time for establishing connections and waiting for response headers. There is
+// deliberately no overall timeout, since WebSockets and streamed responses may stay open for a long time.
+func createProxyTransport() *http.Transport {
+	dialer := &net.Dialer{Timeout: proxyDialTimeout, KeepAlive: 30 * time.Second}
+	return &http.Transport{
+		DialContext:           dialer.DialContext,
+		MaxIdleConns:          100,
+		MaxIdleConnsPerHost:   20,
+		IdleConnTimeout:       90 * time.Second,
+		ResponseHeaderTimeout: proxyResponseHeaderTimeout,
+		ExpectContinueTimeout: 1 * time.Second,
+	}
+}
+
+func (p *AppProxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if remoteIp := net.ParseIP(removePort(r.RemoteAddr)); remoteIp == nil || !containsIp(p.trustedProxies, remoteIp) {
+		r.Header.Del("X-Forwarded-For")
+	}
+
+	if p.config.RoutingMode == tools.RoutingModePath && !p.isCustomDomain(r.Host) {
+		p.serveByPath(w, r)
+		return
+	}
+
+	if p.isOcelotHost(r.Host) {
+		p.serveDashboard(w, r)
+		return
+	}
+
+	targetStack, ok := p.getTargetStack(r.Host)
+	if !ok {
+		Logger.Debug("No stack found for host '%s'", r.Host)
+		writeUnknownHostPage(w, r.Host)
+		return
+	}
+	p.proxyRequestToTheDockerContainer(w, r, targetStack, "")
+}
+
+func (p *AppProxy) serveByPath(w http.ResponseWriter, r *http.Request) {
+	targetStack, ok := getStackFromPath(r.URL.Path)
+	if !ok {
+		p.serveDashboard(w, r)
+		return
+	} else if !p.stackService.StackExists(targetStack) {
+		Logger.Debug("No stack found for path '%s'", r.URL.Path)
+		writeUnknownHostPage(w, r.Host+r.URL.Path)
+		return
+	}
+
+	pathPrefix := AppPathPrefix + targetStack
+	if r.URL.Path == pathPrefix {
+		target := pathPrefix + "/"
+		if r.URL.RawQuery != "" {
+			target += "?" + r.URL.RawQuery
+		}
+		http.Redirect(w, r, target, http.StatusMovedPermanently)
+		return
+	}
+
+	r.URL.Path = strings.TrimPrefix(r.URL.Path, pathPrefix)
+	r.URL.RawPath = strings.TrimPrefix(r.URL.RawPath, pathPrefix)
+	r.Header.Set("X-Forwarded-Prefix", pathPrefix)
+	p.proxyRequestToTheDockerContainer(w, r, targetStack, pathPrefix)
+}
+
+func (p *AppProxy) serveDashboard(w http.ResponseWriter, r *http.Request) {
+	if !p.dashboardFilter.isAllowed(p.getClientIp(r)) {
+		Logger.Debug("client '%s' is not allowed to access the dashboard", p.getClientIp(r))
+		writeAccessDeniedPage(w, "the dashboard")
+		return
+	}
+	p.router.ServeHTTP(w, r)
+}
+
+// getStackFromPath extracts the stack name from paths like "/apps/gitea/explore".
+func getStackFromPath(path string) (string, bool) {
+	if !strings.HasPrefix(path, AppPathPrefix) {
+		return "", false
+	}
+	stackName := strings.SplitN(strings.TrimPrefix(path, AppPathPrefix), "/", 2)[0]
+	return stackName, stackName != ""
+}
+
+func (p *AppProxy) isCustomDomain(host string) bool {
+	_, ok := p.stackService.GetStackDomains()[strings.ToLower(removePort(host))]
+	return ok
+}
+
+func (p *AppProxy) isOcelotHost(host string) bool {
+	ocelotDomain := "ocelot-cloud." + p.config.RootDomain
+	localDomain := p.config.RootDomain + ":" + p.config.Port
+	return host == ocelotDomain || host == localDomain
+}
+
+// getTargetStack resolves the stack serving a host. Custom domains take precedence over subdomains.
+func (p *AppProxy) getTargetStack(host string) (string, bool) {
+	hostName := strings.ToLower(removePort(host))
+	if stackName, ok := p.stackService.GetStackDomains()[hostName]; ok {
+		return stackName, true
+	}
+
+	if !strings.HasSuffix(hostName, "."+p.config.RootDomain) {
+		return "", false
+	}
+	stackName := strings.TrimSuffix(hostName, "."+p.config.RootDomain)
+	if strings.Contains(stackName, ".") || !p.stackService.StackExists(stackName) {
+		return "", false
+	}
+	return stackName, true
+}
+
+// HostPolicy decides for which hosts TLS certificates are requested from Let's Encrypt, which are the ocelot
+// host, the subdomains of all stacks and their custom domains. In the path routing mode, it is the root domain.
+func (p *AppProxy) HostPolicy(_ context.Context, host string) error {
+	if p.isOcelotHost(host) || removePort(host) == "ocelot-cloud."+p.config.RootDomain {
+		return nil
+	} else if p.config.RoutingMode == tools.RoutingModePath && removePort(host) == p.config.RootDomain {
+		return nil
+	}
+	if _, ok := p.getTargetStack(host); !ok {
+		return fmt.Errorf("host '%s' is not served", host)
+	}
+	return nil
+}
+
+func (p *AppProxy) proxyRequestToTheDockerContainer(w http.ResponseWriter, r *http.Request, targetContainer string, pathPrefix string) {
+	Logger.Trace("Proxying request with target host %s to stack %s", r.Host, targetContainer)
+	clientIp := p.getClientIp(r)
+	user := p.getAuthenticatedUser(r)
+	recorder := newResponseRecorder(w)
+	w = recorder
+	startTime := time.Now()
+	defer func() {
+		p.accessLog.Record(AccessLogEntry{
+			Timestamp: startTime,
+			Host:      r.Host,
+			Stack:     targetContainer,
+			Method:    r.Method,
+			Path:      strings.SplitN(r.RequestURI, "?", 2)[0],
+			Status:    recorder.status,
+			Bytes:     recorder.bytes,
+			LatencyMs: float64(time.Since(startTime).Microseconds()) / 1000,
+			ClientIp:  clientIp.String(),
+			User:      user,
+		})
+		proxyRequestsTotal.WithLabelValues(targetContainer, strconv.Itoa(recorder.status)).Inc()
+		proxyRequestDuration.WithLabelValues(targetContainer).Observe(time.Since(startTime).Seconds())
+	}()
+
+	stackConfig := p.stackService.GetStackConfig(targetContainer)
+	targetURL, err := url.Parse("http://" + targetContainer + ":" + stackConfig.Port)
+	if err != nil {
+		Logger.Error("error when parsing URL, %s", err.Error())
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	filter, err := p.getStackFilter(targetContainer, stackConfig.Access)
+	if err != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	} else if !filter.isAllowed(clientIp) {
+		Logger.Debug("client '%s' is not allowed to access stack '%s'", clientIp, targetContainer)
+		writeAccessDeniedPage(w, targetContainer)
+		return
+	}
+
+	limits := stackConfig.Limits.withDefaults(p.defaultLimits)
+	if violation := p.limiter.Acquire(targetContainer, clientIp.String(), limits); violation != NoViolation {
+		Logger.Debug("request of client '%s' to stack '%s' was rejected, %s exceeded", clientIp, targetContainer, violation.String())
+		writeTooManyRequestsPage(w, targetContainer)
+		return
+	}
+	defer p.limiter.Release(targetContainer)
+	if limits.MaxRequestBodyBytes > 0 {
+		if r.ContentLength > limits.MaxRequestBodyBytes {
+			p.limiter.CountBodyTooLarge(targetContainer)
+			writeRequestTooLargePage(w, targetContainer)
+			return
+		}
+		r.Body = http.MaxBytesReader(w, r.Body, limits.MaxRequestBodyBytes)
+	}
+
+	removeCookie(r, security.SessionCookieName)
+	// the path of original request is preserved
+	r.URL.Host = targetContainer
+	r.URL.Scheme = "http"
+	r.Header.Set("X-Forwarded-Host", r.Host)
+	p.getReverseProxy(targetContainer, targetURL, pathPrefix).ServeHTTP(w, r)
+}
+
+func (p *AppProxy) getReverseProxy(targetContainer string, targetURL *url.URL, pathPrefix string) *httputil.ReverseProxy {
+	key := targetURL.String() + pathPrefix
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if proxy, ok := p.proxies[key]; ok {
+		return proxy
+	}
+
+	// WebSocket upgrades are passed through by the reverse proxy, as long as the transport is HTTP/1.1
+	proxy := httputil.NewSingleHostReverseProxy(targetURL)
+	proxy.Transport = p.transport
+	proxy.FlushInterval = proxyFlushInterval
+	proxy.ErrorHandler = p.createErrorHandler(targetContainer)
+	if pathPrefix != "" {
+		proxy.ModifyResponse = createPathPrefixRewriter(pathPrefix, targetURL.Host)
+	}
+	p.proxies[key] = proxy
+	return proxy
+}
+
+// getStackFilter returns the parsed access configuration of a stack. An invalid configuration is only logged once
+// and answered with an error instead of denying the access, so that it is not mistaken for a deliberate restriction.
+func (p *AppProxy) getStackFilter(stackName string, access AccessConfig) (IpFilter, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if cached, ok := p.stackFilters[stackName]; ok && slices.Equal(cached.access.Allow, access.Allow) && slices.Equal(cached.access.Deny, access.Deny) {
+		return cached.filter, cached.err
+	}
+
+	filter, err := parseIpFilter(access)
+	if err != nil {
+		Logger.Error("invalid access configuration of stack '%s': %v", stackName, err)
+	}
+	p.stackFilters[stackName] = stackFilter{access: access, filter: filter, err: err}
+	return filter, err
+}
+
+// createErrorHandler replaces the plain 502 of failed requests by a page explaining why the app is not
+// reachable. While an app is starting, the page reloads itself until the app is available.
+func (p *AppProxy) createErrorHandler(targetContainer string) func(http.ResponseWriter, *http.Request, error) {
+	return func(w http.ResponseWriter, r *http.Request, err error) {
+		if r.Context().Err() != nil {
+			Logger.Trace("request to stack '%s' was canceled by the client", targetContainer)
+			return
+		}
+		var maxBytesError *http.MaxBytesError
+		if errors.As(err, &maxBytesError) {
+			p.limiter.CountBodyTooLarge(targetContainer)
+			writeRequestTooLargePage(w, targetContainer)
+			return
+		}
+		Logger.Debug("request to stack '%s' failed: %v", targetContainer, err)
+
+		switch p.getCachedStackState(targetContainer) {
+		case Available:
+			writeAppUnreachablePage(w, targetContainer)
+		case Uninitialized, Stopping:
+			writeAppNotRunningPage(w, targetContainer)
+		default:
+			writeAppStartingPage(w, targetContainer)
+		}
+	}
+}
+
+func (p *AppProxy) getCachedStackState(stackName string) StackState {
+	p.stackStatesMu.Lock()
+	defer p.stackStatesMu.Unlock()
+	if p.stackStatesFetchedAt.IsZero() || time.Since(p.stackStatesFetchedAt) >= p.stackStatesCacheTime {
+		p.stackStates = p.stackService.GetStackStateInfo()
+		p.stackStatesFetchedAt = time.Now()
+	}
+	return p.stackStates[stackName].State
+}
+
+var cookiePathPattern = regexp.MustCompile(`(?i)(;\s*path\s*=\s*)(/[^;]*)`)
+
+// createPathPrefixRewriter adds the path prefix to redirects and cookie paths of an app, since apps behind a
+// path prefix usually don't know about it and would otherwise refer to paths outside their prefix.
+func createPathPrefixRewriter(pathPrefix string, upstreamHost string) func(*http.Response) error {
+	return func(response *http.Response) error {
+		publicHost := ""
+		if response.Request != nil {
+			publicHost = response.Request.Header.Get("X-Forwarded-Host")
+		}
+		if location := response.Header.Get("Location"); location != "" {
+			response.Header.Set("Location", rewriteLocation(location, pathPrefix, publicHost, upstreamHost))
+		}
+
+		cookies := response.Header.Values("Set-Cookie")
+		response.Header.Del("Set-Cookie")
+		for _, cookie := range cookies {
+			response.Header.Add("Set-Cookie", rewriteCookiePath(cookie, pathPrefix))
+		}
+		return nil
+	}
+}
+
+func rewriteLocation(location string, pathPrefix string, publicHost string, upstreamHost string) string {
+	locationUrl, err := url.Parse(location)
+	if err != nil || (locationUrl.Host != "" && locationUrl.Host != publicHost && locationUrl.Host != upstreamHost) {
+		return location
+	}
+	if !strings.HasPrefix(locationUrl.Path, "/") || hasPathPrefix(locationUrl.Path, pathPrefix) {
+		return location
+	}
+
+	if locationUrl.Host == upstreamHost {
+		locationUrl.Scheme = ""
+		locationUrl.Host = ""
+	}
+	locationUrl.Path = pathPrefix + locationUrl.Path
+	locationUrl.RawPath = ""
+	return locationUrl.String()
+}
+
+func rewriteCookiePath(cookie string, pathPrefix string) string {
+	return cookiePathPattern.ReplaceAllStringFunc(cookie, func(attribute string) string {
+		match := cookiePathPattern.FindStringSubmatch(attribute)
+		path := match[2]
+		if hasPathPrefix(path, pathPrefix) {
+			return attribute
+		} else if path == "/" {
+			return match[1] + pathPrefix
+		}
+		return match[1] + pathPrefix + path
+	})
+}
+
+func hasPathPrefix(path string, pathPrefix string) bool {
+	return path == pathPrefix || strings.HasPrefix(path, pathPrefix+"/")
+}
+
+// removeCookie removes a cookie from the request, so that the apps can't read the session of the dashboard. The
+// other cookies are kept as they are, since apps may use values which the cookie parser of Go would reject.
+func removeCookie(r *http.Request, name string) {
+	cookieHeaders := r.Header.Values("Cookie")
+	r.Header.Del("Cookie")
+	for _, cookieHeader := range cookieHeaders {
+		var keptCookies []string
+		for _, cookie := range strings.Split(cookieHeader, ";") {
+			cookieName, _, _ := strings.Cut(cookie, "=")
+			if strings.TrimSpace(cookieName) != name {
+				keptCookies = append(keptCookies, strings.TrimSpace(cookie))
+			}
+		}
+		if len(keptCookies) > 0 {
+			r.Header.Add("Cookie", strings.Join(keptCookies, "; "))
+		}
+	}
+}
+
+func (p *AppProxy) getClientIp(r *http.Request) net.IP {
+	return getClientIpBehindProxies(r.RemoteAddr, r.Header.Values("X-Forwarded-For"), p.trustedProxies)
+}
+
+// getAuthenticatedUser returns the user of the dashboard session or API token of a request, otherwise the user name
+// of basic authentication used by some apps. The query string is deliberately not logged, since it may contain
+// credentials as well.
+func (p *AppProxy) getAuthenticatedUser(r *http.Request) string {
+	if user := p.securityModule.GetAuthenticatedUser(r); user != "" {
+		return user
+	}
+	user, _, _ := r.BasicAuth()
+	return user
+}
+
+func removePort(host string) string {
+	if hostName, _, err := net.SplitHostPort(host); err == nil {
+		return hostName
+	}
+	return host
+}
@@ -0,0 +1,496 @@
+package internal
+
+import (
+	"bufio"
+	"context"
+	"github.com/gorilla/mux"
+	"github.com/ocelot-cloud/shared/assert"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"ocelot/backend/config"
+	"ocelot/backend/security"
+	"strings"
+	"testing"
+	"time"
+)
+
+func createAppProxy(t *testing.T) (*AppProxy, *StackServiceImpl) {
+	stackService := createStackService()
+	config := &tools.GlobalConfig{RootDomain: "localhost", Port: "8080"}
+	router := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	})
+	return ProvideAppProxy(config, stackService, router, createAccessLog(t), security.ProvideSecurityModule(mux.NewRouter(), config)), stackService
+}
+
+func TestTargetStackIsDerivedFromSubdomain(t *testing.T) {
+	appProxy, _ := createAppProxy(t)
+	targetStack, ok := appProxy.getTargetStack("nginx-default.localhost")
+	assert.True(t, ok)
+	assert.Equal(t, tools.NginxDefault, targetStack)
+
+	targetStack, ok = appProxy.getTargetStack("nginx-default.localhost:8080")
+	assert.True(t, ok)
+	assert.Equal(t, tools.NginxDefault, targetStack)
+}
+
+func TestTargetStackIsDerivedFromCustomDomain(t *testing.T) {
+	appProxy, stackService := createAppProxy(t)
+	assert.Nil(t, stackService.AddStackDomain("Git.Example.org", tools.NginxDefault2))
+
+	targetStack, ok := appProxy.getTargetStack("git.example.org")
+	assert.True(t, ok)
+	assert.Equal(t, tools.NginxDefault2, targetStack)
+
+	targetStack, ok = appProxy.getTargetStack("GIT.example.org:443")
+	assert.True(t, ok)
+	assert.Equal(t, tools.NginxDefault2, targetStack)
+}
+
+func TestUnknownHostsHaveNoTargetStack(t *testing.T) {
+	appProxy, _ := createAppProxy(t)
+	for _, host := range []string{"not-existing-stack.localhost", "a.nginx-default.localhost", "nginx-default.example.org", "localhost"} {
+		_, ok := appProxy.getTargetStack(host)
+		assert.False(t, ok, host)
+	}
+}
+
+func TestUnknownHostGetsNotFoundPage(t *testing.T) {
+	appProxy, _ := createAppProxy(t)
+	request := httptest.NewRequest(http.MethodGet, "/", nil)
+	request.Host = "<script>.localhost"
+	recorder := httptest.NewRecorder()
+
+	appProxy.ServeHTTP(recorder, request)
+	assert.Equal(t, http.StatusNotFound, recorder.Code)
+	assert.True(t, strings.Contains(recorder.Body.String(), "There is no app available at <b>&lt;script&gt;.localhost</b>"))
+}
+
+func TestOcelotHostIsHandledByRouter(t *testing.T) {
+	appProxy, _ := createAppProxy(t)
+	for _, host := range []string{"ocelot-cloud.localhost", "localhost:8080"} {
+		request := httptest.NewRequest(http.MethodGet, "/api/hello", nil)
+		request.Host = host
+		recorder := httptest.NewRecorder()
+
+		appProxy.ServeHTTP(recorder, request)
+		assert.Equal(t, http.StatusTeapot, recorder.Code)
+	}
+}
+
+func TestCertificatesAreOnlyAllowedForServedHosts(t *testing.T) {
+	appProxy, stackService := createAppProxy(t)
+	assert.Nil(t, stackService.AddStackDomain("git.example.org", tools.NginxDefault))
+
+	assert.Nil(t, appProxy.HostPolicy(context.Background(), "ocelot-cloud.localhost"))
+	assert.Nil(t, appProxy.HostPolicy(context.Background(), "nginx-default.localhost"))
+	assert.Nil(t, appProxy.HostPolicy(context.Background(), "git.example.org"))
+	assert.NotNil(t, appProxy.HostPolicy(context.Background(), "wiki.example.org"))
+	assert.NotNil(t, appProxy.HostPolicy(context.Background(), "localhost"))
+
+	appProxy.config.RoutingMode = tools.RoutingModePath
+	assert.Nil(t, appProxy.HostPolicy(context.Background(), "localhost"))
+}
+
+func createPathRoutingAppProxy(t *testing.T) *AppProxy {
+	appProxy, _ := createAppProxy(t)
+	appProxy.config.RoutingMode = tools.RoutingModePath
+	return appProxy
+}
+
+func serveWithPathRouting(appProxy *AppProxy, target string) *httptest.ResponseRecorder {
+	request := httptest.NewRequest(http.MethodGet, target, nil)
+	request.Host = "example.org"
+	recorder := httptest.NewRecorder()
+	appProxy.ServeHTTP(recorder, request)
+	return recorder
+}
+
+func TestPathRoutingHandsOtherPathsToRouter(t *testing.T) {
+	appProxy := createPathRoutingAppProxy(t)
+	assert.Equal(t, http.StatusTeapot, serveWithPathRouting(appProxy, "/api/hello").Code)
+	assert.Equal(t, http.StatusTeapot, serveWithPathRouting(appProxy, "/apps/").Code)
+}
+
+func TestPathRoutingAnswersUnknownStacksWithNotFound(t *testing.T) {
+	appProxy := createPathRoutingAppProxy(t)
+	assert.Equal(t, http.StatusNotFound, serveWithPathRouting(appProxy, "/apps/not-existing-stack/").Code)
+}
+
+func TestPathRoutingRedirectsToPrefixWithTrailingSlash(t *testing.T) {
+	appProxy := createPathRoutingAppProxy(t)
+	recorder := serveWithPathRouting(appProxy, "/apps/nginx-default?page=2")
+	assert.Equal(t, http.StatusMovedPermanently, recorder.Code)
+	assert.Equal(t, "/apps/nginx-default/?page=2", recorder.Header().Get("Location"))
+}
+
+func TestGetStackFromPath(t *testing.T) {
+	testCases := []struct {
+		path          string
+		expectedStack string
+		expectedOk    bool
+	}{
+		{"/apps/gitea/explore/repos", "gitea", true},
+		{"/apps/gitea/", "gitea", true},
+		{"/apps/gitea", "gitea", true},
+		{"/apps/", "", false},
+		{"/api/stacks/read", "", false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.path, func(t *testing.T) {
+			stackName, ok := getStackFromPath(tc.path)
+			assert.Equal(t, tc.expectedStack, stackName)
+			assert.Equal(t, tc.expectedOk, ok)
+		})
+	}
+}
+
+func TestLocationIsRewrittenForPathPrefix(t *testing.T) {
+	testCases := []struct {
+		location         string
+		expectedLocation string
+	}{
+		{"/user/login", "/apps/gitea/user/login"},
+		{"/apps/gitea/user/login", "/apps/gitea/user/login"},
+		{"http://gitea:3000/user/login?redirect=%2F", "/apps/gitea/user/login?redirect=%2F"},
+		{"https://example.org/user/login", "https://example.org/apps/gitea/user/login"},
+		{"https://other.org/user/login", "https://other.org/user/login"},
+		{"login", "login"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.location, func(t *testing.T) {
+			assert.Equal(t, tc.expectedLocation, rewriteLocation(tc.location, "/apps/gitea", "example.org", "gitea:3000"))
+		})
+	}
+}
+
+func TestCookiePathIsRewrittenForPathPrefix(t *testing.T) {
+	testCases := []struct {
+		cookie         string
+		expectedCookie string
+	}{
+		{"session=abc; Path=/; HttpOnly", "session=abc; Path=/apps/gitea; HttpOnly"},
+		{"session=abc; path=/user", "session=abc; path=/apps/gitea/user"},
+		{"session=abc; Path=/apps/gitea/user", "session=abc; Path=/apps/gitea/user"},
+		{"session=abc; HttpOnly", "session=abc; HttpOnly"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.cookie, func(t *testing.T) {
+			assert.Equal(t, tc.expectedCookie, rewriteCookiePath(tc.cookie, "/apps/gitea"))
+		})
+	}
+}
+
+func TestResponsesOfAppsArePrefixed(t *testing.T) {
+	request := httptest.NewRequest(http.MethodGet, "/login", nil)
+	request.Header.Set("X-Forwarded-Host", "example.org")
+	response := &http.Response{Header: make(http.Header), Request: request}
+	response.Header.Set("Location", "/login")
+	response.Header.Add("Set-Cookie", "a=1; Path=/")
+	response.Header.Add("Set-Cookie", "b=2; Path=/admin")
+
+	assert.Nil(t, createPathPrefixRewriter("/apps/gitea", "gitea:3000")(response))
+	assert.Equal(t, "/apps/gitea/login", response.Header.Get("Location"))
+	assert.Equal(t, []string{"a=1; Path=/apps/gitea", "b=2; Path=/apps/gitea/admin"}, response.Header.Values("Set-Cookie"))
+}
+
+// routeAllConnectionsTo lets the proxy reach the given test server instead of the containers of the stacks.
+func routeAllConnectionsTo(appProxy *AppProxy, address string) {
+	appProxy.transport.DialContext = func(ctx context.Context, network string, _ string) (net.Conn, error) {
+		return (&net.Dialer{}).DialContext(ctx, network, address)
+	}
+}
+
+func startProxyServer(t *testing.T, appProxy *AppProxy) *httptest.Server {
+	server := httptest.NewServer(appProxy)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func requestStack(t *testing.T, server *httptest.Server, stackName string) *http.Response {
+	request, err := http.NewRequest(http.MethodGet, server.URL+"/", nil)
+	assert.Nil(t, err)
+	request.Host = stackName + ".localhost"
+	response, err := http.DefaultClient.Do(request)
+	assert.Nil(t, err)
+	t.Cleanup(func() { response.Body.Close() })
+	return response
+}
+
+func TestProxiesArePooledPerStack(t *testing.T) {
+	appProxy, _ := createAppProxy(t)
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "hello from "+r.Header.Get("X-Forwarded-Host"))
+	}))
+	defer upstream.Close()
+	routeAllConnectionsTo(appProxy, upstream.Listener.Addr().String())
+	server := startProxyServer(t, appProxy)
+
+	for i := 0; i < 3; i++ {
+		response := requestStack(t, server, tools.NginxDefault)
+		body, _ := io.ReadAll(response.Body)
+		assert.Equal(t, "hello from nginx-default.localhost", string(body))
+	}
+	requestStack(t, server, tools.NginxDefault2)
+	assert.Equal(t, 2, len(appProxy.proxies))
+}
+
+func TestSessionCookieIsNotPassedToApps(t *testing.T) {
+	appProxy, _ := createAppProxy(t)
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, r.Header.Get("Cookie"))
+	}))
+	defer upstream.Close()
+	routeAllConnectionsTo(appProxy, upstream.Listener.Addr().String())
+	server := startProxyServer(t, appProxy)
+
+	request, err := http.NewRequest(http.MethodGet, server.URL+"/", nil)
+	assert.Nil(t, err)
+	request.Host = "nginx-default.localhost"
+	request.Header.Set("Cookie", "lang=en; auth=valid; app_session=abc")
+	response, err := http.DefaultClient.Do(request)
+	assert.Nil(t, err)
+	defer response.Body.Close()
+	body, _ := io.ReadAll(response.Body)
+	assert.Equal(t, "lang=en; app_session=abc", string(body))
+}
+
+func TestWebSocketUpgradesArePassedThrough(t *testing.T) {
+	appProxy, _ := createAppProxy(t)
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "websocket", strings.ToLower(r.Header.Get("Upgrade")))
+		connection, buffer, err := w.(http.Hijacker).Hijack()
+		assert.Nil(t, err)
+		defer connection.Close()
+		buffer.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n\r\n")
+		buffer.Flush()
+		io.Copy(connection, buffer)
+	}))
+	defer upstream.Close()
+	routeAllConnectionsTo(appProxy, upstream.Listener.Addr().String())
+	server := startProxyServer(t, appProxy)
+
+	connection, err := net.Dial("tcp", server.Listener.Addr().String())
+	assert.Nil(t, err)
+	defer connection.Close()
+	connection.SetDeadline(time.Now().Add(5 * time.Second))
+	io.WriteString(connection, "GET /socket HTTP/1.1\r\nHost: nginx-default.localhost\r\nConnection: Upgrade\r\nUpgrade: websocket\r\n\r\n")
+
+	reader := bufio.NewReader(connection)
+	response, err := http.ReadResponse(reader, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusSwitchingProtocols, response.StatusCode)
+
+	io.WriteString(connection, "ping\n")
+	echo, err := reader.ReadString('\n')
+	assert.Nil(t, err)
+	assert.Equal(t, "ping\n", echo)
+}
+
+func TestStreamedResponsesAreFlushed(t *testing.T) {
+	appProxy, _ := createAppProxy(t)
+	releaseUpstream := make(chan bool)
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/octet-stream")
+		io.WriteString(w, "first chunk\n")
+		w.(http.Flusher).Flush()
+		<-releaseUpstream
+	}))
+	defer upstream.Close()
+	defer close(releaseUpstream)
+	routeAllConnectionsTo(appProxy, upstream.Listener.Addr().String())
+	server := startProxyServer(t, appProxy)
+
+	response := requestStack(t, server, tools.NginxDefault)
+	line, err := bufio.NewReader(response.Body).ReadString('\n')
+	assert.Nil(t, err)
+	assert.Equal(t, "first chunk\n", line)
+}
+
+func TestFailedRequestsShowStateOfApp(t *testing.T) {
+	appProxy, stackService := createAppProxy(t)
+	appProxy.stackStatesCacheTime = 0
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	listener.Close()
+	routeAllConnectionsTo(appProxy, listener.Addr().String())
+	server := startProxyServer(t, appProxy)
+
+	response := requestStack(t, server, tools.NginxDefault)
+	assert.Equal(t, http.StatusServiceUnavailable, response.StatusCode)
+	body, _ := io.ReadAll(response.Body)
+	assert.True(t, strings.Contains(string(body), "is not running"))
+
+	assert.Nil(t, stackService.DeployStack(tools.NginxSlowStart))
+	response = requestStack(t, server, tools.NginxSlowStart)
+	assert.Equal(t, http.StatusServiceUnavailable, response.StatusCode)
+	assert.Equal(t, "5", response.Header.Get("Retry-After"))
+	body, _ = io.ReadAll(response.Body)
+	assert.True(t, strings.Contains(string(body), "is starting"))
+
+	assert.Nil(t, stackService.DeployStack(tools.NginxDefault2))
+	response = requestStack(t, server, tools.NginxDefault2)
+	assert.Equal(t, http.StatusBadGateway, response.StatusCode)
+}
+
+func TestStatesOfErrorPagesAreCached(t *testing.T) {
+	appProxy, stackService := createAppProxy(t)
+	assert.Equal(t, Uninitialized, appProxy.getCachedStackState(tools.NginxDefault))
+
+	assert.Nil(t, stackService.DeployStack(tools.NginxDefault))
+	assert.Equal(t, Uninitialized, appProxy.getCachedStackState(tools.NginxDefault))
+	appProxy.stackStatesCacheTime = 0
+	assert.Equal(t, Available, appProxy.getCachedStackState(tools.NginxDefault))
+}
+
+func TestRequestsExceedingLimitsAreRejected(t *testing.T) {
+	appProxy, stackService := createAppProxy(t)
+	limits := LimitConfig{ClientRequestsPerSecond: 0.001, ClientBurst: 3, MaxRequestBodyBytes: 1024}
+	stackService.StackConfigService = &StackConfigServiceImpl{map[string]StackConfig{tools.NginxDefault: {UrlPath: "/", Port: "80", Limits: limits}}}
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+	}))
+	defer upstream.Close()
+	routeAllConnectionsTo(appProxy, upstream.Listener.Addr().String())
+	server := startProxyServer(t, appProxy)
+
+	postToStack := func(body string) int {
+		request, err := http.NewRequest(http.MethodPost, server.URL+"/", strings.NewReader(body))
+		assert.Nil(t, err)
+		request.Host = tools.NginxDefault + ".localhost"
+		response, err := http.DefaultClient.Do(request)
+		assert.Nil(t, err)
+		response.Body.Close()
+		return response.StatusCode
+	}
+
+	assert.Equal(t, http.StatusOK, postToStack("small"))
+	assert.Equal(t, http.StatusRequestEntityTooLarge, postToStack(strings.Repeat("x", 2048)))
+	assert.Equal(t, http.StatusOK, postToStack("small"))
+	assert.Equal(t, http.StatusTooManyRequests, postToStack("small"))
+	assert.Equal(t, uint64(1), appProxy.limiter.GetCounters()[tools.NginxDefault].BodyTooLarge)
+}
+
+func TestLimitsAreDisabledUnlessConfigured(t *testing.T) {
+	appProxy, _ := createAppProxy(t)
+	assert.Equal(t, NoLimits, appProxy.defaultLimits)
+
+	config := &tools.GlobalConfig{RootDomain: "localhost", Port: "8080", LimitDefaults: tools.LimitDefaults{ClientRequestsPerSecond: 50, ClientBurst: 100}}
+	appProxy = ProvideAppProxy(config, createStackService(), http.NotFoundHandler(), createAccessLog(t), security.ProvideSecurityModule(mux.NewRouter(), config))
+	assert.Equal(t, LimitConfig{RequestsPerSecond: -1, Burst: -1, ClientRequestsPerSecond: 50, ClientBurst: 100, MaxRequestBodyBytes: -1, MaxConcurrentRequests: -1}, appProxy.defaultLimits)
+}
+
+func TestDashboardAccessIsRestricted(t *testing.T) {
+	config := &tools.GlobalConfig{RootDomain: "localhost", Port: "8080", DashboardAllowList: []string{"10.0.0.0/8"}, TrustedProxies: []string{"192.0.2.1"}}
+	router := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	appProxy := ProvideAppProxy(config, createStackService(), router, createAccessLog(t), security.ProvideSecurityModule(mux.NewRouter(), config))
+
+	requestDashboard := func(remoteAddr string, forwardedFor string) int {
+		request := httptest.NewRequest(http.MethodGet, "/api/hello", nil)
+		request.Host = "ocelot-cloud.localhost"
+		request.RemoteAddr = remoteAddr
+		if forwardedFor != "" {
+			request.Header.Set("X-Forwarded-For", forwardedFor)
+		}
+		recorder := httptest.NewRecorder()
+		appProxy.ServeHTTP(recorder, request)
+		return recorder.Code
+	}
+
+	assert.Equal(t, http.StatusOK, requestDashboard("10.1.2.3:4000", ""))
+	assert.Equal(t, http.StatusForbidden, requestDashboard("203.0.113.7:4000", ""))
+	assert.Equal(t, http.StatusForbidden, requestDashboard("203.0.113.7:4000", "10.1.2.3"))
+	assert.Equal(t, http.StatusOK, requestDashboard("192.0.2.1:4000", "10.1.2.3"))
+}
+
+func TestStackAccessIsRestricted(t *testing.T) {
+	appProxy, stackService := createAppProxy(t)
+	access := AccessConfig{Deny: []string{"127.0.0.0/8"}}
+	stackService.StackConfigService = &StackConfigServiceImpl{map[string]StackConfig{tools.NginxDefault: {UrlPath: "/", Port: "80", Access: access}}}
+	server := startProxyServer(t, appProxy)
+
+	response := requestStack(t, server, tools.NginxDefault)
+	assert.Equal(t, http.StatusForbidden, response.StatusCode)
+}
+
+func TestStackAccessIsParsedOnce(t *testing.T) {
+	appProxy, stackService := createAppProxy(t)
+	access := AccessConfig{Allow: []string{"127.0.0.1"}}
+	stackService.StackConfigService = &StackConfigServiceImpl{map[string]StackConfig{tools.NginxDefault: {UrlPath: "/", Port: "80", Access: access}}}
+
+	filter, err := appProxy.getStackFilter(tools.NginxDefault, access)
+	assert.Nil(t, err)
+	assert.True(t, filter.isAllowed(net.ParseIP("127.0.0.1")))
+	appProxy.stackFilters[tools.NginxDefault] = stackFilter{access: access}
+	filter, _ = appProxy.getStackFilter(tools.NginxDefault, access)
+	assert.True(t, filter.isAllowed(net.ParseIP("192.0.2.1")))
+
+	filter, _ = appProxy.getStackFilter(tools.NginxDefault, AccessConfig{Allow: []string{"127.0.0.2"}})
+	assert.False(t, filter.isAllowed(net.ParseIP("127.0.0.1")))
+}
+
+func TestInvalidStackAccessIsAnsweredWithError(t *testing.T) {
+	appProxy, stackService := createAppProxy(t)
+	access := AccessConfig{Allow: []string{"office"}}
+	stackService.StackConfigService = &StackConfigServiceImpl{map[string]StackConfig{tools.NginxDefault: {UrlPath: "/", Port: "80", Access: access}}}
+	server := startProxyServer(t, appProxy)
+
+	response := requestStack(t, server, tools.NginxDefault)
+	assert.Equal(t, http.StatusInternalServerError, response.StatusCode)
+}
+
+func TestProxiedRequestsAreLogged(t *testing.T) {
+	appProxy, _ := createAppProxy(t)
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+		io.WriteString(w, "created")
+	}))
+	defer upstream.Close()
+	routeAllConnectionsTo(appProxy, upstream.Listener.Addr().String())
+	server := startProxyServer(t, appProxy)
+
+	request, err := http.NewRequest(http.MethodPut, server.URL+"/items/1?token=secret", nil)
+	assert.Nil(t, err)
+	request.Host = "nginx-default.localhost"
+	request.SetBasicAuth("alice", "password")
+	response, err := http.DefaultClient.Do(request)
+	assert.Nil(t, err)
+	io.ReadAll(response.Body)
+	response.Body.Close()
+
+	entries := appProxy.accessLog.GetRecentEntries(tools.NginxDefault)
+	assert.Equal(t, 1, len(entries))
+	assert.Equal(t, "nginx-default.localhost", entries[0].Host)
+	assert.Equal(t, http.MethodPut, entries[0].Method)
+	assert.Equal(t, "/items/1", entries[0].Path)
+	assert.Equal(t, http.StatusCreated, entries[0].Status)
+	assert.Equal(t, int64(7), entries[0].Bytes)
+	assert.Equal(t, "127.0.0.1", entries[0].ClientIp)
+	assert.Equal(t, "alice", entries[0].User)
+}
+
+func TestUserOfDashboardSessionIsLogged(t *testing.T) {
+	appProxy, _ := createAppProxy(t)
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer upstream.Close()
+	routeAllConnectionsTo(appProxy, upstream.Listener.Addr().String())
+	server := startProxyServer(t, appProxy)
+
+	request, err := http.NewRequest(http.MethodGet, server.URL+"/", nil)
+	assert.Nil(t, err)
+	request.Host = "nginx-default.localhost"
+	request.SetBasicAuth("alice", "password")
+	request.AddCookie(&http.Cookie{Name: security.SessionCookieName, Value: "valid"})
+	response, err := http.DefaultClient.Do(request)
+	assert.Nil(t, err)
+	response.Body.Close()
+
+	entries := appProxy.accessLog.GetRecentEntries(tools.NginxDefault)
+	assert.Equal(t, 1, len(entries))
+	assert.Equal(t, "admin", entries[0].User)
+}
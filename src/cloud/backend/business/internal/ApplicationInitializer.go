@@ -1,13 +1,15 @@
 package internal
 
 import (
+	"database/sql"
 	"github.com/gorilla/mux" // TODO To be wrapped?
 	"github.com/ocelot-cloud/shared"
+	"golang.org/x/crypto/acme/autocert"
 	"net/http"
-	"net/http/httputil"
-	"net/url"
 	"ocelot/backend/config"
 	"ocelot/backend/security"
+	"os"
+	"path/filepath"
 	"strings"
 )
 
@@ -17,16 +19,25 @@ type ApplicationInitializer struct {
 	stackService       StackService
 	config             *tools.GlobalConfig
 	stackConfigService StackConfigService
+	database           *sql.DB
+	accessLog          *AccessLog
 }
 
 func ProvideAppInitializer(router *mux.Router, config *tools.GlobalConfig, securityModule *security.SecurityModule) ApplicationInitializer {
-	return ApplicationInitializer{securityModule, router, nil, config, nil}
+	return ApplicationInitializer{securityModule, router, nil, config, nil, nil, nil}
 }
 
 func (a *ApplicationInitializer) InitializeApplicationInternally() {
 	StackFileDir = a.getStackFileDir()
+	a.database = ProvideDatabase(DataDir)
+	a.securityModule.UseDatabase(a.database)
+	a.accessLog = a.getAccessLog()
 	a.stackConfigService = ProvideStackConfigService(StackFileDir)
 	a.stackService = a.getStackService(a.stackConfigService)
+	a.stackService.StartBackgroundJobs()
+	a.securityModule.AddLoginFailureListener(ProvideLoginFailurePublisher(a.stackService).Publish)
+	ProvideHostMonitor(a.stackService, a.getCertificateHosts()).StartPeriodicChecks(HostCheckInterval)
+	registerStackStateCollector(a.stackService)
 	a.initializeDockerNetwork()
 	a.initializeHandlers()
 }
@@ -45,8 +56,30 @@ func (a *ApplicationInitializer) getStackService(stackConfigService StackConfigS
 		return ProvideStackServiceMocked(stackConfigService)
 	} else {
 		Logger.Debug("Using real DockerService")
-		return ProvideStackServiceReal(stackConfigService)
+		stores := ProvideStackStores(a.database, DataDir)
+		emailNotifier := ProvideEmailNotifier(stores.EmailSubscriptionService, a.config.Smtp, a.config.RootDomain)
+		if a.config.Smtp.Host == "" {
+			Logger.Info("Email alerts are disabled, since no SMTP server is configured")
+		}
+		return ProvideStackServiceReal(stackConfigService, stores, emailNotifier)
+	}
+}
+
+// getCertificateHosts returns the hosts whose TLS certificates are checked, which is only done when ocelot is
+// served via https. The custom domains of the stacks are checked as well.
+func (a *ApplicationInitializer) getCertificateHosts() []string {
+	if a.config.Scheme != "https" {
+		return nil
 	}
+	return []string{a.config.RootDomain}
+}
+
+func (a *ApplicationInitializer) getAccessLog() *AccessLog {
+	accessLogPath := filepath.Join(DataDir, "logs", AccessLogFileName)
+	if a.config.IsAccessLogPrintedToStdout {
+		return ProvideAccessLog(accessLogPath, os.Stdout)
+	}
+	return ProvideAccessLog(accessLogPath, nil)
 }
 
 func (a *ApplicationInitializer) initializeDockerNetwork() {
@@ -56,45 +89,40 @@ func (a *ApplicationInitializer) initializeDockerNetwork() {
 
 func (a *ApplicationInitializer) initializeHandlers() {
 	a.initializeFunctionalEndpoints()
-	proxyHandler := a.buildProxyHandler()
+	appProxy := ProvideAppProxy(a.config, a.stackService, a.router, a.accessLog, a.securityModule)
+	a.securityModule.UseClientIpResolver(appProxy.getClientIp)
+	if a.config.IsTlsEnabled {
+		a.serveWithTls(appProxy)
+		return
+	}
 	Logger.Info("Starting server listening on port " + a.config.Port)
-	err := http.ListenAndServe(":"+a.config.Port, http.HandlerFunc(proxyHandler))
+	err := http.ListenAndServe(":"+a.config.Port, appProxy)
 	if err != nil {
 		Logger.Fatal("Failed to start server: " + err.Error())
 	}
 }
 
-func (a *ApplicationInitializer) buildProxyHandler() func(w http.ResponseWriter, r *http.Request) {
-	handler := func(w http.ResponseWriter, r *http.Request) {
-		ocelotDomain := "ocelot-cloud." + a.config.RootDomain
-		localDomain := a.config.RootDomain + ":" + a.config.Port
-		if r.Host == ocelotDomain || r.Host == localDomain {
-			a.router.ServeHTTP(w, r)
-		} else {
-			a.proxyRequestToTheDockerContainer(w, r)
-		}
+// serveWithTls serves HTTPS with certificates from Let's Encrypt, which are only requested for the hosts accepted
+// by the host policy of the proxy. The HTTP port answers the ACME challenges and redirects all other requests.
+func (a *ApplicationInitializer) serveWithTls(appProxy *AppProxy) {
+	certificateManager := &autocert.Manager{
+		Prompt:     autocert.AcceptTOS,
+		HostPolicy: appProxy.HostPolicy,
+		Cache:      autocert.DirCache(filepath.Join(DataDir, CertificateCacheDirName)),
+		Email:      a.config.AcmeEmail,
 	}
-	return handler
-}
+	go func() {
+		Logger.Info("Starting HTTP redirect listening on port " + a.config.Port)
+		if err := http.ListenAndServe(":"+a.config.Port, certificateManager.HTTPHandler(nil)); err != nil {
+			Logger.Fatal("Failed to start HTTP redirect: " + err.Error())
+		}
+	}()
 
-// TODO Make sure to remove the ocelot cookie before proxying a request to the service behind, so that it can't read/steal it.
-func (a *ApplicationInitializer) proxyRequestToTheDockerContainer(w http.ResponseWriter, r *http.Request) {
-	Logger.Trace("Proxying request with target host %s", r.Host)
-	targetContainer := strings.TrimSuffix(r.Host, "."+a.config.RootDomain)
-	targetPort := a.stackConfigService.GetStackConfig(targetContainer).Port
-	targetURL, err := url.Parse("http://" + targetContainer + ":" + targetPort)
-	if err != nil {
-		Logger.Error("error when parsing URL, %s", err.Error())
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
-		return
+	server := &http.Server{Addr: ":" + a.config.TlsPort, Handler: appProxy, TLSConfig: certificateManager.TLSConfig()}
+	Logger.Info("Starting server listening on port " + a.config.TlsPort)
+	if err := server.ListenAndServeTLS("", ""); err != nil {
+		Logger.Fatal("Failed to start server: " + err.Error())
 	}
-
-	// the path of original request is preserved
-	proxy := httputil.NewSingleHostReverseProxy(targetURL)
-	r.URL.Host = targetContainer
-	r.URL.Scheme = "http"
-	r.Header.Set("X-Forwarded-Host", r.Host)
-	proxy.ServeHTTP(w, r)
 }
 
 func (a *ApplicationInitializer) initializeFunctionalEndpoints() {
@@ -102,9 +130,17 @@ func (a *ApplicationInitializer) initializeFunctionalEndpoints() {
 	api.HandleFunc("/check-session", checkSessionHandler).Methods("GET")
 	api.HandleFunc("/hello", a.helloHandler)
 
-	a.registerSecuredEndpoint("/stacks/read", createReadHandler(a.stackService))
-	a.registerSecuredEndpoint("/stacks/deploy", createDeployHandler(a.stackService))
-	a.registerSecuredEndpoint("/stacks/stop", createStopHandler(a.stackService))
+	a.registerDeprecatedEndpoint("/stacks/read", "/stacks", createReadHandler(a.stackService))
+	a.registerDeprecatedEndpoint("/stacks/deploy", "/stacks/{name}/deploy", createDeployHandler(a.stackService))
+	a.registerDeprecatedEndpoint("/stacks/stop", "/stacks/{name}/stop", createStopHandler(a.stackService))
+	registerApiV1(a.router, getApiV1Routes(a.stackService, a.accessLog, a.securityModule.GetTokenHandlers()), a.securityModule.ApplyAuthMiddlewares)
+
+	if a.config.MetricsToken != "" {
+		a.router.Handle("/metrics", createMetricsHandler(a.config.MetricsToken))
+	} else {
+		Logger.Info("Metrics endpoint is disabled, since no metrics token is configured")
+	}
+	a.router.Use(instrumentRoute)
 
 	if a.config.IsGuiEnabled {
 		a.InitializeFrontendResourceDelivery()
@@ -133,6 +169,10 @@ func (a *ApplicationInitializer) InitializeFrontendResourceDelivery() {
 	})))
 }
 
+func (a *ApplicationInitializer) registerDeprecatedEndpoint(path string, successorPath string, handlerFunc http.HandlerFunc) {
+	a.registerSecuredEndpoint(path, markAsDeprecated(successorPath, handlerFunc))
+}
+
 func (a *ApplicationInitializer) registerSecuredEndpoint(path string, handlerFunc http.HandlerFunc) {
 	a.router.Handle("/api"+path, a.securityModule.ApplyAuthMiddlewares(handlerFunc))
 }
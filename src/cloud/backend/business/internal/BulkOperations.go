@@ -0,0 +1,158 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+const (
+	BulkDeploy  = "deploy"
+	BulkStop    = "stop"
+	BulkRestart = "restart"
+)
+
+// StackOperationResult is the outcome of a bulk operation for a single stack. Err is nil on success.
+type StackOperationResult struct {
+	StackName string
+	Err       error
+}
+
+// RunBulkOperation deploys, stops or restarts several stacks. If none are given, all stacks which are supposed
+// to run but are stopped are deployed, and all running stacks are stopped or restarted respectively. Stacks are
+// processed concurrently, up to BulkOperationConcurrency at once, but dependencies are deployed and restarted
+// before and stopped after the stacks depending on them. A failure only affects the result of the respective stack.
+func (sm *StackServiceImpl) RunBulkOperation(operation string, stackNames []string) ([]StackOperationResult, error) {
+	if operation != BulkDeploy && operation != BulkStop && operation != BulkRestart {
+		return nil, newStackError(ErrInvalidRequest, "", "unknown bulk operation '%s'", operation)
+	}
+	if len(stackNames) == 0 {
+		var err error
+		if stackNames, err = sm.getStacksForBulkOperation(operation); err != nil {
+			return nil, err
+		}
+	}
+
+	errorsOfStacks := make(map[string]error)
+	var existingStackNames []string
+	for _, stackName := range stackNames {
+		if _, ok := errorsOfStacks[stackName]; ok || contains(existingStackNames, stackName) {
+			continue
+		} else if !sm.StackExists(stackName) {
+			errorsOfStacks[stackName] = logAndCreateStackNotFoundError(stackName)
+		} else {
+			existingStackNames = append(existingStackNames, stackName)
+		}
+	}
+
+	levels := sm.getDependencyLevels(existingStackNames)
+	switch operation {
+	case BulkDeploy:
+		for _, level := range levels {
+			sm.runConcurrently(level, sm.DeployStack, errorsOfStacks)
+		}
+	case BulkRestart:
+		for _, level := range levels {
+			sm.runConcurrently(level, sm.RestartStack, errorsOfStacks)
+		}
+	case BulkStop:
+		for i := len(levels) - 1; i >= 0; i-- {
+			sm.runConcurrently(levels[i], sm.StopStack, errorsOfStacks)
+		}
+	}
+
+	var results []StackOperationResult
+	for stackName, err := range errorsOfStacks {
+		results = append(results, StackOperationResult{StackName: stackName, Err: err})
+	}
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].StackName < results[j].StackName
+	})
+	return results, nil
+}
+
+// getStacksForBulkOperation selects the running stacks for stopping and restarting. For deployment, it selects
+// the stopped stacks whose desired state is running, so that stacks which were never deployed or deliberately
+// stopped are left alone.
+func (sm *StackServiceImpl) getStacksForBulkOperation(operation string) ([]string, error) {
+	desiredStates, err := sm.DesiredStateService.GetDesiredStates()
+	if err != nil {
+		return nil, err
+	}
+	var stackNames []string
+	for stackName, stackDetails := range sm.GetStackStateInfo() {
+		isStopped := stackDetails.State == Uninitialized
+		if operation == BulkDeploy && isStopped && desiredStates[stackName] {
+			stackNames = append(stackNames, stackName)
+		} else if operation != BulkDeploy && !isStopped {
+			stackNames = append(stackNames, stackName)
+		}
+	}
+	return stackNames, nil
+}
+
+// getDependencyLevels groups stacks by the length of their longest dependency chain, so that all stacks of a
+// level only depend on stacks of lower levels.
+func (sm *StackServiceImpl) getDependencyLevels(stackNames []string) [][]string {
+	depths := make(map[string]int)
+	var getDepth func(stackName string) int
+	getDepth = func(stackName string) int {
+		if depth, ok := depths[stackName]; ok {
+			return depth
+		}
+		depth := 0
+		for _, dependency := range sm.GetStackConfig(stackName).DependsOn {
+			if dependencyDepth := getDepth(dependency) + 1; dependencyDepth > depth {
+				depth = dependencyDepth
+			}
+		}
+		depths[stackName] = depth
+		return depth
+	}
+
+	var levels [][]string
+	sort.Strings(stackNames)
+	for _, stackName := range stackNames {
+		depth := getDepth(stackName)
+		for len(levels) <= depth {
+			levels = append(levels, nil)
+		}
+		levels[depth] = append(levels[depth], stackName)
+	}
+	return levels
+}
+
+func (sm *StackServiceImpl) runConcurrently(stackNames []string, action func(stackName string) error, errorsOfStacks map[string]error) {
+	var mu sync.Mutex
+	var waitGroup sync.WaitGroup
+	semaphore := make(chan struct{}, BulkOperationConcurrency)
+	for _, stackName := range stackNames {
+		waitGroup.Add(1)
+		semaphore <- struct{}{}
+		go func(stackName string) {
+			defer waitGroup.Done()
+			defer func() { <-semaphore }()
+			err := action(stackName)
+			mu.Lock()
+			defer mu.Unlock()
+			errorsOfStacks[stackName] = err
+		}(stackName)
+	}
+	waitGroup.Wait()
+}
+
+// StopAllStacks stops all running stacks and reports the stacks which could not be stopped.
+func (sm *StackServiceImpl) StopAllStacks() error {
+	results, err := sm.RunBulkOperation(BulkStop, nil)
+	if err != nil {
+		return err
+	}
+	var stopErrors []error
+	for _, result := range results {
+		if result.Err != nil {
+			stopErrors = append(stopErrors, fmt.Errorf("stack '%s': %w", result.StackName, result.Err))
+		}
+	}
+	return errors.Join(stopErrors...)
+}
@@ -0,0 +1,78 @@
+package internal
+
+import (
+	"github.com/ocelot-cloud/shared/assert"
+	"ocelot/backend/config"
+	"testing"
+)
+
+func getFailedStacks(results []StackOperationResult) []string {
+	var failedStacks []string
+	for _, result := range results {
+		if result.Err != nil {
+			failedStacks = append(failedStacks, result.StackName)
+		}
+	}
+	return failedStacks
+}
+
+func TestBulkDeploymentContinuesAfterFailures(t *testing.T) {
+	stackService := createStackService()
+
+	results, err := stackService.RunBulkOperation(BulkDeploy, []string{tools.NginxDefault, "not-existing-stack", tools.NginxDefault2})
+	assert.Nil(t, err)
+	assert.Equal(t, 3, len(results))
+	assert.Equal(t, []string{"not-existing-stack"}, getFailedStacks(results))
+
+	stackStateInfo := stackService.GetStackStateInfo()
+	assertState(t, stackStateInfo, tools.NginxDefault, Available)
+	assertState(t, stackStateInfo, tools.NginxDefault2, Available)
+}
+
+func TestBulkOperationsOnAllStacks(t *testing.T) {
+	stackService := createStackService()
+	BulkOperationConcurrency = 2
+	defer func() { BulkOperationConcurrency = 3 }()
+
+	_, err := stackService.RunBulkOperation(BulkDeploy, []string{tools.NginxDefault, tools.NginxDefault2, tools.NginxCustomPath})
+	assert.Nil(t, err)
+	assert.Nil(t, stackService.StopStack(tools.NginxCustomPath))
+	// simulates a reboot of the host, after which the stacks are not running anymore, but supposed to
+	assert.Nil(t, stackService.DockerService.StopStack(tools.NginxDefault))
+	assert.Nil(t, stackService.DockerService.StopStack(tools.NginxDefault2))
+	stackService.lastActionOnStack = make(map[string]StackAction)
+
+	results, err := stackService.RunBulkOperation(BulkDeploy, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(results))
+	stackStateInfo := stackService.GetStackStateInfo()
+	assertState(t, stackStateInfo, tools.NginxDefault, Available)
+	assertState(t, stackStateInfo, tools.NginxDefault2, Available)
+	assertState(t, stackStateInfo, tools.NginxCustomPath, Uninitialized)
+	assertState(t, stackStateInfo, tools.NginxCustomPort, Uninitialized)
+
+	assert.Nil(t, stackService.StopAllStacks())
+	for stackName, stackDetails := range stackService.GetStackStateInfo() {
+		assert.Equal(t, Uninitialized, stackDetails.State, stackName)
+	}
+}
+
+func TestBulkOperationsFollowDependencyOrder(t *testing.T) {
+	stackService := createStackServiceWithDependencies()
+	assert.Nil(t, stackService.DeployStack(tools.NginxDefault2))
+	assert.Equal(t, [][]string{{tools.NginxSlowStart}, {tools.NginxDefault}, {tools.NginxDefault2}}, stackService.getDependencyLevels([]string{tools.NginxDefault2, tools.NginxDefault, tools.NginxSlowStart}))
+
+	results, err := stackService.RunBulkOperation(BulkRestart, []string{tools.NginxSlowStart, tools.NginxDefault, tools.NginxDefault2})
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(getFailedStacks(results)))
+	assertState(t, stackService.GetStackStateInfo(), tools.NginxDefault2, Available)
+
+	results, err = stackService.RunBulkOperation(BulkStop, []string{tools.NginxSlowStart})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{tools.NginxSlowStart}, getFailedStacks(results))
+}
+
+func TestUnknownBulkOperationIsRejected(t *testing.T) {
+	_, err := createStackService().RunBulkOperation("delete", nil)
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,156 @@
+package internal
+
+import (
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+type ComposeFile struct {
+	Services map[string]ComposeService `yaml:"services"`
+	Networks map[string]ComposeNetwork `yaml:"networks"`
+}
+
+type ComposeService struct {
+	Image         string    `yaml:"image"`
+	ContainerName string    `yaml:"container_name"`
+	Networks      yaml.Node `yaml:"networks"`
+	Ports         yaml.Node `yaml:"ports"`
+}
+
+type ComposeNetwork struct {
+	External bool `yaml:"external"`
+}
+
+func readComposeFile(composeFilePath string) (ComposeFile, error) {
+	var composeFile ComposeFile
+	content, err := os.ReadFile(composeFilePath)
+	if err != nil {
+		return composeFile, err
+	}
+	if err = yaml.Unmarshal(content, &composeFile); err != nil {
+		return composeFile, fmt.Errorf("error when unmarshalling %s: %w", composeFilePath, err)
+	}
+	return composeFile, nil
+}
+
+// getNetworkNames supports both the list and the map notation of the networks of a service.
+func (s ComposeService) getNetworkNames() []string {
+	var networkNames []string
+	switch s.Networks.Kind {
+	case yaml.SequenceNode:
+		for _, node := range s.Networks.Content {
+			networkNames = append(networkNames, node.Value)
+		}
+	case yaml.MappingNode:
+		for i := 0; i < len(s.Networks.Content); i += 2 {
+			networkNames = append(networkNames, s.Networks.Content[i].Value)
+		}
+	default:
+		networkNames = append(networkNames, "default")
+	}
+	return networkNames
+}
+
+// getHostPortBindings returns the ports of a service which are bound to a fixed port of the host, like "2222:22"
+// in the short or "published: 2222" in the long syntax. Ports without a host port get a random one from docker.
+func (s ComposeService) getHostPortBindings() []string {
+	var bindings []string
+	for _, node := range s.Ports.Content {
+		if node.Kind == yaml.ScalarNode && strings.Contains(node.Value, ":") {
+			bindings = append(bindings, node.Value)
+		} else if node.Kind == yaml.MappingNode {
+			for i := 0; i+1 < len(node.Content); i += 2 {
+				if node.Content[i].Value == "published" && node.Content[i+1].Value != "" {
+					bindings = append(bindings, node.Content[i+1].Value)
+				}
+			}
+		}
+	}
+	return bindings
+}
+
+// getHostPortBindings returns the host port bindings of all services of the compose file.
+func (c ComposeFile) getHostPortBindings() []string {
+	var bindings []string
+	for _, serviceName := range c.getSortedServiceNames() {
+		bindings = append(bindings, c.Services[serviceName].getHostPortBindings()...)
+	}
+	return bindings
+}
+
+func (c ComposeFile) getSortedServiceNames() []string {
+	var serviceNames []string
+	for serviceName := range c.Services {
+		serviceNames = append(serviceNames, serviceName)
+	}
+	sort.Strings(serviceNames)
+	return serviceNames
+}
+
+func (c ComposeFile) isExternalNetwork(networkName string) bool {
+	network, ok := c.Networks[networkName]
+	return ok && network.External
+}
+
+// getInstanceContainerName derives a unique container name for an instance, e.g. for the template "discourse"
+// and the instance "discourse-team-a" the container "discourse-postgresql" becomes "discourse-team-a-postgresql".
+func getInstanceContainerName(containerName string, instanceName string, templateName string) string {
+	if containerName == templateName {
+		return instanceName
+	} else if strings.HasPrefix(containerName, templateName+"-") {
+		return instanceName + strings.TrimPrefix(containerName, templateName)
+	} else {
+		return instanceName + "-" + containerName
+	}
+}
+
+// generateInstanceOverride creates a compose override file, which renames the containers of a template, so
+// that several instances can run side by side. The former container names are kept as aliases in the
+// internal networks of the instance, since they are often used as host names by the other services of a stack.
+func generateInstanceOverride(composeFile ComposeFile, instanceName string, templateName string) ([]byte, error) {
+	services := make(map[string]interface{})
+	for _, serviceName := range composeFile.getSortedServiceNames() {
+		service := composeFile.Services[serviceName]
+		if service.ContainerName == "" {
+			continue
+		}
+
+		networks := make(map[string]interface{})
+		for _, networkName := range service.getNetworkNames() {
+			if !composeFile.isExternalNetwork(networkName) {
+				networks[networkName] = map[string]interface{}{"aliases": []string{service.ContainerName}}
+			}
+		}
+
+		serviceOverride := map[string]interface{}{"container_name": getInstanceContainerName(service.ContainerName, instanceName, templateName)}
+		if len(networks) > 0 {
+			serviceOverride["networks"] = networks
+		}
+		services[serviceName] = serviceOverride
+	}
+	return yaml.Marshal(map[string]interface{}{"services": services})
+}
+
+func getInstanceOverridePath(instanceName string) string {
+	return filepath.Join(DataDir, "instances", instanceName, "docker-compose.override.yml")
+}
+
+func writeInstanceOverrideFile(instanceName string, templateName string, composeFilePath string) error {
+	composeFile, err := readComposeFile(composeFilePath)
+	if err != nil {
+		return err
+	}
+	override, err := generateInstanceOverride(composeFile, instanceName, templateName)
+	if err != nil {
+		return err
+	}
+	overridePath := getInstanceOverridePath(instanceName)
+	if err = os.MkdirAll(filepath.Dir(overridePath), 0700); err != nil {
+		return err
+	}
+	return os.WriteFile(overridePath, override, 0600)
+}
@@ -0,0 +1,89 @@
+package internal
+
+import (
+	"github.com/ocelot-cloud/shared/assert"
+	"gopkg.in/yaml.v3"
+	"testing"
+)
+
+const sampleComposeFile = `
+services:
+  app:
+    image: sample/app:1.0.0
+    container_name: sample
+    networks:
+      - ocelot-net
+      - sample-net
+  db:
+    image: postgres:16
+    container_name: sample-db
+    networks:
+      sample-net:
+  cache:
+    image: redis:7
+    container_name: cache
+  worker:
+    image: sample/worker:1.0.0
+
+networks:
+  sample-net:
+  ocelot-net:
+    external: true
+`
+
+func TestGenerateInstanceOverride(t *testing.T) {
+	var composeFile ComposeFile
+	assert.Nil(t, yaml.Unmarshal([]byte(sampleComposeFile), &composeFile))
+
+	overrideBytes, err := generateInstanceOverride(composeFile, "sample-team-a", "sample")
+	assert.Nil(t, err)
+
+	var override ComposeFile
+	assert.Nil(t, yaml.Unmarshal(overrideBytes, &override))
+	assert.Equal(t, 3, len(override.Services))
+	assert.Equal(t, "sample-team-a", override.Services["app"].ContainerName)
+	assert.Equal(t, "sample-team-a-db", override.Services["db"].ContainerName)
+	assert.Equal(t, "sample-team-a-cache", override.Services["cache"].ContainerName)
+	assert.Equal(t, []string{"sample-net"}, override.Services["app"].getNetworkNames())
+	assert.Equal(t, []string{"sample-net"}, override.Services["db"].getNetworkNames())
+	assert.Equal(t, []string{"default"}, override.Services["cache"].getNetworkNames())
+}
+
+func TestInstanceOverrideKeepsFormerContainerNamesAsAliases(t *testing.T) {
+	var composeFile ComposeFile
+	assert.Nil(t, yaml.Unmarshal([]byte(sampleComposeFile), &composeFile))
+
+	overrideBytes, err := generateInstanceOverride(composeFile, "sample-team-a", "sample")
+	assert.Nil(t, err)
+
+	var override struct {
+		Services map[string]struct {
+			Networks map[string]struct {
+				Aliases []string `yaml:"aliases"`
+			} `yaml:"networks"`
+		} `yaml:"services"`
+	}
+	assert.Nil(t, yaml.Unmarshal(overrideBytes, &override))
+	assert.Equal(t, []string{"sample-db"}, override.Services["db"].Networks["sample-net"].Aliases)
+	assert.Equal(t, []string{"cache"}, override.Services["cache"].Networks["default"].Aliases)
+}
+
+func TestHostPortBindingsAreDetected(t *testing.T) {
+	var composeFile ComposeFile
+	assert.Nil(t, yaml.Unmarshal([]byte(`
+services:
+  app:
+    ports:
+      - "2222:22"
+      - "8080"
+      - target: 443
+        published: "8443"
+  db:
+    ports:
+      - target: 5432
+`), &composeFile))
+	assert.Equal(t, []string{"2222:22", "8443"}, composeFile.getHostPortBindings())
+
+	assert.Nil(t, yaml.Unmarshal([]byte(sampleComposeFile), &composeFile))
+	assert.Equal(t, 0, len(composeFile.getHostPortBindings()))
+}
@@ -2,8 +2,60 @@ package internal
 
 import (
 	"github.com/ocelot-cloud/shared"
+	"github.com/prometheus/client_golang/prometheus"
+	"time"
 )
 
 var Logger = shared.ProvideLogger()
 var StackFileDir string
 var CoreStackFileDir = "stacks/core"
+var DataDir = "data"
+var DatabaseFileName = "ocelot.db"
+var SecretKeyFileName = "secret.key"
+var CertificateCacheDirName = "certificates"
+var ImageUpdateCheckInterval = 6 * time.Hour
+var AppPathPrefix = "/apps/"
+
+// NoLimits disables all limits of an app, which applies unless limits are configured via CLI or in the app.yml.
+var NoLimits = LimitConfig{
+	RequestsPerSecond:       -1,
+	Burst:                   -1,
+	ClientRequestsPerSecond: -1,
+	ClientBurst:             -1,
+	MaxRequestBodyBytes:     -1,
+	MaxConcurrentRequests:   -1,
+}
+
+const proxyDialTimeout = 10 * time.Second
+const proxyResponseHeaderTimeout = 5 * time.Minute
+const proxyFlushInterval = 100 * time.Millisecond
+
+var AccessLogFileName = "access.log"
+var AccessLogMaxFileSize int64 = 10 << 20
+var AccessLogMaxBackups = 5
+var AccessLogRecentEntriesPerStack = 100
+var Metrics = prometheus.NewRegistry()
+var StatsSampleInterval = 30 * time.Second
+var StatsHistorySize = 60
+var DiskUsageWarningPercent = 85.0
+var MemoryUsageWarningPercent = 90.0
+var LoadWarningPerCpu = 1.5
+var WatchdogCheckInterval = 30 * time.Second
+var UnhealthyThreshold = 2 * time.Minute
+var WatchdogMaxRestarts = 3
+var WatchdogInitialBackoff = 30 * time.Second
+var WatchdogMaxBackoff = 10 * time.Minute
+var ReconciliationInterval = 5 * time.Minute
+var BulkOperationConcurrency = 3
+var StateObservationInterval = 10 * time.Second
+var ProxyErrorStateCacheTime = 5 * time.Second
+var WebhookMaxAttempts = 5
+var WebhookInitialBackoff = 10 * time.Second
+var WebhookMaxBackoff = 5 * time.Minute
+var WebhookTimeout = 10 * time.Second
+var WebhookDeliveryLogSize = 100
+var LoginFailureEventsPerMinute = 1.0
+var LoginFailureEventBurst = 5
+var HostCheckInterval = time.Hour
+var CertificateExpiryWarning = 14 * 24 * time.Hour
+var EmailTimeout = 30 * time.Second
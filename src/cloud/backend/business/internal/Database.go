@@ -0,0 +1,38 @@
+package internal
+
+import (
+	"database/sql"
+	_ "github.com/mattn/go-sqlite3"
+	"os"
+	"path/filepath"
+)
+
+func ProvideDatabase(dataDir string) *sql.DB {
+	if err := os.MkdirAll(dataDir, 0700); err != nil {
+		Logger.Fatal("error when creating data directory %s: %v", dataDir, err)
+	}
+
+	databaseFile := filepath.Join(dataDir, DatabaseFileName)
+	db, err := sql.Open("sqlite3", databaseFile)
+	if err != nil {
+		Logger.Fatal("failed to open database %s: %v", databaseFile, err)
+	}
+	return db
+}
+
+// ProvideInMemoryDatabase provides a database which starts empty and is discarded with the process. It is limited
+// to one connection, since each connection to ":memory:" would open a database of its own.
+func ProvideInMemoryDatabase() *sql.DB {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		Logger.Fatal("failed to open in-memory database: %v", err)
+	}
+	db.SetMaxOpenConns(1)
+	return db
+}
+
+func createTableIfNotExisting(db *sql.DB, tableDefinition string) {
+	if _, err := db.Exec("CREATE TABLE IF NOT EXISTS " + tableDefinition); err != nil {
+		Logger.Fatal("failed to create table: %v", err)
+	}
+}
@@ -1,23 +1,40 @@
 package internal
 
 import (
+	"context"
 	"fmt"
+	"io"
 	"ocelot/backend/config"
+	"sync"
 )
 
 type DockerServiceMock struct {
+	mu                           sync.Mutex
 	stackStates                  map[string]StackState
 	hasWaitedToPassDownloadState bool
+	// neverAvailableStacks simulates stacks whose containers do not become healthy after a regular deployment.
+	neverAvailableStacks map[string]bool
+	backedUpStacks       []string
+	restoredStacks       []string
+	stackImages          map[string][]StackImage
+	// unhealthyContainers simulates containers failing their health checks, restarts don't change that.
+	unhealthyContainers map[string][]string
+	restartedContainers []string
+	removedVolumes      []string
+	// isUnavailable simulates a docker daemon which can't be reached, e.g. while it is restarted.
+	isUnavailable bool
 }
 
 func ProvideServiceMock() *DockerServiceMock {
-	return &DockerServiceMock{stackStates: make(map[string]StackState), hasWaitedToPassDownloadState: false}
+	return &DockerServiceMock{stackStates: make(map[string]StackState), hasWaitedToPassDownloadState: false, neverAvailableStacks: make(map[string]bool), stackImages: make(map[string][]StackImage), unhealthyContainers: make(map[string][]string)}
 }
 
-func (d *DockerServiceMock) DeployStack(stackName string) error {
+func (d *DockerServiceMock) DeployStack(stackName string, environment map[string]string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
 	if stackName == "not-existing-stack" {
 		return logAndCreateStackNotFoundError(stackName)
-	} else if stackName == tools.NginxSlowStart || stackName == tools.NginxDownloading {
+	} else if stackName == tools.NginxSlowStart || stackName == tools.NginxDownloading || d.neverAvailableStacks[stackName] {
 		d.stackStates[stackName] = Starting
 	} else {
 		d.stackStates[stackName] = Available
@@ -27,18 +44,55 @@ func (d *DockerServiceMock) DeployStack(stackName string) error {
 	return nil
 }
 
+func (d *DockerServiceMock) DeployStackWithComposeFile(stackName string, composeFilePath string, environment map[string]string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.stackStates[stackName] = Available
+	Logger.Debug("Mock pretends to have deployed stack '%s' with compose file '%s'.", stackName, composeFilePath)
+	return nil
+}
+
 func (d *DockerServiceMock) StopStack(stackName string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
 	if _, ok := d.stackStates[stackName]; ok {
 		d.stackStates[stackName] = Uninitialized
 	} else {
-		return fmt.Errorf("error, stack %s does not exist in mock", stackName)
+		return newStackError(ErrNotFound, stackName, "error, stack %s does not exist in mock", stackName)
 	}
 	Logger.Debug("Mock pretends to have stopped stack '%s'", stackName)
 	return nil
 }
 
+func (d *DockerServiceMock) RestartStack(stackName string, environment map[string]string) error {
+	return d.pretendToReplaceContainers(stackName, "restarted")
+}
+
+func (d *DockerServiceMock) RecreateStack(stackName string, environment map[string]string) error {
+	return d.pretendToReplaceContainers(stackName, "recreated")
+}
+
+func (d *DockerServiceMock) pretendToReplaceContainers(stackName string, participle string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if state, ok := d.stackStates[stackName]; !ok || state == Uninitialized {
+		return newStackError(ErrInvalidState, stackName, "error, stack %s is not running in mock", stackName)
+	}
+	if d.neverAvailableStacks[stackName] {
+		d.stackStates[stackName] = Starting
+	}
+	d.unhealthyContainers[stackName] = nil
+	Logger.Debug("Mock pretends to have %s stack '%s'", participle, stackName)
+	return nil
+}
+
 func (d *DockerServiceMock) GetRunningStackStateInfo() (map[string]StackDetails, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
 	Logger.Trace("Mock return stack state info of virtually managed stacks")
+	if d.isUnavailable {
+		return nil, fmt.Errorf("docker daemon is not reachable")
+	}
 
 	clonedStates := make(map[string]StackDetails)
 	for stackName, stackState := range d.stackStates {
@@ -46,7 +100,7 @@ func (d *DockerServiceMock) GetRunningStackStateInfo() (map[string]StackDetails,
 	}
 
 	for key, value := range d.stackStates {
-		if key == tools.NginxSlowStart {
+		if key == tools.NginxSlowStart && value == Starting {
 			d.stackStates[key] = Available
 		} else if key == tools.NginxDownloading {
 			if !d.hasWaitedToPassDownloadState {
@@ -58,3 +112,126 @@ func (d *DockerServiceMock) GetRunningStackStateInfo() (map[string]StackDetails,
 	}
 	return clonedStates, nil
 }
+
+func (d *DockerServiceMock) BackupStackVolumes(stackName string, backupDir string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.backedUpStacks = append(d.backedUpStacks, stackName)
+	Logger.Debug("Mock pretends to have backed up the volumes of stack '%s'", stackName)
+	return nil
+}
+
+func (d *DockerServiceMock) RestoreStackVolumes(stackName string, backupDir string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.restoredStacks = append(d.restoredStacks, stackName)
+	Logger.Debug("Mock pretends to have restored the volumes of stack '%s'", stackName)
+	return nil
+}
+
+func (d *DockerServiceMock) GetStackImages(stackName string) ([]StackImage, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return append([]StackImage{}, d.stackImages[stackName]...), nil
+}
+
+// GetStackStats returns synthetic resource usage for a single container of every stack that is not stopped.
+func (d *DockerServiceMock) GetStackStats(stackName string) ([]ContainerStats, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if state, ok := d.stackStates[stackName]; !ok || state == Uninitialized {
+		return nil, nil
+	}
+	return []ContainerStats{{
+		Name:             stackName + "-" + stackName + "-1",
+		CpuPercent:       1.5,
+		MemoryUsageBytes: 64 << 20,
+		MemoryLimitBytes: 1 << 30,
+		NetworkRxBytes:   2048,
+		NetworkTxBytes:   1024,
+		BlockReadBytes:   4096,
+		BlockWriteBytes:  512,
+	}}, nil
+}
+
+func (d *DockerServiceMock) GetDockerInfo() (DockerInfo, error) {
+	return DockerInfo{Version: "25.0.3", ComposeVersion: "2.24.6", DataRoot: "/var/lib/docker"}, nil
+}
+
+// GetHostDiskUsage pretends that each path is on a disk of 100 GB, which is 40% full.
+func (d *DockerServiceMock) GetHostDiskUsage(paths []string) ([]DiskUsage, error) {
+	var diskUsages []DiskUsage
+	for _, path := range paths {
+		diskUsages = append(diskUsages, DiskUsage{Path: path, TotalBytes: 100 << 30, UsedBytes: 40 << 30, AvailableBytes: 60 << 30, UsedPercent: 40})
+	}
+	return diskUsages, nil
+}
+
+// GetStackVolumes pretends that every stack, which was deployed at least once, has a data volume.
+func (d *DockerServiceMock) GetStackVolumes(stackName string) ([]string, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if _, ok := d.stackStates[stackName]; !ok {
+		return nil, nil
+	}
+	return []string{stackName + "_data"}, nil
+}
+
+// RemoveStackVolumes pretends to remove the data volume, which also makes the mock forget that the stack was deployed.
+func (d *DockerServiceMock) RemoveStackVolumes(stackName string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if _, ok := d.stackStates[stackName]; ok {
+		d.removedVolumes = append(d.removedVolumes, stackName+"_data")
+		delete(d.stackStates, stackName)
+	}
+	return nil
+}
+
+// GetDiskUsagePerStack pretends that every deployed stack occupies some space for images and volumes.
+func (d *DockerServiceMock) GetDiskUsagePerStack() (map[string]StackDiskUsage, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	usagePerStack := make(map[string]StackDiskUsage)
+	for stackName := range d.stackStates {
+		usagePerStack[stackName] = StackDiskUsage{ImageBytes: 180 << 20, VolumeBytes: 12 << 20}
+	}
+	return usagePerStack, nil
+}
+
+func (d *DockerServiceMock) GetUnhealthyContainers(stackName string) ([]string, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return append([]string{}, d.unhealthyContainers[stackName]...), nil
+}
+
+func (d *DockerServiceMock) RestartContainer(containerName string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.restartedContainers = append(d.restartedContainers, containerName)
+	Logger.Debug("Mock pretends to have restarted container '%s'", containerName)
+	return nil
+}
+
+// StreamStackLogs writes a few synthetic lines for each stack that is not stopped. When following, it waits until
+// the context is canceled like the real implementation.
+func (d *DockerServiceMock) StreamStackLogs(ctx context.Context, stackName string, options LogOptions, output io.Writer) error {
+	d.mu.Lock()
+	state, ok := d.stackStates[stackName]
+	d.mu.Unlock()
+	if ok && state != Uninitialized {
+		lines := []string{"starting " + stackName, stackName + " is ready"}
+		if options.Tail > 0 && options.Tail < len(lines) {
+			lines = lines[len(lines)-options.Tail:]
+		}
+		for _, line := range lines {
+			if _, err := fmt.Fprintf(output, "%s-1  | %s\n", stackName, line); err != nil {
+				return err
+			}
+		}
+	}
+	if options.Follow {
+		<-ctx.Done()
+	}
+	return nil
+}
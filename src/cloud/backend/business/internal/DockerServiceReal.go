@@ -3,41 +3,115 @@ package internal
 import (
 	"bufio"
 	"bytes"
+	"context"
+	"encoding/json"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
+	"path/filepath"
+	"strconv"
 	"strings"
 )
 
+// helperImage is used for short-lived containers which access volumes or host paths on behalf of Ocelot.
+const helperImage = "alpine:3.19"
+
 // TODO Run initial test, either "docker compose" or "docker-compose" must be installed. If not, exit. If one is installed, set it globally as dockerComposeCommand or so
 
-type DockerServiceReal struct{}
+type DockerServiceReal struct {
+	instanceService    StackInstanceService
+	stackConfigService StackConfigService
+}
 
-func (d *DockerServiceReal) DeployStack(stackName string) error {
-	cmdPath := getStackPath(stackName)
+func (d *DockerServiceReal) DeployStack(stackName string, environment map[string]string) error {
+	return d.DeployStackWithComposeFile(stackName, getDeployedComposePath(stackName, d.instanceService.GetTemplateName(stackName)), environment)
+}
 
-	if _, err := os.Stat(cmdPath); os.IsNotExist(err) {
+// DeployStackWithComposeFile deploys a stack based on a compose file which may differ from the one in the stack
+// directory, e.g. the compose file of a previous version. Relative paths are still resolved against the stack directory.
+func (d *DockerServiceReal) DeployStackWithComposeFile(stackName string, composeFilePath string, environment map[string]string) error {
+	templateName := d.instanceService.GetTemplateName(stackName)
+	if _, err := os.Stat(composeFilePath); os.IsNotExist(err) {
 		return logAndCreateStackNotFoundError(stackName)
 	}
 
+	if templateName != stackName {
+		if err := writeInstanceOverrideFile(stackName, templateName, composeFilePath); err != nil {
+			Logger.Error("failed to write compose override file of instance '%s': %v", stackName, err)
+			return fmt.Errorf("failed stack deployment")
+		}
+	}
+	if err := writeResourceOverrideFile(stackName, composeFilePath, d.stackConfigService.GetStackConfig(templateName).Resources); err != nil {
+		Logger.Error("failed to write resource override file of stack '%s': %v", stackName, err)
+		return fmt.Errorf("failed stack deployment")
+	}
+
 	networkCreationBashCmd := fmt.Sprintf("docker network ls | grep -q %s-net || docker network create %s-net", stackName, stackName)
 	_ = exec.Command("/bin/sh", "-c", networkCreationBashCmd).Run()
 
-	stackDeployCmd := exec.Command("docker", "compose", "-f", cmdPath, "-p", stackName, "up", "-d")
+	stackDeployCmd := exec.Command("docker", append(d.getComposeArgsWithComposeFile(stackName, composeFilePath), "up", "-d")...)
+	stackDeployCmd.Env = os.Environ()
+	for key, value := range environment {
+		stackDeployCmd.Env = append(stackDeployCmd.Env, key+"="+value)
+	}
 	output, err := stackDeployCmd.CombinedOutput()
 	if err != nil {
 		Logger.Warn("failed to deploy stack: %v, Output: %s", err, string(output))
-		return fmt.Errorf("failed stack deployment")
+		dockerCommandErrors.WithLabelValues("compose up").Inc()
+		return newDockerCommandError(stackName, "compose up", err, output)
 	} else {
 		Logger.Debug("Docker service deployed stack '%s'", stackName)
 		return nil
 	}
 }
 
-func logAndCreateStackNotFoundError(stackName string) error {
-	errorMessage := "Could not find stack: " + stackName
-	Logger.Error(errorMessage)
-	return fmt.Errorf(errorMessage)
+func (d *DockerServiceReal) RestartStack(stackName string, environment map[string]string) error {
+	return d.runComposeCommand(stackName, environment, "restart")
+}
+
+// RecreateStack replaces all containers of a stack, e.g. to apply images which were pulled in the meantime.
+func (d *DockerServiceReal) RecreateStack(stackName string, environment map[string]string) error {
+	return d.runComposeCommand(stackName, environment, "up", "-d", "--force-recreate")
+}
+
+func (d *DockerServiceReal) runComposeCommand(stackName string, environment map[string]string, args ...string) error {
+	cmd := exec.Command("docker", append(d.getComposeArgs(stackName), args...)...)
+	cmd.Env = os.Environ()
+	for key, value := range environment {
+		cmd.Env = append(cmd.Env, key+"="+value)
+	}
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		Logger.Warn("Command '%s' failed: %v, Output: %s", cmd.String(), err, string(output))
+		dockerCommandErrors.WithLabelValues("compose " + args[0]).Inc()
+		return newDockerCommandError(stackName, "compose "+args[0], err, output)
+	}
+	Logger.Debug("Docker service ran '%s' for stack '%s'", args[0], stackName)
+	return nil
+}
+
+// getComposeArgs returns the arguments selecting the compose project of a stack. Instances of a template
+// additionally use their generated override file, if the instance was already deployed. The same applies to the
+// override file limiting the resources of a stack.
+func (d *DockerServiceReal) getComposeArgs(stackName string) []string {
+	return d.getComposeArgsWithComposeFile(stackName, getDeployedComposePath(stackName, d.instanceService.GetTemplateName(stackName)))
+}
+
+func (d *DockerServiceReal) getComposeArgsWithComposeFile(stackName string, composeFilePath string) []string {
+	templateName := d.instanceService.GetTemplateName(stackName)
+	projectDir := filepath.Dir(getStackPath(templateName))
+	args := []string{"compose", "-p", stackName, "--project-directory", projectDir, "-f", composeFilePath}
+	if templateName != stackName {
+		overridePath := getInstanceOverridePath(stackName)
+		if _, err := os.Stat(overridePath); err == nil {
+			args = append(args, "-f", overridePath)
+		}
+	}
+	if _, err := os.Stat(getResourceOverridePath(stackName)); err == nil {
+		args = append(args, "-f", getResourceOverridePath(stackName))
+	}
+	return args
 }
 
 func getStackPath(stackName string) string {
@@ -49,12 +123,12 @@ func getStackPath(stackName string) string {
 }
 
 func (d *DockerServiceReal) StopStack(stackName string) error {
-	configPath := getStackPath(stackName)
-	cmd := exec.Command("docker", "compose", "-p", stackName, "-f", configPath, "down")
+	cmd := exec.Command("docker", append(d.getComposeArgs(stackName), "down")...)
 	output, err := cmd.CombinedOutput()
 	if err != nil {
 		Logger.Error("Command '%s' failed to stop stack: %v, Output: %s", cmd.String(), err, output)
-		return fmt.Errorf("stack stopping error")
+		dockerCommandErrors.WithLabelValues("compose down").Inc()
+		return newDockerCommandError(stackName, "compose down", err, output)
 	} else {
 		Logger.Debug("Docker service stopped stack '%s'", stackName)
 		return nil
@@ -68,38 +142,38 @@ func (d *DockerServiceReal) GetRunningStackStateInfo() (map[string]StackDetails,
 		return nil, err
 	}
 	genericRunningStateStacksInfo := extractNamesOfRunningStacksFromLines(lines)
-	fullStacksInfoWithMoreSpecificHealthState := setHealthStates(genericRunningStateStacksInfo)
+	fullStacksInfoWithMoreSpecificHealthState := d.setHealthStates(genericRunningStateStacksInfo)
 
 	return fullStacksInfoWithMoreSpecificHealthState, nil
 }
 
-func setHealthStates(stackStateInfo map[string]StackDetails) map[string]StackDetails {
+func (d *DockerServiceReal) setHealthStates(stackStateInfo map[string]StackDetails) map[string]StackDetails {
 	resultInfo := make(map[string]StackDetails)
 	for stackName, stackDetail := range stackStateInfo {
 		if stackDetail.State == Running {
-			stackDetail.State = getHealthStateOf(stackName)
+			stackDetail.State = d.getHealthStateOf(stackName)
 		}
 		resultInfo[stackName] = stackDetail
 	}
 	return resultInfo
 }
 
-func getHealthStateOf(stackName string) StackState {
-	if areAllStackContainersWithHealthChecksReallyHealthy(stackName) {
+func (d *DockerServiceReal) getHealthStateOf(stackName string) StackState {
+	if d.areAllStackContainersWithHealthChecksReallyHealthy(stackName) {
 		return Available
 	} else {
 		return Starting
 	}
 }
 
-func areAllStackContainersWithHealthChecksReallyHealthy(stackName string) bool {
-	dockerComposeYamlPathOfStack := getStackPath(stackName)
-	stackInfoCmd := exec.Command("docker", "compose", "-f", dockerComposeYamlPathOfStack, "ps")
+func (d *DockerServiceReal) areAllStackContainersWithHealthChecksReallyHealthy(stackName string) bool {
+	stackInfoCmd := exec.Command("docker", append(d.getComposeArgs(stackName), "ps")...)
 	var out bytes.Buffer
 	stackInfoCmd.Stdout = &out
 	err := stackInfoCmd.Run()
 	if err != nil {
 		Logger.Error("Failed to read CLI output of stack specific container info for stack '%s'", stackName)
+		dockerCommandErrors.WithLabelValues("compose ps").Inc()
 		return false
 	}
 
@@ -127,11 +201,11 @@ func getDockerComposeListLines() ([]string, error) {
 	outputBytes, err := cmd.CombinedOutput()
 	if err != nil {
 		Logger.Error("Command '%s' did not work: %v. Maybe the wrong version is used.", cmd.String(), err)
-		versionOutputBytes, versionErr := exec.Command("docker", "compose", "version").CombinedOutput()
-		if versionErr == nil {
-			Logger.Error("Docker Compose version is: %s", string(versionOutputBytes))
+		dockerCommandErrors.WithLabelValues("compose ls").Inc()
+		if composeVersion, versionErr := getComposeVersion(); versionErr == nil {
+			Logger.Error("Docker Compose version is: %s", composeVersion)
 		}
-		return nil, err
+		return nil, newDockerCommandError("", "compose ls", err, outputBytes)
 	}
 
 	output := string(outputBytes)
@@ -167,3 +241,333 @@ func transformToStackStackInfo(fields []string) (string, StackDetails) {
 	}
 	return name, StackDetails{status, "/"}
 }
+
+// BackupStackVolumes writes the content of each volume of a stack to an archive in the backup directory. The
+// archives are streamed through the docker CLI, so that this also works when Ocelot itself runs in a container.
+func (d *DockerServiceReal) BackupStackVolumes(stackName string, backupDir string) error {
+	volumeNames, err := getVolumeNamesOfStack(stackName)
+	if err != nil {
+		return err
+	}
+
+	for _, volumeName := range volumeNames {
+		archive, err := os.Create(filepath.Join(backupDir, volumeName+".tar.gz"))
+		if err != nil {
+			return err
+		}
+		cmd := exec.Command("docker", "run", "--rm", "-v", volumeName+":/volume:ro", helperImage, "tar", "czf", "-", "-C", "/volume", ".")
+		var stderr bytes.Buffer
+		cmd.Stdout = archive
+		cmd.Stderr = &stderr
+		err = cmd.Run()
+		archive.Close()
+		if err != nil {
+			Logger.Error("Command '%s' failed to back up volume: %v, Output: %s", cmd.String(), err, stderr.String())
+			dockerCommandErrors.WithLabelValues("run").Inc()
+			return fmt.Errorf("volume backup error")
+		}
+		Logger.Debug("Backed up volume '%s' of stack '%s'", volumeName, stackName)
+	}
+	return nil
+}
+
+// RestoreStackVolumes replaces the content of the volumes of a stack by the archives in the backup directory.
+func (d *DockerServiceReal) RestoreStackVolumes(stackName string, backupDir string) error {
+	archivePaths, err := filepath.Glob(filepath.Join(backupDir, "*.tar.gz"))
+	if err != nil {
+		return err
+	}
+
+	for _, archivePath := range archivePaths {
+		volumeName := strings.TrimSuffix(filepath.Base(archivePath), ".tar.gz")
+		archive, err := os.Open(archivePath)
+		if err != nil {
+			return err
+		}
+		cmd := exec.Command("docker", "run", "--rm", "-i", "-v", volumeName+":/volume", helperImage, "sh", "-c", "find /volume -mindepth 1 -delete && tar xzf - -C /volume")
+		cmd.Stdin = archive
+		output, err := cmd.CombinedOutput()
+		archive.Close()
+		if err != nil {
+			Logger.Error("Command '%s' failed to restore volume: %v, Output: %s", cmd.String(), err, output)
+			dockerCommandErrors.WithLabelValues("run").Inc()
+			return fmt.Errorf("volume restore error")
+		}
+		Logger.Debug("Restored volume '%s' of stack '%s'", volumeName, stackName)
+	}
+	return nil
+}
+
+func (d *DockerServiceReal) GetStackVolumes(stackName string) ([]string, error) {
+	return getVolumeNamesOfStack(stackName)
+}
+
+// RemoveStackVolumes deletes the volumes of a stack, which must not have containers anymore.
+func (d *DockerServiceReal) RemoveStackVolumes(stackName string) error {
+	volumeNames, err := getVolumeNamesOfStack(stackName)
+	if err != nil || len(volumeNames) == 0 {
+		return err
+	}
+	cmd := exec.Command("docker", append([]string{"volume", "rm"}, volumeNames...)...)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		Logger.Error("Command '%s' failed to remove volumes: %v, Output: %s", cmd.String(), err, output)
+		dockerCommandErrors.WithLabelValues("volume rm").Inc()
+		return fmt.Errorf("volume removal error")
+	}
+	Logger.Debug("Removed the volumes of stack '%s'", stackName)
+	return nil
+}
+
+func getVolumeNamesOfStack(stackName string) ([]string, error) {
+	cmd := exec.Command("docker", "volume", "ls", "-q", "--filter", "label=com.docker.compose.project="+stackName)
+	output, err := cmd.Output()
+	if err != nil {
+		Logger.Error("Command '%s' failed to list volumes: %v", cmd.String(), err)
+		dockerCommandErrors.WithLabelValues("volume ls").Inc()
+		return nil, fmt.Errorf("volume listing error")
+	}
+	return strings.Fields(string(output)), nil
+}
+
+// GetStackImages returns the images of the containers of a stack. The digest is the one the image was pulled
+// with from the registry and is empty for images which were built locally.
+func (d *DockerServiceReal) GetStackImages(stackName string) ([]StackImage, error) {
+	containerIdsCmd := exec.Command("docker", append(d.getComposeArgs(stackName), "ps", "-q")...)
+	containerIdsOutput, err := containerIdsCmd.Output()
+	if err != nil {
+		Logger.Error("Command '%s' failed to list containers: %v", containerIdsCmd.String(), err)
+		dockerCommandErrors.WithLabelValues("compose ps").Inc()
+		return nil, fmt.Errorf("container listing error")
+	}
+	containerIds := strings.Fields(string(containerIdsOutput))
+	if len(containerIds) == 0 {
+		return nil, nil
+	}
+
+	inspectCmd := exec.Command("docker", append([]string{"inspect", "--format", "{{.Config.Image}} {{.Image}}"}, containerIds...)...)
+	inspectOutput, err := inspectCmd.Output()
+	if err != nil {
+		Logger.Error("Command '%s' failed to inspect containers: %v", inspectCmd.String(), err)
+		dockerCommandErrors.WithLabelValues("inspect").Inc()
+		return nil, fmt.Errorf("container inspection error")
+	}
+
+	var stackImages []StackImage
+	knownImages := make(map[string]bool)
+	for _, line := range strings.Split(strings.TrimSpace(string(inspectOutput)), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 2 || knownImages[line] {
+			continue
+		}
+		knownImages[line] = true
+		digest, err := getRepoDigestOfImage(fields[0], fields[1])
+		if err != nil {
+			return nil, err
+		}
+		stackImages = append(stackImages, StackImage{Image: fields[0], Digest: digest})
+	}
+	return stackImages, nil
+}
+
+func getRepoDigestOfImage(imageName string, imageId string) (string, error) {
+	cmd := exec.Command("docker", "image", "inspect", "--format", "{{json .RepoDigests}}", imageId)
+	output, err := cmd.Output()
+	if err != nil {
+		Logger.Error("Command '%s' failed to inspect image: %v", cmd.String(), err)
+		dockerCommandErrors.WithLabelValues("image inspect").Inc()
+		return "", fmt.Errorf("image inspection error")
+	}
+	var repoDigests []string
+	if err = json.Unmarshal(output, &repoDigests); err != nil {
+		return "", err
+	}
+
+	repository := imageName
+	if index := strings.LastIndex(imageName, ":"); index > strings.LastIndex(imageName, "/") {
+		repository = imageName[:index]
+	}
+	for _, repoDigest := range repoDigests {
+		if strings.HasPrefix(repoDigest, repository+"@") {
+			return strings.SplitN(repoDigest, "@", 2)[1], nil
+		}
+	}
+	if len(repoDigests) > 0 {
+		return strings.SplitN(repoDigests[0], "@", 2)[1], nil
+	}
+	return "", nil
+}
+
+// dockerStatsLine is a line of the output of "docker stats --format '{{json .}}'".
+type dockerStatsLine struct {
+	Name     string `json:"Name"`
+	CPUPerc  string `json:"CPUPerc"`
+	MemUsage string `json:"MemUsage"`
+	NetIO    string `json:"NetIO"`
+	BlockIO  string `json:"BlockIO"`
+}
+
+func (d *DockerServiceReal) GetStackStats(stackName string) ([]ContainerStats, error) {
+	containerIdsCmd := exec.Command("docker", append(d.getComposeArgs(stackName), "ps", "-q")...)
+	containerIdsOutput, err := containerIdsCmd.Output()
+	if err != nil {
+		Logger.Error("Command '%s' failed to list containers: %v", containerIdsCmd.String(), err)
+		dockerCommandErrors.WithLabelValues("compose ps").Inc()
+		return nil, fmt.Errorf("container listing error")
+	}
+	containerIds := strings.Fields(string(containerIdsOutput))
+	if len(containerIds) == 0 {
+		return nil, nil
+	}
+
+	statsCmd := exec.Command("docker", append([]string{"stats", "--no-stream", "--format", "{{json .}}"}, containerIds...)...)
+	statsOutput, err := statsCmd.Output()
+	if err != nil {
+		Logger.Error("Command '%s' failed to determine container stats: %v", statsCmd.String(), err)
+		dockerCommandErrors.WithLabelValues("stats").Inc()
+		return nil, fmt.Errorf("container stats error")
+	}
+	return parseDockerStatsOutput(statsOutput)
+}
+
+func parseDockerStatsOutput(output []byte) ([]ContainerStats, error) {
+	var containerStats []ContainerStats
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		var statsLine dockerStatsLine
+		if err := json.Unmarshal([]byte(line), &statsLine); err != nil {
+			return nil, err
+		}
+		stats, err := statsLine.toContainerStats()
+		if err != nil {
+			return nil, err
+		}
+		containerStats = append(containerStats, stats)
+	}
+	return containerStats, scanner.Err()
+}
+
+func (l dockerStatsLine) toContainerStats() (ContainerStats, error) {
+	stats := ContainerStats{Name: l.Name}
+	cpuPercent, err := strconv.ParseFloat(strings.TrimSuffix(l.CPUPerc, "%"), 64)
+	if err != nil {
+		return stats, fmt.Errorf("invalid CPU usage '%s' of container '%s'", l.CPUPerc, l.Name)
+	}
+	stats.CpuPercent = cpuPercent
+	if stats.MemoryUsageBytes, stats.MemoryLimitBytes, err = parseByteSizePair(l.MemUsage); err != nil {
+		return stats, err
+	}
+	if stats.NetworkRxBytes, stats.NetworkTxBytes, err = parseByteSizePair(l.NetIO); err != nil {
+		return stats, err
+	}
+	if stats.BlockReadBytes, stats.BlockWriteBytes, err = parseByteSizePair(l.BlockIO); err != nil {
+		return stats, err
+	}
+	return stats, nil
+}
+
+func getComposeVersion() (string, error) {
+	output, err := exec.Command("docker", "compose", "version", "--short").Output()
+	if err != nil {
+		dockerCommandErrors.WithLabelValues("compose version").Inc()
+		return "", err
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// GetHostDiskUsage determines the disk usage of paths of the host. It is measured in a helper container with the
+// root of the host mounted read-only, since Ocelot itself usually runs in a container without access to the host.
+func (d *DockerServiceReal) GetHostDiskUsage(paths []string) ([]DiskUsage, error) {
+	args := []string{"run", "--rm", "-v", "/:/host:ro", helperImage, "df", "-P", "-k"}
+	for _, path := range paths {
+		args = append(args, filepath.Join("/host", path))
+	}
+	cmd := exec.Command("docker", args...)
+	output, err := cmd.Output()
+	if err != nil {
+		Logger.Error("Command '%s' failed to determine the disk usage: %v", cmd.String(), err)
+		dockerCommandErrors.WithLabelValues("run").Inc()
+		return nil, fmt.Errorf("disk usage error")
+	}
+	return parseDfOutput(output, paths)
+}
+
+func (d *DockerServiceReal) GetDockerInfo() (DockerInfo, error) {
+	cmd := exec.Command("docker", "info", "--format", "{{.ServerVersion}} {{.DockerRootDir}}")
+	output, err := cmd.Output()
+	if err != nil {
+		Logger.Error("Command '%s' failed to read docker info: %v", cmd.String(), err)
+		dockerCommandErrors.WithLabelValues("info").Inc()
+		return DockerInfo{}, fmt.Errorf("docker info error")
+	}
+	fields := strings.Fields(string(output))
+	if len(fields) != 2 {
+		return DockerInfo{}, fmt.Errorf("unexpected docker info '%s'", string(output))
+	}
+
+	composeVersion, err := getComposeVersion()
+	if err != nil {
+		Logger.Error("Docker Compose version could not be determined: %v", err)
+		return DockerInfo{}, fmt.Errorf("docker compose version error")
+	}
+	return DockerInfo{Version: fields[0], ComposeVersion: composeVersion, DataRoot: fields[1]}, nil
+}
+
+func (d *DockerServiceReal) GetDiskUsagePerStack() (map[string]StackDiskUsage, error) {
+	cmd := exec.Command("docker", "system", "df", "-v", "--format", "{{json .}}")
+	output, err := cmd.Output()
+	if err != nil {
+		Logger.Error("Command '%s' failed to determine disk usage: %v", cmd.String(), err)
+		dockerCommandErrors.WithLabelValues("system df").Inc()
+		return nil, fmt.Errorf("disk usage error")
+	}
+	var diskUsage dockerDiskUsage
+	if err = json.Unmarshal(output, &diskUsage); err != nil {
+		return nil, err
+	}
+	return diskUsage.getUsagePerStack(), nil
+}
+
+func (d *DockerServiceReal) GetUnhealthyContainers(stackName string) ([]string, error) {
+	cmd := exec.Command("docker", "ps", "--filter", "label=com.docker.compose.project="+stackName, "--filter", "health=unhealthy", "--format", "{{.Names}}")
+	output, err := cmd.Output()
+	if err != nil {
+		Logger.Error("Command '%s' failed to list unhealthy containers: %v", cmd.String(), err)
+		dockerCommandErrors.WithLabelValues("ps").Inc()
+		return nil, fmt.Errorf("container listing error")
+	}
+	return strings.Fields(string(output)), nil
+}
+
+func (d *DockerServiceReal) RestartContainer(containerName string) error {
+	cmd := exec.Command("docker", "restart", containerName)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		Logger.Error("Command '%s' failed to restart container: %v, Output: %s", cmd.String(), err, output)
+		dockerCommandErrors.WithLabelValues("restart").Inc()
+		return fmt.Errorf("container restart error")
+	}
+	return nil
+}
+
+// StreamStackLogs writes the logs of the containers of a stack to the output. When following the logs, this only
+// returns after the context was canceled, e.g. because the client disconnected.
+func (d *DockerServiceReal) StreamStackLogs(ctx context.Context, stackName string, options LogOptions, output io.Writer) error {
+	args := append(d.getComposeArgs(stackName), "logs", "--no-color", "--tail", options.getTailArgument())
+	if options.Follow {
+		args = append(args, "--follow")
+	}
+	cmd := exec.CommandContext(ctx, "docker", args...)
+	var stderr bytes.Buffer
+	cmd.Stdout = output
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil && ctx.Err() == nil {
+		Logger.Warn("Command '%s' failed: %v, Output: %s", cmd.String(), err, stderr.String())
+		dockerCommandErrors.WithLabelValues("compose logs").Inc()
+		return newDockerCommandError(stackName, "compose logs", err, stderr.Bytes())
+	}
+	return nil
+}
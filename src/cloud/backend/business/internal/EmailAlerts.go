@@ -0,0 +1,283 @@
+package internal
+
+import (
+	"bytes"
+	"crypto/tls"
+	"fmt"
+	"mime"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"ocelot/backend/config"
+	"regexp"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// EmailAlertEventTypes are the events which are critical enough to be sent by email. All other events are only
+// delivered to webhooks.
+var EmailAlertEventTypes = []string{EventStackDown, EventBackupFailed, EventDiskNearlyFull, EventCertificateExpiring}
+
+const emailTestTemplate = "test"
+
+var emailUserPattern = regexp.MustCompile(`^[A-Za-z0-9_.-]{1,64}$`)
+
+// emailTemplate renders the subject and the plain text body of an email from an emailData.
+type emailTemplate struct {
+	subject *template.Template
+	body    *template.Template
+}
+
+type emailData struct {
+	Host    string
+	Stack   string
+	Message string
+	Details map[string]string
+	Time    string
+}
+
+const emailFooter = `
+{{range $key, $value := .Details}}{{$key}}: {{$value}}
+{{end}}
+--
+You receive this email, since you subscribed to the alerts of Ocelot on {{.Host}}.
+`
+
+var emailTemplates = map[string]emailTemplate{
+	EventStackDown: parseEmailTemplate("[Ocelot] Stack {{.Stack}} is down", `The stack "{{.Stack}}" on {{.Host}} is down since {{.Time}}.
+
+{{.Message}}.
+`),
+	EventBackupFailed: parseEmailTemplate("[Ocelot] Backup of stack {{.Stack}} failed", `The backup of the stack "{{.Stack}}" on {{.Host}} failed at {{.Time}}.
+
+{{.Message}}.
+`),
+	EventDiskNearlyFull: parseEmailTemplate("[Ocelot] Disk nearly full on {{.Host}}", `A disk of {{.Host}} is nearly full since {{.Time}}. When it is full, the apps can no longer store data.
+
+{{.Message}}.
+`),
+	EventCertificateExpiring: parseEmailTemplate("[Ocelot] Certificate of {{index .Details \"host\"}} is expiring", `The TLS certificate of {{index .Details "host"}} expires soon, it was checked at {{.Time}}. Browsers will refuse to connect when it expired.
+
+{{.Message}}.
+`),
+	emailTestTemplate: parseEmailTemplate("[Ocelot] Test email", `This is a test email sent by Ocelot on {{.Host}} at {{.Time}}. The email alerts are set up correctly.
+`),
+}
+
+func parseEmailTemplate(subject string, body string) emailTemplate {
+	return emailTemplate{
+		subject: template.Must(template.New("subject").Parse(subject)),
+		body:    template.Must(template.New("body").Parse(body + emailFooter)),
+	}
+}
+
+// EmailNotifier sends the critical events by email to the users who subscribed to them. Nothing is sent, if no
+// SMTP server is configured.
+type EmailNotifier struct {
+	subscriptions EmailSubscriptionService
+	config        tools.SmtpConfig
+	host          string
+	// tlsConfig is used for connections to the SMTP server, the server name is set when connecting.
+	tlsConfig *tls.Config
+}
+
+func ProvideEmailNotifier(subscriptions EmailSubscriptionService, config tools.SmtpConfig, host string) *EmailNotifier {
+	return &EmailNotifier{subscriptions: subscriptions, config: config, host: host, tlsConfig: &tls.Config{MinVersion: tls.VersionTLS12}}
+}
+
+func (n *EmailNotifier) isEnabled() bool {
+	return n.config.Host != ""
+}
+
+func (n *EmailNotifier) Notify(event StackEvent) {
+	if !n.isEnabled() || !contains(EmailAlertEventTypes, event.Type) {
+		return
+	}
+	subscriptions, err := n.subscriptions.GetSubscriptions()
+	if err != nil {
+		Logger.Warn("event '%s' is not sent by email, since the subscriptions could not be read", event.Type)
+		return
+	}
+	for _, subscription := range subscriptions {
+		if subscription.isSubscribedTo(event.Type) {
+			go n.notifySubscriber(subscription, event)
+		}
+	}
+}
+
+func (n *EmailNotifier) notifySubscriber(subscription EmailSubscription, event StackEvent) {
+	if err := n.send(subscription.Email, event.Type, n.getEmailData(event)); err != nil {
+		emailDeliveryFailures.WithLabelValues(event.Type).Inc()
+		Logger.Warn("email about event '%s' could not be sent to user '%s': %v", event.Type, subscription.User, err)
+		return
+	}
+	Logger.Info("Email about event '%s' was sent to user '%s'", event.Type, subscription.User)
+}
+
+func (n *EmailNotifier) getEmailData(event StackEvent) emailData {
+	return emailData{Host: n.host, Stack: event.Stack, Message: event.Message, Details: event.Details, Time: event.Timestamp.Format(time.RFC1123)}
+}
+
+func (n *EmailNotifier) send(recipient string, templateName string, data emailData) error {
+	message, err := n.render(recipient, emailTemplates[templateName], data)
+	if err != nil {
+		return err
+	}
+
+	client, err := n.connect()
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	if n.config.Username != "" {
+		if err = client.Auth(smtp.PlainAuth("", n.config.Username, n.config.Password, n.config.Host)); err != nil {
+			return fmt.Errorf("authentication at SMTP server failed: %w", err)
+		}
+	}
+	if err = client.Mail(n.config.From); err != nil {
+		return err
+	}
+	if err = client.Rcpt(recipient); err != nil {
+		return err
+	}
+	writer, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err = writer.Write(message); err != nil {
+		return err
+	}
+	if err = writer.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+// connect opens a connection to the SMTP server, which is encrypted unless SmtpSecurityNone is configured.
+func (n *EmailNotifier) connect() (*smtp.Client, error) {
+	address := net.JoinHostPort(n.config.Host, n.config.Port)
+	tlsConfig := n.tlsConfig.Clone()
+	tlsConfig.ServerName = n.config.Host
+
+	var connection net.Conn
+	var err error
+	dialer := &net.Dialer{Timeout: EmailTimeout}
+	if n.config.Security == tools.SmtpSecurityTls {
+		connection, err = tls.DialWithDialer(dialer, "tcp", address, tlsConfig)
+	} else {
+		connection, err = dialer.Dial("tcp", address)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to SMTP server: %w", err)
+	}
+	_ = connection.SetDeadline(time.Now().Add(EmailTimeout))
+
+	client, err := smtp.NewClient(connection, n.config.Host)
+	if err != nil {
+		connection.Close()
+		return nil, fmt.Errorf("failed to connect to SMTP server: %w", err)
+	}
+	if n.config.Security == tools.SmtpSecurityStartTls {
+		if ok, _ := client.Extension("STARTTLS"); !ok {
+			client.Close()
+			return nil, fmt.Errorf("SMTP server does not support STARTTLS")
+		}
+		if err = client.StartTLS(tlsConfig); err != nil {
+			client.Close()
+			return nil, fmt.Errorf("STARTTLS failed: %w", err)
+		}
+	}
+	return client, nil
+}
+
+// render creates the message including its headers. Line breaks are converted to CRLF when the message is sent.
+func (n *EmailNotifier) render(recipient string, emailTemplate emailTemplate, data emailData) ([]byte, error) {
+	var subject, body bytes.Buffer
+	if err := emailTemplate.subject.Execute(&subject, data); err != nil {
+		return nil, err
+	}
+	if err := emailTemplate.body.Execute(&body, data); err != nil {
+		return nil, err
+	}
+
+	var message bytes.Buffer
+	headers := map[string]string{
+		"From":                      n.config.From,
+		"To":                        recipient,
+		"Subject":                   mime.QEncoding.Encode("utf-8", strings.Join(strings.Fields(subject.String()), " ")),
+		"Date":                      time.Now().Format(time.RFC1123Z),
+		"MIME-Version":              "1.0",
+		"Content-Type":              "text/plain; charset=utf-8",
+		"Content-Transfer-Encoding": "8bit",
+	}
+	var headerNames []string
+	for name := range headers {
+		headerNames = append(headerNames, name)
+	}
+	sort.Strings(headerNames)
+	for _, name := range headerNames {
+		fmt.Fprintf(&message, "%s: %s\n", name, headers[name])
+	}
+	message.WriteString("\n")
+	message.Write(body.Bytes())
+	return message.Bytes(), nil
+}
+
+// SetEmailSubscription subscribes a user to email alerts or replaces the existing subscription of the user.
+func (sm *StackServiceImpl) SetEmailSubscription(user string, email string, events []string) error {
+	if !emailUserPattern.MatchString(user) {
+		return newStackError(ErrInvalidRequest, "", "invalid user name '%s'", user)
+	}
+	if err := validateEmailAddress(email); err != nil {
+		return err
+	}
+	for _, event := range events {
+		if !contains(EmailAlertEventTypes, event) {
+			return newStackError(ErrInvalidRequest, "", "event '%s' is not available as email alert, possible events are: %s", event, strings.Join(EmailAlertEventTypes, ", "))
+		}
+	}
+	if err := sm.EmailNotifier.subscriptions.SetSubscription(EmailSubscription{User: user, Email: email, Events: events}); err != nil {
+		return err
+	}
+	Logger.Info("Email subscription of user '%s' was saved", user)
+	return nil
+}
+
+func (sm *StackServiceImpl) GetEmailSubscriptions() ([]EmailSubscription, error) {
+	return sm.EmailNotifier.subscriptions.GetSubscriptions()
+}
+
+func (sm *StackServiceImpl) DeleteEmailSubscription(user string) error {
+	if err := sm.EmailNotifier.subscriptions.RemoveSubscription(user); err != nil {
+		return err
+	}
+	Logger.Info("Email subscription of user '%s' was deleted", user)
+	return nil
+}
+
+// SendTestEmail sends an email synchronously, so that errors in the SMTP configuration are reported to the caller.
+func (sm *StackServiceImpl) SendTestEmail(email string) error {
+	if !sm.EmailNotifier.isEnabled() {
+		return newStackError(ErrInvalidState, "", "email alerts are disabled, since no SMTP server is configured")
+	}
+	if err := validateEmailAddress(email); err != nil {
+		return err
+	}
+	if err := sm.EmailNotifier.send(email, emailTestTemplate, emailData{Host: sm.EmailNotifier.host, Time: time.Now().Format(time.RFC1123)}); err != nil {
+		Logger.Warn("test email could not be sent: %v", err)
+		return fmt.Errorf("failed to send test email: %w", err)
+	}
+	return nil
+}
+
+// validateEmailAddress only accepts plain addresses like "admin@example.org", since the address is also used in
+// the header of the email.
+func validateEmailAddress(email string) error {
+	address, err := mail.ParseAddress(email)
+	if err != nil || address.Address != email {
+		return newStackError(ErrInvalidRequest, "", "invalid email address '%s'", email)
+	}
+	return nil
+}
@@ -0,0 +1,246 @@
+package internal
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"github.com/ocelot-cloud/shared/assert"
+	"math/big"
+	"net"
+	"net/textproto"
+	"ocelot/backend/config"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type fakeSmtpMessage struct {
+	from        string
+	recipients  []string
+	credentials string
+	isEncrypted bool
+	data        string
+}
+
+// fakeSmtpServer implements the part of SMTP used by the EmailNotifier and records the received messages.
+type fakeSmtpServer struct {
+	listener         net.Listener
+	tlsConfig        *tls.Config
+	supportsStartTls bool
+	mu               sync.Mutex
+	messages         []fakeSmtpMessage
+}
+
+// startFakeSmtpServer starts a server on a random local port. With implicit TLS, the connections are encrypted from
+// the start, otherwise they can be upgraded via STARTTLS if supportsStartTls is set.
+func startFakeSmtpServer(t *testing.T, certificate tls.Certificate, isImplicitTls bool, supportsStartTls bool) *fakeSmtpServer {
+	server := &fakeSmtpServer{tlsConfig: &tls.Config{Certificates: []tls.Certificate{certificate}}, supportsStartTls: supportsStartTls}
+	var err error
+	if isImplicitTls {
+		server.listener, err = tls.Listen("tcp", "127.0.0.1:0", server.tlsConfig)
+	} else {
+		server.listener, err = net.Listen("tcp", "127.0.0.1:0")
+	}
+	assert.Nil(t, err)
+	t.Cleanup(func() { server.listener.Close() })
+	go func() {
+		for {
+			connection, err := server.listener.Accept()
+			if err != nil {
+				return
+			}
+			go server.handle(connection, isImplicitTls)
+		}
+	}()
+	return server
+}
+
+func (s *fakeSmtpServer) handle(connection net.Conn, isEncrypted bool) {
+	defer func() { connection.Close() }()
+	text := textproto.NewConn(connection)
+	message := fakeSmtpMessage{isEncrypted: isEncrypted}
+	_ = text.PrintfLine("220 fake ESMTP")
+	for {
+		line, err := text.ReadLine()
+		if err != nil {
+			return
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		switch strings.ToUpper(fields[0]) {
+		case "EHLO":
+			if s.supportsStartTls && !message.isEncrypted {
+				_ = text.PrintfLine("250-fake\r\n250-STARTTLS\r\n250 AUTH PLAIN")
+			} else {
+				_ = text.PrintfLine("250-fake\r\n250 AUTH PLAIN")
+			}
+		case "STARTTLS":
+			_ = text.PrintfLine("220 ready to start TLS")
+			tlsConnection := tls.Server(connection, s.tlsConfig)
+			if tlsConnection.Handshake() != nil {
+				return
+			}
+			connection = tlsConnection
+			text = textproto.NewConn(connection)
+			message.isEncrypted = true
+		case "AUTH":
+			credentials, _ := base64.StdEncoding.DecodeString(fields[len(fields)-1])
+			message.credentials = string(credentials)
+			_ = text.PrintfLine("235 authenticated")
+		case "MAIL":
+			message.from = strings.Trim(strings.TrimPrefix(fields[1], "FROM:"), "<>")
+			_ = text.PrintfLine("250 ok")
+		case "RCPT":
+			message.recipients = append(message.recipients, strings.Trim(strings.TrimPrefix(fields[1], "TO:"), "<>"))
+			_ = text.PrintfLine("250 ok")
+		case "DATA":
+			_ = text.PrintfLine("354 send the message")
+			lines, err := text.ReadDotLines()
+			if err != nil {
+				return
+			}
+			message.data = strings.Join(lines, "\n")
+			s.mu.Lock()
+			s.messages = append(s.messages, message)
+			s.mu.Unlock()
+			_ = text.PrintfLine("250 queued")
+		case "QUIT":
+			_ = text.PrintfLine("221 bye")
+			return
+		default:
+			_ = text.PrintfLine("250 ok")
+		}
+	}
+}
+
+func (s *fakeSmtpServer) getMessages() []fakeSmtpMessage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]fakeSmtpMessage{}, s.messages...)
+}
+
+func (s *fakeSmtpServer) waitForMessages(t *testing.T, count int) []fakeSmtpMessage {
+	for i := 0; i < 100; i++ {
+		if messages := s.getMessages(); len(messages) >= count {
+			return messages
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.Fail(t, "emails were not received in time")
+	return nil
+}
+
+func createCertificate(t *testing.T) (tls.Certificate, *x509.CertPool) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.Nil(t, err)
+	certificateTemplate := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	certificateBytes, err := x509.CreateCertificate(rand.Reader, certificateTemplate, certificateTemplate, &key.PublicKey, key)
+	assert.Nil(t, err)
+	certificate, err := x509.ParseCertificate(certificateBytes)
+	assert.Nil(t, err)
+	pool := x509.NewCertPool()
+	pool.AddCert(certificate)
+	return tls.Certificate{Certificate: [][]byte{certificateBytes}, PrivateKey: key}, pool
+}
+
+// createStackServiceWithSmtp returns a stack service, which sends its email alerts to a fake SMTP server.
+func createStackServiceWithSmtp(t *testing.T, security string, isImplicitTls bool, supportsStartTls bool) (*StackServiceImpl, *fakeSmtpServer) {
+	certificate, pool := createCertificate(t)
+	server := startFakeSmtpServer(t, certificate, isImplicitTls, supportsStartTls)
+	_, port, err := net.SplitHostPort(server.listener.Addr().String())
+	assert.Nil(t, err)
+
+	stackService := createStackService()
+	smtpConfig := tools.SmtpConfig{Host: "127.0.0.1", Port: port, Username: "ocelot", Password: "smtp-password", From: "ocelot@example.org", Security: security}
+	stackService.EmailNotifier.config = smtpConfig
+	stackService.EmailNotifier.tlsConfig.RootCAs = pool
+	return stackService, server
+}
+
+func TestEmailAlertIsSentToSubscribers(t *testing.T) {
+	stackService, server := createStackServiceWithSmtp(t, tools.SmtpSecurityStartTls, false, true)
+	assert.Nil(t, stackService.SetEmailSubscription("alice", "alice@example.org", nil))
+	assert.Nil(t, stackService.SetEmailSubscription("bob", "bob@example.org", []string{EventDiskNearlyFull}))
+
+	stackService.PublishEvent(StackEvent{Type: EventStackStateChanged, Stack: tools.NginxDefault})
+	stackService.PublishEvent(StackEvent{Type: EventBackupFailed, Stack: tools.NginxDefault, Message: "backup of stack 'nginx-default' failed", Details: map[string]string{"error": "disk full"}})
+	server.waitForMessages(t, 1)
+	time.Sleep(50 * time.Millisecond)
+	messages := server.getMessages()
+	assert.Equal(t, 1, len(messages))
+
+	message := messages[0]
+	assert.True(t, message.isEncrypted)
+	assert.Equal(t, "\x00ocelot\x00smtp-password", message.credentials)
+	assert.Equal(t, "ocelot@example.org", message.from)
+	assert.Equal(t, []string{"alice@example.org"}, message.recipients)
+	assert.True(t, strings.Contains(message.data, "Subject: [Ocelot] Backup of stack nginx-default failed\n"))
+	assert.True(t, strings.Contains(message.data, "To: alice@example.org\n"))
+	assert.True(t, strings.Contains(message.data, "error: disk full\n"))
+}
+
+func TestTestEmailIsSentViaTls(t *testing.T) {
+	stackService, server := createStackServiceWithSmtp(t, tools.SmtpSecurityTls, true, false)
+
+	assert.Nil(t, stackService.SendTestEmail("admin@example.org"))
+	messages := server.getMessages()
+	assert.Equal(t, 1, len(messages))
+	assert.True(t, messages[0].isEncrypted)
+	assert.True(t, strings.Contains(messages[0].data, "Subject: [Ocelot] Test email\n"))
+	assert.True(t, strings.Contains(messages[0].data, "The email alerts are set up correctly."))
+}
+
+func TestTestEmailFailsIfStartTlsIsNotSupported(t *testing.T) {
+	stackService, server := createStackServiceWithSmtp(t, tools.SmtpSecurityStartTls, false, false)
+
+	err := stackService.SendTestEmail("admin@example.org")
+	assert.NotNil(t, err)
+	assert.True(t, strings.Contains(err.Error(), "STARTTLS"))
+	assert.Equal(t, 0, len(server.getMessages()))
+}
+
+func TestTestEmailRequiresSmtpServer(t *testing.T) {
+	stackService := createStackService()
+	assert.True(t, errors.Is(stackService.SendTestEmail("admin@example.org"), ErrInvalidState))
+}
+
+func TestEmailSubscriptionsAreValidated(t *testing.T) {
+	stackService := createStackService()
+
+	assert.True(t, errors.Is(stackService.SetEmailSubscription("alice", "Alice <alice@example.org>", nil), ErrInvalidRequest))
+	assert.True(t, errors.Is(stackService.SetEmailSubscription("alice", "alice@example.org", []string{EventStackStateChanged}), ErrInvalidRequest))
+	assert.True(t, errors.Is(stackService.SetEmailSubscription("", "alice@example.org", nil), ErrInvalidRequest))
+	assert.Nil(t, stackService.SetEmailSubscription("alice", "alice@example.org", []string{EventStackDown}))
+	assert.Nil(t, stackService.SetEmailSubscription("alice", "alice@example.com", nil))
+
+	subscriptions, err := stackService.GetEmailSubscriptions()
+	assert.Nil(t, err)
+	assert.Equal(t, []EmailSubscription{{User: "alice", Email: "alice@example.com"}}, subscriptions)
+	assert.Nil(t, stackService.DeleteEmailSubscription("alice"))
+	assert.True(t, errors.Is(stackService.DeleteEmailSubscription("alice"), ErrNotFound))
+}
+
+func TestEmailTemplatesExistForAllAlerts(t *testing.T) {
+	notifier := ProvideEmailNotifier(ProvideInMemoryStackStores().EmailSubscriptionService, tools.SmtpConfig{From: "ocelot@example.org"}, "example.org")
+	for _, eventType := range append(EmailAlertEventTypes, emailTestTemplate) {
+		emailTemplate, ok := emailTemplates[eventType]
+		assert.True(t, ok)
+		message, err := notifier.render("admin@example.org", emailTemplate, emailData{Host: "example.org", Stack: tools.NginxDefault, Details: map[string]string{"host": "example.org"}})
+		assert.Nil(t, err)
+		assert.True(t, strings.Contains(string(message), "example.org"))
+	}
+}
@@ -0,0 +1,73 @@
+package internal
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// EmailSubscription selects the alerts a user receives by email. An empty list of events subscribes to all alerts.
+type EmailSubscription struct {
+	User   string
+	Email  string
+	Events []string
+}
+
+func (s EmailSubscription) isSubscribedTo(eventType string) bool {
+	return len(s.Events) == 0 || contains(s.Events, eventType)
+}
+
+type EmailSubscriptionServiceImpl struct {
+	db *sql.DB
+}
+
+func ProvideEmailSubscriptionService(db *sql.DB) *EmailSubscriptionServiceImpl {
+	createTableIfNotExisting(db, "email_subscriptions (user_name TEXT NOT NULL PRIMARY KEY, email TEXT NOT NULL, events TEXT NOT NULL)")
+	return &EmailSubscriptionServiceImpl{db}
+}
+
+func (s *EmailSubscriptionServiceImpl) SetSubscription(subscription EmailSubscription) error {
+	_, err := s.db.Exec("INSERT INTO email_subscriptions (user_name, email, events) VALUES (?, ?, ?) ON CONFLICT(user_name) DO UPDATE SET email = excluded.email, events = excluded.events",
+		subscription.User, subscription.Email, strings.Join(subscription.Events, ","))
+	if err != nil {
+		Logger.Error("failed to store email subscription of user '%s': %v", subscription.User, err)
+		return fmt.Errorf("failed to store email subscription")
+	}
+	return nil
+}
+
+func (s *EmailSubscriptionServiceImpl) RemoveSubscription(user string) error {
+	result, err := s.db.Exec("DELETE FROM email_subscriptions WHERE user_name = ?", user)
+	if err != nil {
+		Logger.Error("failed to delete email subscription of user '%s': %v", user, err)
+		return fmt.Errorf("failed to delete email subscription")
+	}
+	if affectedRows, _ := result.RowsAffected(); affectedRows == 0 {
+		return newStackError(ErrNotFound, "", "user '%s' has no email subscription", user)
+	}
+	return nil
+}
+
+// GetSubscriptions returns the subscriptions of all users, sorted by the name of the user.
+func (s *EmailSubscriptionServiceImpl) GetSubscriptions() ([]EmailSubscription, error) {
+	rows, err := s.db.Query("SELECT user_name, email, events FROM email_subscriptions ORDER BY user_name")
+	if err != nil {
+		Logger.Error("failed to query email subscriptions: %v", err)
+		return nil, fmt.Errorf("failed to read email subscriptions")
+	}
+	defer rows.Close()
+
+	subscriptions := make([]EmailSubscription, 0)
+	for rows.Next() {
+		var subscription EmailSubscription
+		var events string
+		if err := rows.Scan(&subscription.User, &subscription.Email, &events); err != nil {
+			return nil, err
+		}
+		if events != "" {
+			subscription.Events = strings.Split(events, ",")
+		}
+		subscriptions = append(subscriptions, subscription)
+	}
+	return subscriptions, rows.Err()
+}
@@ -0,0 +1,29 @@
+package internal
+
+import (
+	"github.com/ocelot-cloud/shared/assert"
+	"testing"
+)
+
+func TestEmailSubscriptionService(t *testing.T) {
+	database := ProvideDatabase(t.TempDir())
+	defer database.Close()
+	subscriptionService := ProvideEmailSubscriptionService(database)
+
+	assert.Nil(t, subscriptionService.SetSubscription(EmailSubscription{User: "bob", Email: "bob@example.org", Events: []string{EventStackDown, EventBackupFailed}}))
+	assert.Nil(t, subscriptionService.SetSubscription(EmailSubscription{User: "alice", Email: "alice@example.org"}))
+	assert.Nil(t, subscriptionService.SetSubscription(EmailSubscription{User: "bob", Email: "bob@example.com", Events: []string{EventDiskNearlyFull}}))
+
+	subscriptions, err := subscriptionService.GetSubscriptions()
+	assert.Nil(t, err)
+	assert.Equal(t, []EmailSubscription{
+		{User: "alice", Email: "alice@example.org"},
+		{User: "bob", Email: "bob@example.com", Events: []string{EventDiskNearlyFull}},
+	}, subscriptions)
+
+	assert.Nil(t, subscriptionService.RemoveSubscription("alice"))
+	assert.NotNil(t, subscriptionService.RemoveSubscription("alice"))
+	subscriptions, err = subscriptionService.GetSubscriptions()
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(subscriptions))
+}
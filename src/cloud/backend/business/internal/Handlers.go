@@ -1,11 +1,15 @@
 package internal
 
 import (
+	"crypto/subtle"
 	"encoding/json"
 	"fmt"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"io"
 	"net/http"
 	"ocelot/backend/config"
+	"sort"
+	"strings"
 )
 
 func checkSessionHandler(w http.ResponseWriter, r *http.Request) {
@@ -31,17 +35,31 @@ func createReadHandler(stackService StackService) http.HandlerFunc {
 			return
 		}
 
-		stackStateInfo := stackService.GetStackStateInfo()
-		response := make([]tools.ResponsePayloadDto, 0)
-		for stackName, stackDetails := range stackStateInfo {
-			response = append(response, tools.ResponsePayloadDto{stackName, stackDetails.State.String(), stackDetails.Path})
-		}
-
 		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(response)
+		json.NewEncoder(w).Encode(getStackOverview(stackService))
 	}
 }
 
+func getStackOverview(stackService StackService) []tools.ResponsePayloadDto {
+	stackStateInfo := stackService.GetStackStateInfo()
+	instances := stackService.GetStackInstances()
+	updates := stackService.GetAvailableUpdates()
+	response := make([]tools.ResponsePayloadDto, 0)
+	for stackName, stackDetails := range stackStateInfo {
+		response = append(response, tools.ResponsePayloadDto{
+			Name:            stackName,
+			State:           stackDetails.State.String(),
+			UrlPath:         stackDetails.Path,
+			Template:        instances[stackName],
+			UpdateAvailable: len(updates[stackName]) > 0,
+		})
+	}
+	sort.Slice(response, func(i, j int) bool {
+		return response[i].Name < response[j].Name
+	})
+	return response
+}
+
 func createDeployHandler(stackService StackService) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != "POST" {
@@ -58,7 +76,7 @@ func createDeployHandler(stackService StackService) http.HandlerFunc {
 		if err := stackService.DeployStack(stackName); err != nil {
 			if err != nil {
 				Logger.Error("Deploying stack failed: " + stackName + "\n" + err.Error() + "\n")
-				http.Error(w, "Deploying stack failed: "+stackName, http.StatusInternalServerError)
+				writeStackError(w, r, err)
 			}
 			return
 		}
@@ -81,13 +99,114 @@ func createStopHandler(stackService StackService) http.HandlerFunc {
 		if err := stackService.StopStack(stackName); err != nil {
 			if err != nil {
 				Logger.Warn("error when trying to stop stack, %s", err.Error())
-				http.Error(w, "Stopping stack failed: "+stackName, http.StatusInternalServerError)
+				writeStackError(w, r, err)
 			}
 			return
 		}
 	}
 }
 
+func toStackSecretDtos(secrets map[string]string) []tools.StackSecretDto {
+	response := make([]tools.StackSecretDto, 0)
+	for secretName, value := range secrets {
+		response = append(response, tools.StackSecretDto{Name: secretName, Value: value})
+	}
+	sort.Slice(response, func(i, j int) bool {
+		return response[i].Name < response[j].Name
+	})
+	return response
+}
+
+func toStackVersionDtos(versions []StackVersion) []tools.StackVersionDto {
+	response := make([]tools.StackVersionDto, 0)
+	for _, version := range versions {
+		response = append(response, tools.StackVersionDto{Version: version.Version, Status: version.Status, Timestamp: version.Timestamp})
+	}
+	return response
+}
+
+func getStackUpdateDtos(stackService StackService) []tools.StackUpdateDto {
+	response := make([]tools.StackUpdateDto, 0)
+	for stackName, updates := range stackService.GetAvailableUpdates() {
+		for _, update := range updates {
+			response = append(response, tools.StackUpdateDto{
+				Stack:         stackName,
+				Image:         update.Image,
+				CurrentDigest: update.CurrentDigest,
+				LatestDigest:  update.LatestDigest,
+				NewerTag:      update.NewerTag,
+			})
+		}
+	}
+	sort.Slice(response, func(i, j int) bool {
+		if response[i].Stack != response[j].Stack {
+			return response[i].Stack < response[j].Stack
+		}
+		return response[i].Image < response[j].Image
+	})
+	return response
+}
+
+func getStackInstanceDtos(stackService StackService) []tools.StackInstanceDto {
+	response := make([]tools.StackInstanceDto, 0)
+	for instanceName, templateName := range stackService.GetStackInstances() {
+		response = append(response, tools.StackInstanceDto{Name: instanceName, Template: templateName})
+	}
+	sort.Slice(response, func(i, j int) bool {
+		return response[i].Name < response[j].Name
+	})
+	return response
+}
+
+func getStackDomainDtos(stackService StackService) []tools.StackDomainDto {
+	response := make([]tools.StackDomainDto, 0)
+	for domain, stackName := range stackService.GetStackDomains() {
+		response = append(response, tools.StackDomainDto{Domain: domain, Stack: stackName})
+	}
+	sort.Slice(response, func(i, j int) bool {
+		return response[i].Domain < response[j].Domain
+	})
+	return response
+}
+
+func toAccessLogEntryDtos(entries []AccessLogEntry) []tools.AccessLogEntryDto {
+	response := make([]tools.AccessLogEntryDto, 0)
+	for _, entry := range entries {
+		response = append(response, tools.AccessLogEntryDto{
+			Timestamp: entry.Timestamp,
+			Host:      entry.Host,
+			Method:    entry.Method,
+			Path:      entry.Path,
+			Status:    entry.Status,
+			Bytes:     entry.Bytes,
+			LatencyMs: entry.LatencyMs,
+			ClientIp:  entry.ClientIp,
+			User:      entry.User,
+		})
+	}
+	return response
+}
+
+// createMetricsHandler provides the metrics to monitoring systems like Prometheus, which authenticate with
+// the configured token as bearer token.
+func createMetricsHandler(token string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "GET" {
+			http.Error(w, "Only GET method is supported.", http.StatusMethodNotAllowed)
+			return
+		}
+
+		providedToken := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(providedToken), []byte(token)) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		promhttp.HandlerFor(Metrics, promhttp.HandlerOpts{}).ServeHTTP(w, r)
+	}
+}
+
 func decodeStackInfo(r *http.Request) (string, error) {
 	body, err := io.ReadAll(r.Body)
 	if err != nil {
@@ -101,3 +220,68 @@ func decodeStackInfo(r *http.Request) (string, error) {
 	}
 	return stackInfo.Name, nil
 }
+
+func toStackStatsDto(stackName string, stats StackStats, history []StackStatsSample) tools.StackStatsDto {
+	response := tools.StackStatsDto{Stack: stackName, Containers: make([]tools.ContainerStatsDto, 0), Total: toContainerStatsDto(stats.Total), History: make([]tools.StackStatsSampleDto, 0)}
+	for _, container := range stats.Containers {
+		response.Containers = append(response.Containers, toContainerStatsDto(container))
+	}
+	for _, sample := range history {
+		response.History = append(response.History, tools.StackStatsSampleDto{Timestamp: sample.Timestamp, CpuPercent: sample.CpuPercent, MemoryUsageBytes: sample.MemoryUsageBytes})
+	}
+	return response
+}
+
+func toContainerStatsDto(stats ContainerStats) tools.ContainerStatsDto {
+	return tools.ContainerStatsDto{
+		Name:             stats.Name,
+		CpuPercent:       stats.CpuPercent,
+		MemoryUsageBytes: stats.MemoryUsageBytes,
+		MemoryLimitBytes: stats.MemoryLimitBytes,
+		NetworkRxBytes:   stats.NetworkRxBytes,
+		NetworkTxBytes:   stats.NetworkTxBytes,
+		BlockReadBytes:   stats.BlockReadBytes,
+		BlockWriteBytes:  stats.BlockWriteBytes,
+	}
+}
+
+func toSystemInfoDto(info SystemInfo) tools.SystemInfoDto {
+	response := tools.SystemInfoDto{
+		Disks:    make([]tools.DiskUsageDto, 0),
+		Memory:   tools.MemoryUsageDto{TotalBytes: info.Memory.TotalBytes, AvailableBytes: info.Memory.AvailableBytes, UsedPercent: info.Memory.UsedPercent},
+		Load:     tools.LoadAverageDto{Load1: info.Load.Load1, Load5: info.Load.Load5, Load15: info.Load.Load15, Cpus: info.Load.Cpus},
+		Docker:   tools.DockerInfoDto{Version: info.Docker.Version, ComposeVersion: info.Docker.ComposeVersion, DataRoot: info.Docker.DataRoot},
+		Stacks:   make([]tools.StackDiskUsageDto, 0),
+		Warnings: append(make([]string, 0), info.Warnings...),
+	}
+	for _, disk := range info.Disks {
+		response.Disks = append(response.Disks, tools.DiskUsageDto{Path: disk.Path, TotalBytes: disk.TotalBytes, UsedBytes: disk.UsedBytes, AvailableBytes: disk.AvailableBytes, UsedPercent: disk.UsedPercent})
+	}
+	for stackName, usage := range info.Stacks {
+		response.Stacks = append(response.Stacks, tools.StackDiskUsageDto{Stack: stackName, ImageBytes: usage.ImageBytes, VolumeBytes: usage.VolumeBytes})
+	}
+	sort.Slice(response.Stacks, func(i, j int) bool {
+		return response.Stacks[i].Stack < response.Stacks[j].Stack
+	})
+	return response
+}
+
+func toStackIncidentDtos(incidents []StackIncident) []tools.StackIncidentDto {
+	response := make([]tools.StackIncidentDto, 0)
+	for _, incident := range incidents {
+		response = append(response, tools.StackIncidentDto{Container: incident.Container, Kind: incident.Kind, Message: incident.Message, Timestamp: incident.Timestamp})
+	}
+	return response
+}
+
+func toBulkOperationResultDtos(results []StackOperationResult) []tools.BulkOperationResultDto {
+	response := make([]tools.BulkOperationResultDto, 0)
+	for _, result := range results {
+		resultDto := tools.BulkOperationResultDto{Stack: result.StackName, Success: result.Err == nil}
+		if result.Err != nil {
+			resultDto.Error = result.Err.Error()
+		}
+		response = append(response, resultDto)
+	}
+	return response
+}
@@ -0,0 +1,122 @@
+package internal
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"sort"
+	"sync"
+	"time"
+)
+
+const certificateCheckTimeout = 10 * time.Second
+
+// HostMonitor publishes events about problems of the host, which need the attention of the admin before they turn
+// into outages. Each problem is only published once, until it is solved.
+type HostMonitor struct {
+	stackService StackService
+	// certificateHosts are checked for expiring TLS certificates, custom domains of the stacks are added to them.
+	certificateHosts []string
+	certificatePort  string
+	now              func() time.Time
+	mu               sync.Mutex
+	fullDisks        map[string]bool
+	// expiringCertificates maps the hosts to the expiry of their reported certificates, so that a renewed
+	// certificate which is also about to expire is reported again.
+	expiringCertificates map[string]time.Time
+}
+
+func ProvideHostMonitor(stackService StackService, certificateHosts []string) *HostMonitor {
+	return &HostMonitor{stackService: stackService, certificateHosts: certificateHosts, certificatePort: "443", now: time.Now,
+		fullDisks: make(map[string]bool), expiringCertificates: make(map[string]time.Time)}
+}
+
+func (m *HostMonitor) StartPeriodicChecks(interval time.Duration) {
+	go func() {
+		for {
+			time.Sleep(interval)
+			m.CheckHost()
+		}
+	}()
+}
+
+func (m *HostMonitor) CheckHost() {
+	m.checkDisks(m.stackService.GetSystemInfo().Disks)
+	for _, host := range m.getCertificateHosts() {
+		m.checkCertificate(host)
+	}
+}
+
+func (m *HostMonitor) checkDisks(disks []DiskUsage) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, disk := range disks {
+		if disk.UsedPercent < DiskUsageWarningPercent {
+			delete(m.fullDisks, disk.Path)
+			continue
+		} else if m.fullDisks[disk.Path] {
+			continue
+		}
+		m.fullDisks[disk.Path] = true
+		m.stackService.PublishEvent(StackEvent{
+			Type:    EventDiskNearlyFull,
+			Message: fmt.Sprintf("disk of '%s' is %.0f%% full, %d MB are available", disk.Path, disk.UsedPercent, disk.AvailableBytes>>20),
+			Details: map[string]string{"path": disk.Path, "usedPercent": fmt.Sprintf("%.0f", disk.UsedPercent)},
+		})
+	}
+}
+
+func (m *HostMonitor) checkCertificate(host string) {
+	expiry, err := getCertificateExpiry(net.JoinHostPort(host, m.certificatePort))
+	if err != nil {
+		Logger.Debug("certificate of '%s' could not be checked: %v", host, err)
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	remaining := expiry.Sub(m.now())
+	if remaining >= CertificateExpiryWarning {
+		delete(m.expiringCertificates, host)
+		return
+	} else if reportedExpiry, ok := m.expiringCertificates[host]; ok && reportedExpiry.Equal(expiry) {
+		return
+	}
+	m.expiringCertificates[host] = expiry
+	m.stackService.PublishEvent(StackEvent{
+		Type:    EventCertificateExpiring,
+		Message: fmt.Sprintf("certificate of '%s' expires in %d days", host, int(remaining.Hours()/24)),
+		Details: map[string]string{"host": host, "expiresAt": expiry.Format(time.RFC3339)},
+	})
+}
+
+func (m *HostMonitor) getCertificateHosts() []string {
+	if len(m.certificateHosts) == 0 {
+		return nil
+	}
+	hosts := append([]string{}, m.certificateHosts...)
+	for domain := range m.stackService.GetStackDomains() {
+		hosts = append(hosts, domain)
+	}
+	sort.Strings(hosts[len(m.certificateHosts):])
+	return hosts
+}
+
+// getCertificateExpiry returns the expiry of the certificate presented by the server. The certificate is not
+// verified, since an expired or otherwise invalid certificate should be reported as well.
+func getCertificateExpiry(address string) (time.Time, error) {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return time.Time{}, err
+	}
+	dialer := &net.Dialer{Timeout: certificateCheckTimeout}
+	connection, err := tls.DialWithDialer(dialer, "tcp", address, &tls.Config{ServerName: host, InsecureSkipVerify: true})
+	if err != nil {
+		return time.Time{}, err
+	}
+	defer connection.Close()
+	certificates := connection.ConnectionState().PeerCertificates
+	if len(certificates) == 0 {
+		return time.Time{}, fmt.Errorf("server presented no certificate")
+	}
+	return certificates[0].NotAfter, nil
+}
@@ -0,0 +1,57 @@
+package internal
+
+import (
+	"github.com/ocelot-cloud/shared/assert"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestFullDiskIsPublishedOnce(t *testing.T) {
+	stackService := createStackService()
+	getEvents := collectEvents(stackService)
+	monitor := ProvideHostMonitor(stackService, nil)
+
+	monitor.checkDisks([]DiskUsage{{Path: "/", UsedPercent: 50}, {Path: "/data", UsedPercent: 97, AvailableBytes: 300 << 20}})
+	monitor.checkDisks([]DiskUsage{{Path: "/", UsedPercent: 50}, {Path: "/data", UsedPercent: 98}})
+	events := getEvents()
+	assert.Equal(t, 1, len(events))
+	assert.Equal(t, EventDiskNearlyFull, events[0].Type)
+	assert.Equal(t, "/data", events[0].Details["path"])
+	assert.Equal(t, "disk of '/data' is 97% full, 300 MB are available", events[0].Message)
+
+	monitor.checkDisks([]DiskUsage{{Path: "/data", UsedPercent: 60}})
+	monitor.checkDisks([]DiskUsage{{Path: "/data", UsedPercent: 96}})
+	assert.Equal(t, 2, len(getEvents()))
+}
+
+func TestExpiringCertificateIsPublishedOnce(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+	serverUrl, err := url.Parse(server.URL)
+	assert.Nil(t, err)
+	host, port, err := net.SplitHostPort(serverUrl.Host)
+	assert.Nil(t, err)
+
+	stackService := createStackService()
+	getEvents := collectEvents(stackService)
+	monitor := ProvideHostMonitor(stackService, []string{host})
+	monitor.certificatePort = port
+	expiry := server.Certificate().NotAfter
+
+	monitor.now = func() time.Time { return expiry.Add(-CertificateExpiryWarning - time.Hour) }
+	monitor.checkCertificate(host)
+	assert.Equal(t, 0, len(getEvents()))
+
+	monitor.now = func() time.Time { return expiry.Add(-72 * time.Hour) }
+	monitor.checkCertificate(host)
+	monitor.checkCertificate(host)
+	events := getEvents()
+	assert.Equal(t, 1, len(events))
+	assert.Equal(t, EventCertificateExpiring, events[0].Type)
+	assert.Equal(t, host, events[0].Details["host"])
+	assert.Equal(t, "certificate of '"+host+"' expires in 3 days", events[0].Message)
+}
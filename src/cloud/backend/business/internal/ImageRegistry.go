@@ -0,0 +1,187 @@
+package internal
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const dockerHubRegistry = "registry-1.docker.io"
+
+var manifestMediaTypes = []string{
+	"application/vnd.oci.image.index.v1+json",
+	"application/vnd.docker.distribution.manifest.list.v2+json",
+	"application/vnd.docker.distribution.manifest.v2+json",
+	"application/vnd.oci.image.manifest.v1+json",
+}
+
+var authenticateParameterPattern = regexp.MustCompile(`(\w+)="([^"]*)"`)
+
+// ImageReference is an image as it is referenced in a compose file, e.g. "gitea/gitea:1.20.2".
+type ImageReference struct {
+	Registry   string
+	Repository string
+	Tag        string
+}
+
+func (i ImageReference) String() string {
+	return i.Registry + "/" + i.Repository + ":" + i.Tag
+}
+
+func parseImageReference(image string) (ImageReference, error) {
+	if strings.Contains(image, "@") {
+		return ImageReference{}, fmt.Errorf("image '%s' is pinned to a digest", image)
+	}
+
+	name, tag := image, "latest"
+	if index := strings.LastIndex(image, ":"); index > strings.LastIndex(image, "/") {
+		name, tag = image[:index], image[index+1:]
+	}
+
+	registry := dockerHubRegistry
+	parts := strings.SplitN(name, "/", 2)
+	if len(parts) == 2 && (strings.ContainsAny(parts[0], ".:") || parts[0] == "localhost") {
+		registry, name = parts[0], parts[1]
+	}
+	if registry == "docker.io" || registry == "index.docker.io" {
+		registry = dockerHubRegistry
+	}
+	if registry == dockerHubRegistry && !strings.Contains(name, "/") {
+		name = "library/" + name
+	}
+	if name == "" || tag == "" {
+		return ImageReference{}, fmt.Errorf("invalid image '%s'", image)
+	}
+	return ImageReference{registry, name, tag}, nil
+}
+
+// ImageRegistryReal talks to registries implementing the Docker Registry HTTP API V2, such as Docker Hub.
+type ImageRegistryReal struct {
+	httpClient *http.Client
+	// insecureRegistries are accessed via plain HTTP, e.g. a registry running on the local machine.
+	insecureRegistries map[string]bool
+}
+
+func ProvideImageRegistryReal() *ImageRegistryReal {
+	return &ImageRegistryReal{&http.Client{Timeout: 30 * time.Second}, make(map[string]bool)}
+}
+
+func (r *ImageRegistryReal) GetDigest(image ImageReference) (string, error) {
+	request, err := http.NewRequest(http.MethodHead, r.getUrl(image, "manifests/"+image.Tag), nil)
+	if err != nil {
+		return "", err
+	}
+	request.Header.Set("Accept", strings.Join(manifestMediaTypes, ", "))
+
+	response, err := r.doWithAuthentication(request)
+	if err != nil {
+		return "", err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("registry responded with status %d to manifest request of image '%s'", response.StatusCode, image.String())
+	}
+
+	digest := response.Header.Get("Docker-Content-Digest")
+	if digest == "" {
+		return "", fmt.Errorf("registry did not return a digest for image '%s'", image.String())
+	}
+	return digest, nil
+}
+
+func (r *ImageRegistryReal) GetTags(image ImageReference) ([]string, error) {
+	request, err := http.NewRequest(http.MethodGet, r.getUrl(image, "tags/list"), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := r.doWithAuthentication(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("registry responded with status %d to tag list request of image '%s'", response.StatusCode, image.String())
+	}
+
+	var tagList struct {
+		Tags []string `json:"tags"`
+	}
+	if err = json.NewDecoder(response.Body).Decode(&tagList); err != nil {
+		return nil, err
+	}
+	return tagList.Tags, nil
+}
+
+func (r *ImageRegistryReal) getUrl(image ImageReference, path string) string {
+	scheme := "https"
+	if r.insecureRegistries[image.Registry] {
+		scheme = "http"
+	}
+	return fmt.Sprintf("%s://%s/v2/%s/%s", scheme, image.Registry, image.Repository, path)
+}
+
+// doWithAuthentication performs the request anonymously first. Most registries, including Docker Hub, then
+// demand a bearer token, which can be obtained without credentials from the realm stated in the response.
+func (r *ImageRegistryReal) doWithAuthentication(request *http.Request) (*http.Response, error) {
+	response, err := r.httpClient.Do(request)
+	if err != nil || response.StatusCode != http.StatusUnauthorized {
+		return response, err
+	}
+	response.Body.Close()
+
+	token, err := r.fetchToken(response.Header.Get("WWW-Authenticate"))
+	if err != nil {
+		return nil, err
+	}
+	authenticatedRequest := request.Clone(request.Context())
+	authenticatedRequest.Header.Set("Authorization", "Bearer "+token)
+	return r.httpClient.Do(authenticatedRequest)
+}
+
+func (r *ImageRegistryReal) fetchToken(authenticateHeader string) (string, error) {
+	if !strings.HasPrefix(authenticateHeader, "Bearer ") {
+		return "", fmt.Errorf("unsupported authentication challenge '%s'", authenticateHeader)
+	}
+	parameters := make(map[string]string)
+	for _, match := range authenticateParameterPattern.FindAllStringSubmatch(authenticateHeader, -1) {
+		parameters[match[1]] = match[2]
+	}
+
+	tokenUrl, err := url.Parse(parameters["realm"])
+	if err != nil || parameters["realm"] == "" {
+		return "", fmt.Errorf("invalid authentication realm in '%s'", authenticateHeader)
+	}
+	query := tokenUrl.Query()
+	for _, key := range []string{"service", "scope"} {
+		if value, ok := parameters[key]; ok {
+			query.Set(key, value)
+		}
+	}
+	tokenUrl.RawQuery = query.Encode()
+
+	response, err := r.httpClient.Get(tokenUrl.String())
+	if err != nil {
+		return "", err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("token request failed with status %d", response.StatusCode)
+	}
+
+	var tokenResponse struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err = json.NewDecoder(response.Body).Decode(&tokenResponse); err != nil {
+		return "", err
+	}
+	if tokenResponse.Token != "" {
+		return tokenResponse.Token, nil
+	}
+	return tokenResponse.AccessToken, nil
+}
@@ -0,0 +1,41 @@
+package internal
+
+import (
+	"fmt"
+	"sync"
+)
+
+type ImageRegistryMock struct {
+	mu      sync.Mutex
+	digests map[string]string
+	tags    map[string][]string
+}
+
+func ProvideImageRegistryMock() *ImageRegistryMock {
+	return &ImageRegistryMock{digests: make(map[string]string), tags: make(map[string][]string)}
+}
+
+// setImage pretends that the registry provides the image with the given digest, e.g. "nginx:1.24.0".
+func (r *ImageRegistryMock) setImage(image string, digest string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	reference, _ := parseImageReference(image)
+	r.digests[reference.String()] = digest
+	repository := reference.Registry + "/" + reference.Repository
+	r.tags[repository] = append(r.tags[repository], reference.Tag)
+}
+
+func (r *ImageRegistryMock) GetDigest(image ImageReference) (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if digest, ok := r.digests[image.String()]; ok {
+		return digest, nil
+	}
+	return "", fmt.Errorf("image '%s' does not exist in mock registry", image.String())
+}
+
+func (r *ImageRegistryMock) GetTags(image ImageReference) ([]string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string{}, r.tags[image.Registry+"/"+image.Repository]...), nil
+}
@@ -0,0 +1,92 @@
+package internal
+
+import (
+	"encoding/json"
+	"github.com/ocelot-cloud/shared/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestParseImageReference(t *testing.T) {
+	testCases := []struct {
+		image    string
+		expected ImageReference
+	}{
+		{"nginx", ImageReference{dockerHubRegistry, "library/nginx", "latest"}},
+		{"postgres:16-alpine", ImageReference{dockerHubRegistry, "library/postgres", "16-alpine"}},
+		{"gitea/gitea:1.20.2", ImageReference{dockerHubRegistry, "gitea/gitea", "1.20.2"}},
+		{"docker.io/bitnami/redis:7.0.15", ImageReference{dockerHubRegistry, "bitnami/redis", "7.0.15"}},
+		{"ghcr.io/org/app:v2", ImageReference{"ghcr.io", "org/app", "v2"}},
+		{"localhost:5000/app", ImageReference{"localhost:5000", "app", "latest"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.image, func(t *testing.T) {
+			reference, err := parseImageReference(tc.image)
+			assert.Nil(t, err)
+			assert.Equal(t, tc.expected, reference)
+		})
+	}
+}
+
+func TestImagesPinnedToDigestAreNotParsed(t *testing.T) {
+	_, err := parseImageReference("nginx@sha256:0123")
+	assert.NotNil(t, err)
+}
+
+func startFakeRegistry(t *testing.T) *httptest.Server {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/token" {
+			assert.Equal(t, "repository:library/nginx:pull", r.URL.Query().Get("scope"))
+			json.NewEncoder(w).Encode(map[string]string{"token": "secret-token"})
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer secret-token" {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="`+server.URL+`/token",service="fake-registry",scope="repository:library/nginx:pull"`)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Path {
+		case "/v2/library/nginx/manifests/1.24.0":
+			assert.True(t, strings.Contains(r.Header.Get("Accept"), "application/vnd.oci.image.index.v1+json"))
+			w.Header().Set("Docker-Content-Digest", "sha256:1240")
+		case "/v2/library/nginx/tags/list":
+			json.NewEncoder(w).Encode(map[string]interface{}{"name": "library/nginx", "tags": []string{"1.24.0", "1.25.3"}})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func createRegistryClientForFakeRegistry(t *testing.T) (*ImageRegistryReal, string) {
+	server := startFakeRegistry(t)
+	registryHost := strings.TrimPrefix(server.URL, "http://")
+	registry := ProvideImageRegistryReal()
+	registry.insecureRegistries[registryHost] = true
+	return registry, registryHost
+}
+
+func TestRegistryClientGetsDigestWithToken(t *testing.T) {
+	registry, registryHost := createRegistryClientForFakeRegistry(t)
+	digest, err := registry.GetDigest(ImageReference{registryHost, "library/nginx", "1.24.0"})
+	assert.Nil(t, err)
+	assert.Equal(t, "sha256:1240", digest)
+}
+
+func TestRegistryClientGetsTags(t *testing.T) {
+	registry, registryHost := createRegistryClientForFakeRegistry(t)
+	tags, err := registry.GetTags(ImageReference{registryHost, "library/nginx", "1.24.0"})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"1.24.0", "1.25.3"}, tags)
+}
+
+func TestRegistryClientFailsForUnknownImage(t *testing.T) {
+	registry, registryHost := createRegistryClientForFakeRegistry(t)
+	_, err := registry.GetDigest(ImageReference{registryHost, "library/nginx", "0.0.1"})
+	assert.NotNil(t, err)
+}
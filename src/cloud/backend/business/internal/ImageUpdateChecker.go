@@ -0,0 +1,168 @@
+package internal
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var semanticVersionPattern = regexp.MustCompile(`^(v?)(\d+(?:\.\d+){0,2})(-.+)?$`)
+
+// StackImage is an image used by a container of a running stack together with the digest it was pulled with.
+type StackImage struct {
+	Image  string
+	Digest string
+}
+
+// ImageUpdate describes a newer image for an image used by a stack. The tag may either point to a new digest,
+// e.g. a rebuilt "latest" image, or there is a newer semantic version tag, or both.
+type ImageUpdate struct {
+	Image         string
+	CurrentDigest string
+	LatestDigest  string
+	NewerTag      string
+}
+
+type ImageUpdateChecker struct {
+	dockerService DockerService
+	registry      ImageRegistry
+	mu            sync.Mutex
+	updates       map[string][]ImageUpdate
+	lastCheck     time.Time
+}
+
+func ProvideImageUpdateChecker(dockerService DockerService, registry ImageRegistry) *ImageUpdateChecker {
+	return &ImageUpdateChecker{dockerService: dockerService, registry: registry, updates: make(map[string][]ImageUpdate)}
+}
+
+func (c *ImageUpdateChecker) StartPeriodicChecks(interval time.Duration) {
+	go func() {
+		for {
+			c.CheckForUpdates()
+			time.Sleep(interval)
+		}
+	}()
+}
+
+func (c *ImageUpdateChecker) CheckForUpdates() {
+	stackStateInfo, err := c.dockerService.GetRunningStackStateInfo()
+	if err != nil {
+		Logger.Warn("checking for image updates failed, since the running stacks could not be determined")
+		return
+	}
+
+	updates := make(map[string][]ImageUpdate)
+	for stackName, stackDetails := range stackStateInfo {
+		if stackDetails.State == Uninitialized || stackName == "ocelot-cloud" {
+			continue
+		}
+		stackImages, err := c.dockerService.GetStackImages(stackName)
+		if err != nil {
+			Logger.Warn("failed to determine the images of stack '%s': %s", stackName, err.Error())
+			continue
+		}
+		for _, stackImage := range stackImages {
+			if update, ok := c.checkImage(stackImage); ok {
+				Logger.Info("Update available for image '%s' of stack '%s'", stackImage.Image, stackName)
+				updates[stackName] = append(updates[stackName], update)
+			}
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.updates = updates
+	c.lastCheck = time.Now()
+}
+
+func (c *ImageUpdateChecker) checkImage(stackImage StackImage) (ImageUpdate, bool) {
+	if stackImage.Digest == "" {
+		Logger.Debug("image '%s' was built locally, so there are no updates", stackImage.Image)
+		return ImageUpdate{}, false
+	}
+	reference, err := parseImageReference(stackImage.Image)
+	if err != nil {
+		Logger.Debug("skipping update check of image: %s", err.Error())
+		return ImageUpdate{}, false
+	}
+
+	update := ImageUpdate{Image: stackImage.Image, CurrentDigest: stackImage.Digest}
+	latestDigest, err := c.registry.GetDigest(reference)
+	if err != nil {
+		Logger.Warn("failed to get digest of image '%s' from registry: %s", stackImage.Image, err.Error())
+	} else if latestDigest != stackImage.Digest {
+		update.LatestDigest = latestDigest
+	}
+
+	if _, isSemanticVersion := parseSemanticVersion(reference.Tag); isSemanticVersion {
+		tags, err := c.registry.GetTags(reference)
+		if err != nil {
+			Logger.Warn("failed to get tags of image '%s' from registry: %s", stackImage.Image, err.Error())
+		} else {
+			update.NewerTag = findNewestTag(reference.Tag, tags)
+		}
+	}
+	return update, update.LatestDigest != "" || update.NewerTag != ""
+}
+
+func (c *ImageUpdateChecker) GetAvailableUpdates() map[string][]ImageUpdate {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	updatesClone := make(map[string][]ImageUpdate)
+	for stackName, updates := range c.updates {
+		updatesClone[stackName] = append([]ImageUpdate{}, updates...)
+	}
+	return updatesClone
+}
+
+type semanticVersion struct {
+	prefix  string
+	numbers []int
+	suffix  string
+}
+
+func parseSemanticVersion(tag string) (semanticVersion, bool) {
+	match := semanticVersionPattern.FindStringSubmatch(tag)
+	if match == nil {
+		return semanticVersion{}, false
+	}
+	version := semanticVersion{prefix: match[1], suffix: match[3]}
+	for _, number := range strings.Split(match[2], ".") {
+		value, _ := strconv.Atoi(number)
+		version.numbers = append(version.numbers, value)
+	}
+	return version, true
+}
+
+// isComparableTo is true for versions of the same format, e.g. "16-alpine" and "17-alpine", but not "16.1-alpine".
+func (v semanticVersion) isComparableTo(other semanticVersion) bool {
+	return v.prefix == other.prefix && v.suffix == other.suffix && len(v.numbers) == len(other.numbers)
+}
+
+func (v semanticVersion) isNewerThan(other semanticVersion) bool {
+	for i := range v.numbers {
+		if v.numbers[i] != other.numbers[i] {
+			return v.numbers[i] > other.numbers[i]
+		}
+	}
+	return false
+}
+
+// findNewestTag returns the newest tag which is newer than the current tag and has the same format or
+// an empty string, if there is no such tag.
+func findNewestTag(currentTag string, tags []string) string {
+	currentVersion, ok := parseSemanticVersion(currentTag)
+	if !ok {
+		return ""
+	}
+	newestTag, newestVersion := "", currentVersion
+	for _, tag := range tags {
+		version, ok := parseSemanticVersion(tag)
+		if ok && version.isComparableTo(currentVersion) && version.isNewerThan(newestVersion) {
+			newestTag, newestVersion = tag, version
+		}
+	}
+	return newestTag
+}
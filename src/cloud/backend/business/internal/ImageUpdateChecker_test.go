@@ -0,0 +1,83 @@
+package internal
+
+import (
+	"github.com/ocelot-cloud/shared/assert"
+	"ocelot/backend/config"
+	"testing"
+)
+
+func createUpdateChecker(t *testing.T) (*ImageUpdateChecker, *DockerServiceMock, *ImageRegistryMock) {
+	dockerService := ProvideServiceMock()
+	registry := ProvideImageRegistryMock()
+	assert.Nil(t, dockerService.DeployStack(tools.NginxDefault, nil))
+	return ProvideImageUpdateChecker(dockerService, registry), dockerService, registry
+}
+
+func TestNoUpdateIsReportedForUpToDateImages(t *testing.T) {
+	checker, dockerService, registry := createUpdateChecker(t)
+	dockerService.stackImages[tools.NginxDefault] = []StackImage{{"nginx:1.24.0", "sha256:1240"}}
+	registry.setImage("nginx:1.24.0", "sha256:1240")
+
+	checker.CheckForUpdates()
+	assert.Equal(t, 0, len(checker.GetAvailableUpdates()))
+}
+
+func TestNewDigestOfSameTagIsReported(t *testing.T) {
+	checker, dockerService, registry := createUpdateChecker(t)
+	dockerService.stackImages[tools.NginxDefault] = []StackImage{{"nginx:latest", "sha256:old"}}
+	registry.setImage("nginx:latest", "sha256:new")
+
+	checker.CheckForUpdates()
+	expectedUpdate := ImageUpdate{Image: "nginx:latest", CurrentDigest: "sha256:old", LatestDigest: "sha256:new"}
+	assert.Equal(t, []ImageUpdate{expectedUpdate}, checker.GetAvailableUpdates()[tools.NginxDefault])
+}
+
+func TestNewerSemanticVersionTagIsReported(t *testing.T) {
+	checker, dockerService, registry := createUpdateChecker(t)
+	dockerService.stackImages[tools.NginxDefault] = []StackImage{{"nginx:1.24.0", "sha256:1240"}}
+	registry.setImage("nginx:1.24.0", "sha256:1240")
+	registry.setImage("nginx:1.25.3", "sha256:1253")
+	registry.setImage("nginx:1.26.0-alpine", "sha256:1260")
+
+	checker.CheckForUpdates()
+	expectedUpdate := ImageUpdate{Image: "nginx:1.24.0", CurrentDigest: "sha256:1240", NewerTag: "1.25.3"}
+	assert.Equal(t, []ImageUpdate{expectedUpdate}, checker.GetAvailableUpdates()[tools.NginxDefault])
+}
+
+func TestLocallyBuiltImagesAreIgnored(t *testing.T) {
+	checker, dockerService, _ := createUpdateChecker(t)
+	dockerService.stackImages[tools.NginxDefault] = []StackImage{{"nginx-default-nginx-default", ""}}
+
+	checker.CheckForUpdates()
+	assert.Equal(t, 0, len(checker.GetAvailableUpdates()))
+}
+
+func TestUpdatesOfStoppedStacksAreNotReported(t *testing.T) {
+	checker, dockerService, registry := createUpdateChecker(t)
+	dockerService.stackImages[tools.NginxDefault] = []StackImage{{"nginx:latest", "sha256:old"}}
+	registry.setImage("nginx:latest", "sha256:new")
+	assert.Nil(t, dockerService.StopStack(tools.NginxDefault))
+
+	checker.CheckForUpdates()
+	assert.Equal(t, 0, len(checker.GetAvailableUpdates()))
+}
+
+func TestFindNewestTag(t *testing.T) {
+	testCases := []struct {
+		currentTag  string
+		tags        []string
+		expectedTag string
+	}{
+		{"1.20.2", []string{"1.20.1", "1.20.2", "1.21.0", "1.21.0-rc1", "2.0"}, "1.21.0"},
+		{"16-alpine", []string{"15-alpine", "16-alpine", "17-alpine", "17", "17.1-alpine"}, "17-alpine"},
+		{"v2.1", []string{"v2.1", "v2.10", "2.11"}, "v2.10"},
+		{"1.20.2", []string{"1.20.2", "1.9.9"}, ""},
+		{"latest", []string{"1.0.0"}, ""},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.currentTag, func(t *testing.T) {
+			assert.Equal(t, tc.expectedTag, findNewestTag(tc.currentTag, tc.tags))
+		})
+	}
+}
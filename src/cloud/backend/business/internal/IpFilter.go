@@ -0,0 +1,96 @@
+package internal
+
+import (
+	"fmt"
+	"net"
+	"strings"
+)
+
+// IpFilter decides by the IP of a client whether it may access an app or the dashboard. Denied networks
+// take precedence. If allowed networks are configured, all other clients are denied.
+type IpFilter struct {
+	allowed []*net.IPNet
+	denied  []*net.IPNet
+}
+
+func parseIpFilter(access AccessConfig) (IpFilter, error) {
+	allowed, err := parseNetworks(access.Allow)
+	if err != nil {
+		return IpFilter{}, err
+	}
+	denied, err := parseNetworks(access.Deny)
+	if err != nil {
+		return IpFilter{}, err
+	}
+	return IpFilter{allowed, denied}, nil
+}
+
+func (f IpFilter) isAllowed(ip net.IP) bool {
+	if ip == nil {
+		return len(f.allowed) == 0 && len(f.denied) == 0
+	}
+	if containsIp(f.denied, ip) {
+		return false
+	}
+	return len(f.allowed) == 0 || containsIp(f.allowed, ip)
+}
+
+// parseNetworks accepts CIDR ranges like "10.0.0.0/8" as well as single addresses like "192.168.1.5".
+func parseNetworks(entries []string) ([]*net.IPNet, error) {
+	var networks []*net.IPNet
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if !strings.Contains(entry, "/") {
+			ip := net.ParseIP(entry)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid IP address '%s'", entry)
+			}
+			if ip.To4() != nil {
+				entry += "/32"
+			} else {
+				entry += "/128"
+			}
+		}
+		_, network, err := net.ParseCIDR(entry)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CIDR range '%s'", entry)
+		}
+		networks = append(networks, network)
+	}
+	return networks, nil
+}
+
+func containsIp(networks []*net.IPNet, ip net.IP) bool {
+	for _, network := range networks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// getClientIpBehindProxies determines the IP of a client. The X-Forwarded-For header can be forged by
+// clients, so it is only evaluated if the request was sent by a trusted proxy. The header is read from
+// right to left, the first address which is not a trusted proxy is the client.
+func getClientIpBehindProxies(remoteAddr string, forwardedFor []string, trustedProxies []*net.IPNet) net.IP {
+	clientIp := net.ParseIP(removePort(remoteAddr))
+	if clientIp == nil || !containsIp(trustedProxies, clientIp) {
+		return clientIp
+	}
+
+	var hops []string
+	for _, header := range forwardedFor {
+		hops = append(hops, strings.Split(header, ",")...)
+	}
+	for i := len(hops) - 1; i >= 0; i-- {
+		hopIp := net.ParseIP(strings.TrimSpace(hops[i]))
+		if hopIp == nil {
+			break
+		}
+		clientIp = hopIp
+		if !containsIp(trustedProxies, hopIp) {
+			break
+		}
+	}
+	return clientIp
+}
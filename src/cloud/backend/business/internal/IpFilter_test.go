@@ -0,0 +1,65 @@
+package internal
+
+import (
+	"github.com/ocelot-cloud/shared/assert"
+	"net"
+	"testing"
+)
+
+func TestIpFilter(t *testing.T) {
+	testCases := []struct {
+		name     string
+		access   AccessConfig
+		ip       string
+		expected bool
+	}{
+		{"no restrictions", AccessConfig{}, "203.0.113.7", true},
+		{"allowed network", AccessConfig{Allow: []string{"10.0.0.0/8"}}, "10.1.2.3", true},
+		{"outside of allowed network", AccessConfig{Allow: []string{"10.0.0.0/8"}}, "203.0.113.7", false},
+		{"allowed single address", AccessConfig{Allow: []string{"203.0.113.7"}}, "203.0.113.7", true},
+		{"denied network", AccessConfig{Deny: []string{"203.0.113.0/24"}}, "203.0.113.7", false},
+		{"deny takes precedence", AccessConfig{Allow: []string{"10.0.0.0/8"}, Deny: []string{"10.0.0.5"}}, "10.0.0.5", false},
+		{"ipv6", AccessConfig{Allow: []string{"fd00::/8"}}, "fd12::1", true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			filter, err := parseIpFilter(tc.access)
+			assert.Nil(t, err)
+			assert.Equal(t, tc.expected, filter.isAllowed(net.ParseIP(tc.ip)))
+		})
+	}
+}
+
+func TestInvalidAccessConfigIsRejected(t *testing.T) {
+	_, err := parseIpFilter(AccessConfig{Allow: []string{"10.0.0.0/33"}})
+	assert.NotNil(t, err)
+	_, err = parseIpFilter(AccessConfig{Deny: []string{"office"}})
+	assert.NotNil(t, err)
+}
+
+func TestClientIpIsOnlyTakenFromTrustedProxies(t *testing.T) {
+	trustedProxies, err := parseNetworks([]string{"172.16.0.0/12", "10.0.0.1"})
+	assert.Nil(t, err)
+
+	testCases := []struct {
+		name         string
+		remoteAddr   string
+		forwardedFor []string
+		expected     string
+	}{
+		{"direct client", "203.0.113.7:5000", nil, "203.0.113.7"},
+		{"forged header of untrusted client", "203.0.113.7:5000", []string{"10.1.1.1"}, "203.0.113.7"},
+		{"trusted proxy", "172.18.0.2:5000", []string{"198.51.100.1"}, "198.51.100.1"},
+		{"chain of trusted proxies", "172.18.0.2:5000", []string{"6.6.6.6, 198.51.100.1", "10.0.0.1"}, "198.51.100.1"},
+		{"trusted proxy without header", "172.18.0.2:5000", nil, "172.18.0.2"},
+		{"garbage in header", "172.18.0.2:5000", []string{"unknown"}, "172.18.0.2"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			clientIp := getClientIpBehindProxies(tc.remoteAddr, tc.forwardedFor, trustedProxies)
+			assert.Equal(t, tc.expected, clientIp.String())
+		})
+	}
+}
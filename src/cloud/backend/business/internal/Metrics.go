@@ -0,0 +1,86 @@
+package internal
+
+import (
+	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+var defaultDurationBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 300}
+
+var httpRequestsTotal = newCounterVec("ocelot_http_requests_total", "Number of requests to the ocelot API by route, method and status.", "route", "method", "status")
+var httpRequestDuration = newHistogramVec("ocelot_http_request_duration_seconds", "Latency of requests to the ocelot API by route.", "route")
+var proxyRequestsTotal = newCounterVec("ocelot_proxy_requests_total", "Number of requests proxied to the apps by stack and status.", "stack", "status")
+var proxyRequestDuration = newHistogramVec("ocelot_proxy_request_duration_seconds", "Latency of requests proxied to the apps by stack.", "stack")
+var stackOperationDuration = newHistogramVec("ocelot_stack_operation_duration_seconds", "Duration of stack operations like deploy, stop and download.", "operation")
+var stackOperationFailures = newCounterVec("ocelot_stack_operation_failures_total", "Number of failed stack operations like deploy, stop and download.", "operation")
+var dockerCommandErrors = newCounterVec("ocelot_docker_command_errors_total", "Number of failed docker commands.", "command")
+var stackReconciliations = newCounterVec("ocelot_stack_reconciliations_total", "Number of deployments and stops performed to restore the desired state of stacks.", "action")
+var watchdogRestarts = newCounterVec("ocelot_watchdog_restarts_total", "Number of unhealthy containers restarted by the watchdog by stack.", "stack")
+var emailDeliveryFailures = newCounterVec("ocelot_email_delivery_failures_total", "Number of email alerts which could not be sent by event.", "event")
+var webhookDeliveryFailures = newCounterVec("ocelot_webhook_delivery_failures_total", "Number of events which could not be delivered to a webhook by event.", "event")
+
+func newCounterVec(name string, help string, labelNames ...string) *prometheus.CounterVec {
+	return promauto.With(Metrics).NewCounterVec(prometheus.CounterOpts{Name: name, Help: help}, labelNames)
+}
+
+func newHistogramVec(name string, help string, labelNames ...string) *prometheus.HistogramVec {
+	return promauto.With(Metrics).NewHistogramVec(prometheus.HistogramOpts{Name: name, Help: help, Buckets: defaultDurationBuckets}, labelNames)
+}
+
+// scrapeCollector provides the values of a metric at the time it is scraped, e.g. the current state of all stacks.
+type scrapeCollector struct {
+	description *prometheus.Desc
+	valueType   prometheus.ValueType
+	collect     func(emit func(value float64, labelValues ...string))
+}
+
+func (c *scrapeCollector) Describe(descriptions chan<- *prometheus.Desc) {
+	descriptions <- c.description
+}
+
+func (c *scrapeCollector) Collect(metrics chan<- prometheus.Metric) {
+	c.collect(func(value float64, labelValues ...string) {
+		metrics <- prometheus.MustNewConstMetric(c.description, c.valueType, value, labelValues...)
+	})
+}
+
+// registerScrapeCollector adds a collector to the metrics, a collector already registered for the same metric is
+// replaced.
+func registerScrapeCollector(name string, help string, valueType prometheus.ValueType, labelNames []string, collect func(emit func(value float64, labelValues ...string))) {
+	collector := &scrapeCollector{prometheus.NewDesc(name, help, labelNames, nil), valueType, collect}
+	Metrics.Unregister(collector)
+	Metrics.MustRegister(collector)
+}
+
+// instrumentRoute measures the requests to the routes of the ocelot API. The route template is used as label
+// instead of the path, so that paths with parameters don't create a time series each.
+func instrumentRoute(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		route := "unknown"
+		if currentRoute := mux.CurrentRoute(r); currentRoute != nil {
+			if template, err := currentRoute.GetPathTemplate(); err == nil {
+				route = template
+			}
+		}
+		recorder := newResponseRecorder(w)
+		startTime := time.Now()
+		next.ServeHTTP(recorder, r)
+		if recorder.status == 0 {
+			recorder.status = http.StatusOK
+		}
+		httpRequestsTotal.WithLabelValues(route, r.Method, strconv.Itoa(recorder.status)).Inc()
+		httpRequestDuration.WithLabelValues(route).Observe(time.Since(startTime).Seconds())
+	})
+}
+
+func registerStackStateCollector(stackService StackService) {
+	registerScrapeCollector("ocelot_stack_state", "Current state of each stack, the value is 1 for the current state.", prometheus.GaugeValue, []string{"stack", "state"}, func(emit func(value float64, labelValues ...string)) {
+		for stackName, stackDetails := range stackService.GetStackStateInfo() {
+			emit(1, stackName, stackDetails.State.String())
+		}
+	})
+}
@@ -0,0 +1,116 @@
+package internal
+
+import (
+	"github.com/gorilla/mux"
+	"github.com/ocelot-cloud/shared/assert"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+	"net/http"
+	"net/http/httptest"
+	"ocelot/backend/config"
+	"strings"
+	"testing"
+)
+
+func TestScrapeCollectorsAreReplaced(t *testing.T) {
+	for _, value := range []float64{1, 2} {
+		value := value
+		registerScrapeCollector("ocelot_test_state", "State.", prometheus.GaugeValue, []string{"stack"}, func(emit func(value float64, labelValues ...string)) {
+			emit(value, `quote"d`)
+		})
+	}
+	defer Metrics.Unregister(&scrapeCollector{description: prometheus.NewDesc("ocelot_test_state", "State.", []string{"stack"}, nil)})
+
+	output := scrapeMetrics(t)
+	assert.True(t, strings.Contains(output, "# TYPE ocelot_test_state gauge\nocelot_test_state{stack=\"quote\\\"d\"} 2\n"))
+}
+
+func TestMetricsEndpointRequiresToken(t *testing.T) {
+	handler := createMetricsHandler("secret-token")
+
+	recorder := httptest.NewRecorder()
+	handler(recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	assert.Equal(t, http.StatusUnauthorized, recorder.Code)
+
+	request := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	request.Header.Set("Authorization", "Bearer wrong-token")
+	recorder = httptest.NewRecorder()
+	handler(recorder, request)
+	assert.Equal(t, http.StatusUnauthorized, recorder.Code)
+
+	request.Header.Set("Authorization", "Bearer secret-token")
+	recorder = httptest.NewRecorder()
+	handler(recorder, request)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.True(t, strings.HasPrefix(recorder.Header().Get("Content-Type"), "text/plain"))
+}
+
+func scrapeMetrics(t *testing.T) string {
+	request := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	request.Header.Set("Authorization", "Bearer secret-token")
+	recorder := httptest.NewRecorder()
+	createMetricsHandler("secret-token")(recorder, request)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	return recorder.Body.String()
+}
+
+func getCounterValue(counter *prometheus.CounterVec, labelValues ...string) float64 {
+	return testutil.ToFloat64(counter.WithLabelValues(labelValues...))
+}
+
+func getSampleCount(t *testing.T, histogram *prometheus.HistogramVec, labelValues ...string) uint64 {
+	metric := &dto.Metric{}
+	assert.Nil(t, histogram.WithLabelValues(labelValues...).(prometheus.Metric).Write(metric))
+	return metric.GetHistogram().GetSampleCount()
+}
+
+func TestRoutesAreMeasuredByTemplate(t *testing.T) {
+	router := mux.NewRouter()
+	router.HandleFunc("/api/stacks/{name}/stats", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+	})
+	router.Use(instrumentRoute)
+	countBefore := getCounterValue(httpRequestsTotal, "/api/stacks/{name}/stats", "GET", "202")
+
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/api/stacks/gitea/stats", nil))
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/api/stacks/wiki/stats", nil))
+	assert.Equal(t, countBefore+2, getCounterValue(httpRequestsTotal, "/api/stacks/{name}/stats", "GET", "202"))
+}
+
+func TestStackOperationsAreMeasured(t *testing.T) {
+	stackService := createStackService()
+	deploymentsBefore := getSampleCount(t, stackOperationDuration, "deploy")
+	stopsBefore := getSampleCount(t, stackOperationDuration, "stop")
+	failuresBefore := getCounterValue(stackOperationFailures, "deploy")
+
+	assert.Nil(t, stackService.DeployStack(tools.NginxDefault))
+	assert.Nil(t, stackService.StopStack(tools.NginxDefault))
+	assert.NotNil(t, stackService.DeployStack("not-existing-stack"))
+
+	assert.Equal(t, deploymentsBefore+2, getSampleCount(t, stackOperationDuration, "deploy"))
+	assert.Equal(t, stopsBefore+1, getSampleCount(t, stackOperationDuration, "stop"))
+	assert.Equal(t, failuresBefore+1, getCounterValue(stackOperationFailures, "deploy"))
+}
+
+func TestStackStatesAreCollected(t *testing.T) {
+	stackService := createStackService()
+	assert.Nil(t, stackService.DeployStack(tools.NginxDefault))
+	registerStackStateCollector(stackService)
+
+	output := scrapeMetrics(t)
+	assert.True(t, strings.Contains(output, `ocelot_stack_state{stack="nginx-default",state="Available"} 1`))
+	assert.True(t, strings.Contains(output, `ocelot_stack_state{stack="nginx-default2",state="Uninitialized"} 1`))
+}
+
+func TestProxiedRequestsAreMeasured(t *testing.T) {
+	appProxy, _ := createAppProxy(t)
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer upstream.Close()
+	routeAllConnectionsTo(appProxy, upstream.Listener.Addr().String())
+	server := startProxyServer(t, appProxy)
+	countBefore := getSampleCount(t, proxyRequestDuration, tools.NginxDefault)
+
+	requestStack(t, server, tools.NginxDefault)
+	assert.Equal(t, countBefore+1, getSampleCount(t, proxyRequestDuration, tools.NginxDefault))
+}
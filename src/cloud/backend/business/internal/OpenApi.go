@@ -0,0 +1,148 @@
+package internal
+
+import (
+	"net/http"
+	"ocelot/backend/config"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var pathVariablePattern = regexp.MustCompile(`\{([^}:]+)(:[^}]*)?}`)
+
+// generateOpenApiDocument describes the routes as OpenAPI 3 document. The schemas are derived from the DTOs
+// via reflection, using their JSON names. Fields without "omitempty" are listed as required.
+func generateOpenApiDocument(routes []apiRoute) map[string]any {
+	generator := &openApiSchemaGenerator{schemas: make(map[string]any)}
+	errorSchema := generator.schemaOf(reflect.TypeOf(tools.ErrorDto{}))
+
+	paths := make(map[string]map[string]any)
+	for _, route := range routes {
+		responses := map[string]any{
+			strconv.Itoa(route.status): generator.describeContent(http.StatusText(route.status), route.response, route.contentType),
+			"default":                  map[string]any{"description": "Error", "content": jsonContent(errorSchema)},
+		}
+		operation := map[string]any{
+			"operationId": route.operationId,
+			"summary":     route.summary,
+			"parameters":  getOpenApiParameters(route),
+			"responses":   responses,
+		}
+		if route.request != nil {
+			operation["requestBody"] = map[string]any{"required": true, "content": jsonContent(generator.schemaOf(reflect.TypeOf(route.request)))}
+		}
+		if route.isPublic {
+			operation["security"] = []any{}
+		}
+
+		path := pathVariablePattern.ReplaceAllString(route.path, "{$1}")
+		if paths[path] == nil {
+			paths[path] = make(map[string]any)
+		}
+		paths[path][strings.ToLower(route.method)] = operation
+	}
+
+	return map[string]any{
+		"openapi": "3.0.3",
+		"info":    map[string]any{"title": "Ocelot Cloud API", "version": "v1"},
+		"servers": []any{map[string]any{"url": apiV1Prefix}},
+		"paths":   paths,
+		"components": map[string]any{
+			"schemas":         generator.schemas,
+			"securitySchemes": map[string]any{"cookieAuth": map[string]any{"type": "apiKey", "in": "cookie", "name": "auth"}},
+		},
+		"security": []any{map[string]any{"cookieAuth": []any{}}},
+	}
+}
+
+func getOpenApiParameters(route apiRoute) []any {
+	parameters := make([]any, 0)
+	for _, match := range pathVariablePattern.FindAllStringSubmatch(route.path, -1) {
+		parameters = append(parameters, map[string]any{"name": match[1], "in": "path", "required": true, "schema": map[string]any{"type": "string"}})
+	}
+	for _, param := range route.queryParams {
+		parameters = append(parameters, map[string]any{"name": param.name, "in": "query", "description": param.description, "schema": map[string]any{"type": param.kind}})
+	}
+	return parameters
+}
+
+func jsonContent(schema map[string]any) map[string]any {
+	return map[string]any{"application/json": map[string]any{"schema": schema}}
+}
+
+type openApiSchemaGenerator struct {
+	schemas map[string]any
+}
+
+func (g *openApiSchemaGenerator) describeContent(description string, body any, contentType string) map[string]any {
+	response := map[string]any{"description": description}
+	if body != nil && contentType != "" {
+		response["content"] = map[string]any{contentType: map[string]any{"schema": g.schemaOf(reflect.TypeOf(body))}}
+	} else if body != nil {
+		response["content"] = jsonContent(g.schemaOf(reflect.TypeOf(body)))
+	}
+	return response
+}
+
+// schemaOf returns the schema of a type. Structs are added to the components and referenced.
+func (g *openApiSchemaGenerator) schemaOf(t reflect.Type) map[string]any {
+	if t == reflect.TypeOf(time.Time{}) {
+		return map[string]any{"type": "string", "format": "date-time"}
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		return g.schemaOf(t.Elem())
+	case reflect.Struct:
+		if _, ok := g.schemas[t.Name()]; !ok {
+			g.schemas[t.Name()] = g.structSchemaOf(t)
+		}
+		return map[string]any{"$ref": "#/components/schemas/" + t.Name()}
+	case reflect.Slice, reflect.Array:
+		return map[string]any{"type": "array", "items": g.schemaOf(t.Elem())}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": g.schemaOf(t.Elem())}
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int64, reflect.Uint64:
+		return map[string]any{"type": "integer", "format": "int64"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return map[string]any{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	default:
+		return map[string]any{}
+	}
+}
+
+func (g *openApiSchemaGenerator) structSchemaOf(t reflect.Type) map[string]any {
+	properties := make(map[string]any)
+	required := make([]string, 0)
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name, options, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		properties[name] = g.schemaOf(field.Type)
+		if !strings.Contains(options, "omitempty") {
+			required = append(required, name)
+		}
+	}
+
+	schema := map[string]any{"type": "object", "properties": properties}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
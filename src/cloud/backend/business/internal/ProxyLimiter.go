@@ -0,0 +1,174 @@
+package internal
+
+import (
+	"sync"
+	"time"
+)
+
+const clientBucketIdleTimeout = 10 * time.Minute
+
+type LimitCounters struct {
+	RateLimited        uint64
+	ClientRateLimited  uint64
+	BodyTooLarge       uint64
+	ConcurrencyLimited uint64
+}
+
+type LimitViolation int
+
+const (
+	NoViolation LimitViolation = iota
+	RateLimitExceeded
+	ClientRateLimitExceeded
+	ConcurrencyLimitExceeded
+)
+
+func (v *LimitViolation) String() string {
+	return [...]string{"none", "stack rate limit", "client rate limit", "concurrency limit"}[*v]
+}
+
+// ProxyLimiter enforces the limits of the apps in the proxy. It keeps a token bucket per stack and per client
+// of a stack, as well as the number of requests currently processed by each stack.
+type ProxyLimiter struct {
+	mu        sync.Mutex
+	stacks    map[string]*stackLimiter
+	lastPrune time.Time
+	now       func() time.Time
+}
+
+type stackLimiter struct {
+	config             LimitConfig
+	bucket             *tokenBucket
+	clientBuckets      map[string]*tokenBucket
+	concurrentRequests int
+	counters           LimitCounters
+}
+
+func ProvideProxyLimiter() *ProxyLimiter {
+	return &ProxyLimiter{stacks: make(map[string]*stackLimiter), now: time.Now}
+}
+
+// Acquire admits a request of a client to a stack. Admitted requests must be released when they are done.
+func (l *ProxyLimiter) Acquire(stackName string, clientIp string, config LimitConfig) LimitViolation {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := l.now()
+	l.pruneIdleClients(now)
+	stack := l.getStackLimiter(stackName, config, now)
+
+	if config.MaxConcurrentRequests > 0 && stack.concurrentRequests >= config.MaxConcurrentRequests {
+		stack.counters.ConcurrencyLimited++
+		return ConcurrencyLimitExceeded
+	}
+
+	clientBucket, ok := stack.clientBuckets[clientIp]
+	if !ok {
+		clientBucket = newTokenBucket(config.ClientRequestsPerSecond, config.ClientBurst, now)
+		stack.clientBuckets[clientIp] = clientBucket
+	}
+	if !clientBucket.allow(now) {
+		stack.counters.ClientRateLimited++
+		return ClientRateLimitExceeded
+	}
+	if !stack.bucket.allow(now) {
+		stack.counters.RateLimited++
+		return RateLimitExceeded
+	}
+
+	stack.concurrentRequests++
+	return NoViolation
+}
+
+func (l *ProxyLimiter) Release(stackName string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if stack, ok := l.stacks[stackName]; ok && stack.concurrentRequests > 0 {
+		stack.concurrentRequests--
+	}
+}
+
+func (l *ProxyLimiter) CountBodyTooLarge(stackName string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if stack, ok := l.stacks[stackName]; ok {
+		stack.counters.BodyTooLarge++
+	}
+}
+
+func (l *ProxyLimiter) GetCounters() map[string]LimitCounters {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	counters := make(map[string]LimitCounters)
+	for stackName, stack := range l.stacks {
+		counters[stackName] = stack.counters
+	}
+	return counters
+}
+
+func (l *ProxyLimiter) getStackLimiter(stackName string, config LimitConfig, now time.Time) *stackLimiter {
+	stack, ok := l.stacks[stackName]
+	if !ok || stack.config != config {
+		newStack := &stackLimiter{
+			config:        config,
+			bucket:        newTokenBucket(config.RequestsPerSecond, config.Burst, now),
+			clientBuckets: make(map[string]*tokenBucket),
+		}
+		if ok {
+			newStack.concurrentRequests = stack.concurrentRequests
+			newStack.counters = stack.counters
+		}
+		l.stacks[stackName] = newStack
+		stack = newStack
+	}
+	return stack
+}
+
+// pruneIdleClients forgets clients which did not send requests for a while, so that the number of buckets
+// does not grow without bounds.
+func (l *ProxyLimiter) pruneIdleClients(now time.Time) {
+	if now.Sub(l.lastPrune) < time.Minute {
+		return
+	}
+	l.lastPrune = now
+	for _, stack := range l.stacks {
+		for clientIp, bucket := range stack.clientBuckets {
+			if now.Sub(bucket.lastUpdate) > clientBucketIdleTimeout {
+				delete(stack.clientBuckets, clientIp)
+			}
+		}
+	}
+}
+
+// tokenBucket allows bursts of requests up to its capacity, which refills at a constant rate. A bucket with
+// a negative rate never runs out of tokens.
+type tokenBucket struct {
+	rate       float64
+	capacity   float64
+	tokens     float64
+	lastUpdate time.Time
+}
+
+func newTokenBucket(rate float64, burst int, now time.Time) *tokenBucket {
+	capacity := float64(burst)
+	if capacity < 1 {
+		capacity = 1
+	}
+	return &tokenBucket{rate: rate, capacity: capacity, tokens: capacity, lastUpdate: now}
+}
+
+func (b *tokenBucket) allow(now time.Time) bool {
+	if b.rate < 0 {
+		b.lastUpdate = now
+		return true
+	}
+	b.tokens += now.Sub(b.lastUpdate).Seconds() * b.rate
+	if b.tokens > b.capacity {
+		b.tokens = b.capacity
+	}
+	b.lastUpdate = now
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
@@ -0,0 +1,87 @@
+package internal
+
+import (
+	"github.com/ocelot-cloud/shared/assert"
+	"testing"
+	"time"
+)
+
+func createProxyLimiter() (*ProxyLimiter, *time.Time) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	limiter := ProvideProxyLimiter()
+	limiter.now = func() time.Time { return now }
+	return limiter, &now
+}
+
+var unlimited = LimitConfig{RequestsPerSecond: -1, ClientRequestsPerSecond: -1, MaxConcurrentRequests: -1}
+
+func acquireAndRelease(limiter *ProxyLimiter, clientIp string, config LimitConfig) LimitViolation {
+	violation := limiter.Acquire("app", clientIp, config)
+	if violation == NoViolation {
+		limiter.Release("app")
+	}
+	return violation
+}
+
+func TestClientRateLimit(t *testing.T) {
+	limiter, now := createProxyLimiter()
+	config := unlimited
+	config.ClientRequestsPerSecond = 1
+	config.ClientBurst = 2
+
+	assert.Equal(t, NoViolation, acquireAndRelease(limiter, "10.0.0.1", config))
+	assert.Equal(t, NoViolation, acquireAndRelease(limiter, "10.0.0.1", config))
+	assert.Equal(t, ClientRateLimitExceeded, acquireAndRelease(limiter, "10.0.0.1", config))
+	assert.Equal(t, NoViolation, acquireAndRelease(limiter, "10.0.0.2", config))
+
+	*now = now.Add(time.Second)
+	assert.Equal(t, NoViolation, acquireAndRelease(limiter, "10.0.0.1", config))
+	assert.Equal(t, uint64(1), limiter.GetCounters()["app"].ClientRateLimited)
+}
+
+func TestStackRateLimitAppliesToAllClients(t *testing.T) {
+	limiter, now := createProxyLimiter()
+	config := unlimited
+	config.RequestsPerSecond = 2
+	config.Burst = 2
+
+	assert.Equal(t, NoViolation, acquireAndRelease(limiter, "10.0.0.1", config))
+	assert.Equal(t, NoViolation, acquireAndRelease(limiter, "10.0.0.2", config))
+	assert.Equal(t, RateLimitExceeded, acquireAndRelease(limiter, "10.0.0.3", config))
+
+	*now = now.Add(500 * time.Millisecond)
+	assert.Equal(t, NoViolation, acquireAndRelease(limiter, "10.0.0.3", config))
+	assert.Equal(t, uint64(1), limiter.GetCounters()["app"].RateLimited)
+}
+
+func TestConcurrencyLimit(t *testing.T) {
+	limiter, _ := createProxyLimiter()
+	config := unlimited
+	config.MaxConcurrentRequests = 2
+
+	assert.Equal(t, NoViolation, limiter.Acquire("app", "10.0.0.1", config))
+	assert.Equal(t, NoViolation, limiter.Acquire("app", "10.0.0.1", config))
+	assert.Equal(t, ConcurrencyLimitExceeded, limiter.Acquire("app", "10.0.0.1", config))
+	limiter.Release("app")
+	assert.Equal(t, NoViolation, limiter.Acquire("app", "10.0.0.1", config))
+	assert.Equal(t, uint64(1), limiter.GetCounters()["app"].ConcurrencyLimited)
+}
+
+func TestIdleClientsAreForgotten(t *testing.T) {
+	limiter, now := createProxyLimiter()
+	acquireAndRelease(limiter, "10.0.0.1", NoLimits)
+	assert.Equal(t, 1, len(limiter.stacks["app"].clientBuckets))
+
+	*now = now.Add(clientBucketIdleTimeout + time.Minute)
+	acquireAndRelease(limiter, "10.0.0.2", NoLimits)
+	assert.Equal(t, 1, len(limiter.stacks["app"].clientBuckets))
+}
+
+func TestLimitsFallBackToDefaults(t *testing.T) {
+	defaults := LimitConfig{RequestsPerSecond: 200, Burst: 400, MaxRequestBodyBytes: 1 << 30, MaxConcurrentRequests: 500}
+	limits := LimitConfig{RequestsPerSecond: 5, MaxRequestBodyBytes: -1}.withDefaults(defaults)
+	assert.Equal(t, float64(5), limits.RequestsPerSecond)
+	assert.Equal(t, defaults.Burst, limits.Burst)
+	assert.Equal(t, int64(-1), limits.MaxRequestBodyBytes)
+	assert.Equal(t, defaults.MaxConcurrentRequests, limits.MaxConcurrentRequests)
+}
@@ -0,0 +1,70 @@
+package internal
+
+import (
+	"fmt"
+	"html"
+	"net/http"
+	"strconv"
+)
+
+const appStartingRefreshSeconds = 5
+
+const statusPage = `<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8">%s<title>%s</title></head>
+<body style="font-family: sans-serif; text-align: center; margin-top: 10%%;">
+<h1>%s</h1>
+<p>%s</p>
+</body>
+</html>
+`
+
+// writeStatusPage answers a request with a human-readable page instead of a plain status code. The message
+// has to be escaped by the caller. A positive refresh interval makes the browser reload the page.
+func writeStatusPage(w http.ResponseWriter, statusCode int, title string, message string, refreshSeconds int) {
+	refresh := ""
+	if refreshSeconds > 0 {
+		refresh = fmt.Sprintf(`<meta http-equiv="refresh" content="%d">`, refreshSeconds)
+		w.Header().Set("Retry-After", strconv.Itoa(refreshSeconds))
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(statusCode)
+	fmt.Fprintf(w, statusPage, refresh, title, title, message)
+}
+
+func writeUnknownHostPage(w http.ResponseWriter, host string) {
+	message := fmt.Sprintf("There is no app available at <b>%s</b>.", html.EscapeString(host))
+	writeStatusPage(w, http.StatusNotFound, "404 - Not Found", message, 0)
+}
+
+func writeAppStartingPage(w http.ResponseWriter, stackName string) {
+	message := fmt.Sprintf("The app <b>%s</b> is starting. This page reloads automatically as soon as it is available.", html.EscapeString(stackName))
+	writeStatusPage(w, http.StatusServiceUnavailable, "App is starting", message, appStartingRefreshSeconds)
+}
+
+func writeAppNotRunningPage(w http.ResponseWriter, stackName string) {
+	message := fmt.Sprintf("The app <b>%s</b> is not running. It can be started in the Ocelot dashboard.", html.EscapeString(stackName))
+	writeStatusPage(w, http.StatusServiceUnavailable, "App is not running", message, 0)
+}
+
+func writeAppUnreachablePage(w http.ResponseWriter, stackName string) {
+	message := fmt.Sprintf("The app <b>%s</b> is running, but did not respond properly.", html.EscapeString(stackName))
+	writeStatusPage(w, http.StatusBadGateway, "502 - Bad Gateway", message, 0)
+}
+
+func writeTooManyRequestsPage(w http.ResponseWriter, stackName string) {
+	message := fmt.Sprintf("The app <b>%s</b> received too many requests. Please try again later.", html.EscapeString(stackName))
+	w.Header().Set("Retry-After", "1")
+	writeStatusPage(w, http.StatusTooManyRequests, "429 - Too Many Requests", message, 0)
+}
+
+func writeRequestTooLargePage(w http.ResponseWriter, stackName string) {
+	message := fmt.Sprintf("The request exceeds the maximum size accepted by the app <b>%s</b>.", html.EscapeString(stackName))
+	writeStatusPage(w, http.StatusRequestEntityTooLarge, "413 - Request Entity Too Large", message, 0)
+}
+
+func writeAccessDeniedPage(w http.ResponseWriter, target string) {
+	message := fmt.Sprintf("You are not allowed to access <b>%s</b> from your network.", html.EscapeString(target))
+	writeStatusPage(w, http.StatusForbidden, "403 - Forbidden", message, 0)
+}
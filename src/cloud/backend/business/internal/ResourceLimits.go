@@ -0,0 +1,216 @@
+package internal
+
+import (
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+var memoryUnits = map[string]uint64{
+	"":  1,
+	"b": 1,
+	"k": 1 << 10,
+	"m": 1 << 20,
+	"g": 1 << 30,
+}
+
+// parseMemorySize parses memory sizes like docker compose does, e.g. "512m" or "1.5g". Units are binary.
+func parseMemorySize(size string) (uint64, error) {
+	size = strings.ToLower(strings.TrimSpace(size))
+	unitIndex := strings.IndexFunc(size, func(r rune) bool {
+		return (r < '0' || r > '9') && r != '.'
+	})
+	if unitIndex < 0 {
+		unitIndex = len(size)
+	}
+	value, err := strconv.ParseFloat(size[:unitIndex], 64)
+	factor, ok := memoryUnits[strings.TrimSuffix(size[unitIndex:], "b")]
+	if unitIndex == 0 || err != nil || !ok || value < 0 {
+		return 0, fmt.Errorf("invalid memory size '%s'", size)
+	}
+	return uint64(value * float64(factor)), nil
+}
+
+func (r ResourceConfig) validate() error {
+	if r.Cpus < 0 || r.CpuReservation < 0 {
+		return fmt.Errorf("CPUs must not be negative")
+	}
+	if r.Cpus > 0 && r.CpuReservation > r.Cpus {
+		return fmt.Errorf("CPU reservation exceeds the CPU limit")
+	}
+	memory, err := r.getMemoryBytes()
+	if err != nil {
+		return err
+	}
+	memoryReservation, err := r.getMemoryReservationBytes()
+	if err != nil {
+		return err
+	}
+	if memory > 0 && memoryReservation > memory {
+		return fmt.Errorf("memory reservation exceeds the memory limit")
+	}
+	return nil
+}
+
+func (r ResourceConfig) isEmpty() bool {
+	return r == ResourceConfig{}
+}
+
+func (r ResourceConfig) getMemoryBytes() (uint64, error) {
+	if r.Memory == "" {
+		return 0, nil
+	}
+	return parseMemorySize(r.Memory)
+}
+
+func (r ResourceConfig) getMemoryReservationBytes() (uint64, error) {
+	if r.MemoryReservation == "" {
+		return 0, nil
+	}
+	return parseMemorySize(r.MemoryReservation)
+}
+
+// ResourceReservation is the amount of CPU and memory guaranteed to the services of a stack.
+type ResourceReservation struct {
+	Cpus        float64
+	MemoryBytes uint64
+}
+
+func (r ResourceReservation) add(other ResourceReservation) ResourceReservation {
+	return ResourceReservation{Cpus: r.Cpus + other.Cpus, MemoryBytes: r.MemoryBytes + other.MemoryBytes}
+}
+
+// getStackReservation sums up the reservations of all services of a stack, since they apply to each service.
+func getStackReservation(resources ResourceConfig, composeFile ComposeFile) (ResourceReservation, error) {
+	memoryReservation, err := resources.getMemoryReservationBytes()
+	if err != nil {
+		return ResourceReservation{}, err
+	}
+	services := len(composeFile.Services)
+	return ResourceReservation{Cpus: resources.CpuReservation * float64(services), MemoryBytes: memoryReservation * uint64(services)}, nil
+}
+
+// generateResourceOverride creates a compose override file, which applies the resources to every service.
+func generateResourceOverride(resources ResourceConfig, composeFile ComposeFile) ([]byte, error) {
+	limits := make(map[string]interface{})
+	reservations := make(map[string]interface{})
+	if resources.Cpus > 0 {
+		limits["cpus"] = strconv.FormatFloat(resources.Cpus, 'f', -1, 64)
+	}
+	if resources.Memory != "" {
+		limits["memory"] = resources.Memory
+	}
+	if resources.CpuReservation > 0 {
+		reservations["cpus"] = strconv.FormatFloat(resources.CpuReservation, 'f', -1, 64)
+	}
+	if resources.MemoryReservation != "" {
+		reservations["memory"] = resources.MemoryReservation
+	}
+
+	serviceResources := make(map[string]interface{})
+	if len(limits) > 0 {
+		serviceResources["limits"] = limits
+	}
+	if len(reservations) > 0 {
+		serviceResources["reservations"] = reservations
+	}
+	services := make(map[string]interface{})
+	for _, serviceName := range composeFile.getSortedServiceNames() {
+		services[serviceName] = map[string]interface{}{"deploy": map[string]interface{}{"resources": serviceResources}}
+	}
+	return yaml.Marshal(map[string]interface{}{"services": services})
+}
+
+func getResourceOverridePath(stackName string) string {
+	return filepath.Join(DataDir, "resources", stackName, "docker-compose.resources.yml")
+}
+
+// writeResourceOverrideFile writes the override file of a stack or removes it, if no resources are configured,
+// so that limits which were removed from the configuration don't apply anymore.
+func writeResourceOverrideFile(stackName string, composeFilePath string, resources ResourceConfig) error {
+	overridePath := getResourceOverridePath(stackName)
+	if resources.isEmpty() {
+		if err := os.Remove(overridePath); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+
+	composeFile, err := readComposeFile(composeFilePath)
+	if err != nil {
+		return err
+	}
+	override, err := generateResourceOverride(resources, composeFile)
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(filepath.Dir(overridePath), 0700); err != nil {
+		return err
+	}
+	return os.WriteFile(overridePath, override, 0600)
+}
+
+// getHostCapacity is a variable, so that tests can simulate hosts of any size.
+var getHostCapacity = func() (ResourceReservation, error) {
+	memory, err := getMemoryUsage()
+	if err != nil {
+		return ResourceReservation{}, err
+	}
+	return ResourceReservation{Cpus: float64(runtime.NumCPU()), MemoryBytes: memory.TotalBytes}, nil
+}
+
+// checkResourceReservations refuses the deployment of a stack, if its reservations together with the ones of the
+// other running stacks exceed the CPUs or memory of the host.
+func (sm *StackServiceImpl) checkResourceReservations(stackName string) error {
+	required, err := sm.getReservationOfStack(stackName)
+	if err != nil {
+		return err
+	}
+	if required == (ResourceReservation{}) {
+		return nil
+	}
+
+	stackStateInfo, err := sm.DockerService.GetRunningStackStateInfo()
+	if err != nil {
+		return err
+	}
+	for otherStackName, stackDetails := range stackStateInfo {
+		if otherStackName == stackName || stackDetails.State == Uninitialized {
+			continue
+		}
+		reservation, err := sm.getReservationOfStack(otherStackName)
+		if err != nil {
+			Logger.Warn("reservations of stack '%s' could not be determined: %v", otherStackName, err)
+			continue
+		}
+		required = required.add(reservation)
+	}
+
+	capacity, err := getHostCapacity()
+	if err != nil {
+		return fmt.Errorf("host capacity could not be determined: %w", err)
+	}
+	if required.Cpus > capacity.Cpus {
+		return newStackError(ErrInvalidState, stackName, "stack '%s' can't be deployed, running stacks would reserve %.2f CPUs but the host only has %.0f", stackName, required.Cpus, capacity.Cpus)
+	}
+	if required.MemoryBytes > capacity.MemoryBytes {
+		return newStackError(ErrInvalidState, stackName, "stack '%s' can't be deployed, running stacks would reserve %d MiB of memory but the host only has %d MiB", stackName, required.MemoryBytes>>20, capacity.MemoryBytes>>20)
+	}
+	return nil
+}
+
+func (sm *StackServiceImpl) getReservationOfStack(stackName string) (ResourceReservation, error) {
+	resources := sm.GetStackConfig(stackName).Resources
+	if resources.CpuReservation == 0 && resources.MemoryReservation == "" {
+		return ResourceReservation{}, nil
+	}
+	composeFile, err := readComposeFile(getStackPath(sm.InstanceService.GetTemplateName(stackName)))
+	if err != nil {
+		return ResourceReservation{}, err
+	}
+	return getStackReservation(resources, composeFile)
+}
@@ -0,0 +1,97 @@
+package internal
+
+import (
+	"github.com/ocelot-cloud/shared/assert"
+	"gopkg.in/yaml.v3"
+	"ocelot/backend/config"
+	"os"
+	"testing"
+)
+
+func TestParseMemorySize(t *testing.T) {
+	sizes := map[string]uint64{
+		"512":   512,
+		"100b":  100,
+		"64k":   64 << 10,
+		"512m":  512 << 20,
+		"512MB": 512 << 20,
+		"1.5g":  3 << 29,
+		"2G":    2 << 30,
+	}
+	for size, expected := range sizes {
+		actual, err := parseMemorySize(size)
+		assert.Nil(t, err)
+		assert.Equal(t, expected, actual)
+	}
+
+	for _, size := range []string{"", "m", "12x", "-1g", "1.2.3m"} {
+		_, err := parseMemorySize(size)
+		assert.NotNil(t, err)
+	}
+}
+
+func TestResourceConfigValidation(t *testing.T) {
+	assert.Nil(t, ResourceConfig{}.validate())
+	assert.Nil(t, ResourceConfig{Cpus: 2, Memory: "1g", CpuReservation: 0.5, MemoryReservation: "512m"}.validate())
+	assert.NotNil(t, ResourceConfig{Cpus: -1}.validate())
+	assert.NotNil(t, ResourceConfig{Memory: "lots"}.validate())
+	assert.NotNil(t, ResourceConfig{Cpus: 1, CpuReservation: 2}.validate())
+	assert.NotNil(t, ResourceConfig{Memory: "512m", MemoryReservation: "1g"}.validate())
+}
+
+func TestResourceOverrideAppliesToEveryService(t *testing.T) {
+	composeFile := ComposeFile{Services: map[string]ComposeService{"app": {}, "db": {}}}
+	resources := ResourceConfig{Cpus: 1.5, Memory: "1g", MemoryReservation: "256m"}
+
+	override, err := generateResourceOverride(resources, composeFile)
+	assert.Nil(t, err)
+
+	var parsedOverride map[string]map[string]map[string]map[string]map[string]map[string]string
+	assert.Nil(t, yaml.Unmarshal(override, &parsedOverride))
+	for _, serviceName := range []string{"app", "db"} {
+		serviceResources := parsedOverride["services"][serviceName]["deploy"]["resources"]
+		assert.Equal(t, map[string]string{"cpus": "1.5", "memory": "1g"}, serviceResources["limits"])
+		assert.Equal(t, map[string]string{"memory": "256m"}, serviceResources["reservations"])
+	}
+
+	reservation, err := getStackReservation(resources, composeFile)
+	assert.Nil(t, err)
+	assert.Equal(t, ResourceReservation{MemoryBytes: 512 << 20}, reservation)
+}
+
+func TestResourceOverrideFileIsRemovedWithoutConfiguration(t *testing.T) {
+	useTemporaryDataDir(t)
+	composeFilePath := getStackPath(tools.NginxDefault)
+
+	assert.Nil(t, writeResourceOverrideFile(tools.NginxDefault, composeFilePath, ResourceConfig{Memory: "512m"}))
+	_, err := os.Stat(getResourceOverridePath(tools.NginxDefault))
+	assert.Nil(t, err)
+
+	assert.Nil(t, writeResourceOverrideFile(tools.NginxDefault, composeFilePath, ResourceConfig{}))
+	_, err = os.Stat(getResourceOverridePath(tools.NginxDefault))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func simulateHostCapacity(t *testing.T, capacity ResourceReservation) {
+	originalGetHostCapacity := getHostCapacity
+	getHostCapacity = func() (ResourceReservation, error) { return capacity, nil }
+	t.Cleanup(func() { getHostCapacity = originalGetHostCapacity })
+}
+
+func TestDeploymentIsRefusedIfReservationsExceedHostCapacity(t *testing.T) {
+	simulateHostCapacity(t, ResourceReservation{Cpus: 2, MemoryBytes: 1 << 30})
+	stackService := createStackService()
+	reservation := ResourceConfig{MemoryReservation: "768m"}
+	stackService.StackConfigService = &StackConfigServiceImpl{map[string]StackConfig{
+		tools.NginxDefault:  {UrlPath: "/", Port: "80", Resources: reservation},
+		tools.NginxDefault2: {UrlPath: "/", Port: "80", Resources: reservation},
+	}}
+
+	assert.Nil(t, stackService.DeployStack(tools.NginxDefault))
+	assert.Nil(t, stackService.DeployStack(tools.NginxDefault))
+	assert.NotNil(t, stackService.DeployStack(tools.NginxDefault2))
+	assertState(t, stackService.GetStackStateInfo(), tools.NginxDefault2, Uninitialized)
+
+	assert.Nil(t, stackService.StopStack(tools.NginxDefault))
+	assert.Nil(t, stackService.DeployStack(tools.NginxDefault2))
+}
@@ -0,0 +1,240 @@
+package internal
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+const defaultSecretLength = 32
+const maxSecretLength = 1024
+const defaultSecretCharset = "alphanumeric"
+
+// The characters "$" and quotes are left out on purpose, since they have a special meaning in docker compose files.
+var secretCharsets = map[string]string{
+	"alphanumeric": "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789",
+	"alpha":        "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz",
+	"numeric":      "0123456789",
+	"hex":          "0123456789abcdef",
+	"symbols":      "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789!#%+-.:=?@_~",
+}
+
+var secretNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+type SecretServiceImpl struct {
+	db  *sql.DB
+	gcm cipher.AEAD
+}
+
+func ProvideSecretService(db *sql.DB, dataDir string) *SecretServiceImpl {
+	return newSecretService(db, loadOrCreateSecretKey(filepath.Join(dataDir, SecretKeyFileName)))
+}
+
+func newSecretService(db *sql.DB, key []byte) *SecretServiceImpl {
+	createTableIfNotExisting(db, "stack_secrets (stack_name TEXT NOT NULL, secret_name TEXT NOT NULL, encrypted_value TEXT NOT NULL, PRIMARY KEY (stack_name, secret_name))")
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		Logger.Fatal("failed to create cipher for secret encryption: %v", err)
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		Logger.Fatal("failed to create cipher for secret encryption: %v", err)
+	}
+	return &SecretServiceImpl{db, gcm}
+}
+
+func loadOrCreateSecretKey(keyFilePath string) []byte {
+	content, err := os.ReadFile(keyFilePath)
+	if err == nil {
+		key, decodeErr := hex.DecodeString(strings.TrimSpace(string(content)))
+		if decodeErr != nil || len(key) != 32 {
+			Logger.Fatal("secret key file %s is corrupted", keyFilePath)
+		}
+		return key
+	} else if !os.IsNotExist(err) {
+		Logger.Fatal("error when reading secret key file %s: %v", keyFilePath, err)
+	}
+
+	Logger.Info("No secret key found, generating a new one at %s", keyFilePath)
+	key := generateSecretKey()
+	if err := os.WriteFile(keyFilePath, []byte(hex.EncodeToString(key)), 0600); err != nil {
+		Logger.Fatal("error when writing secret key file %s: %v", keyFilePath, err)
+	}
+	return key
+}
+
+func generateSecretKey() []byte {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		Logger.Fatal("failed to generate secret key: %v", err)
+	}
+	return key
+}
+
+// GetOrGenerateSecrets returns the secrets of a stack. Secrets which are declared in the configuration but
+// do not exist yet, e.g. on the first installation of a stack, are generated and stored before.
+func (s *SecretServiceImpl) GetOrGenerateSecrets(stackName string, secretConfigs []SecretConfig) (map[string]string, error) {
+	secrets, err := s.GetSecrets(stackName)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, secretConfig := range secretConfigs {
+		if _, ok := secrets[secretConfig.Name]; ok {
+			continue
+		}
+		value, err := generateSecret(secretConfig)
+		if err != nil {
+			return nil, err
+		}
+		if err = s.storeSecret(stackName, secretConfig.Name, value); err != nil {
+			return nil, err
+		}
+		Logger.Info("Generated secret '%s' for stack '%s'", secretConfig.Name, stackName)
+		secrets[secretConfig.Name] = value
+	}
+	return secrets, nil
+}
+
+// ImportSecret stores a secret which was not generated by Ocelot, e.g. the legacy value of a secret.
+func (s *SecretServiceImpl) ImportSecret(stackName string, secretName string, value string) error {
+	return s.storeSecret(stackName, secretName, value)
+}
+
+func (s *SecretServiceImpl) RemoveSecrets(stackName string) error {
+	if _, err := s.db.Exec("DELETE FROM stack_secrets WHERE stack_name = ?", stackName); err != nil {
+		Logger.Error("failed to delete secrets of stack '%s': %v", stackName, err)
+		return fmt.Errorf("failed to delete secrets")
+	}
+	return nil
+}
+
+func (s *SecretServiceImpl) GetSecrets(stackName string) (map[string]string, error) {
+	rows, err := s.db.Query("SELECT secret_name, encrypted_value FROM stack_secrets WHERE stack_name = ?", stackName)
+	if err != nil {
+		Logger.Error("failed to query secrets of stack '%s': %v", stackName, err)
+		return nil, fmt.Errorf("failed to read secrets")
+	}
+	defer rows.Close()
+
+	secrets := make(map[string]string)
+	for rows.Next() {
+		var secretName, encryptedValue string
+		if err := rows.Scan(&secretName, &encryptedValue); err != nil {
+			return nil, err
+		}
+		value, err := s.decrypt(encryptedValue)
+		if err != nil {
+			Logger.Error("failed to decrypt secret '%s' of stack '%s': %v", secretName, stackName, err)
+			return nil, fmt.Errorf("failed to decrypt secret")
+		}
+		secrets[secretName] = value
+	}
+	return secrets, rows.Err()
+}
+
+func (s *SecretServiceImpl) storeSecret(stackName string, secretName string, value string) error {
+	encryptedValue, err := s.encrypt(value)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.Exec("INSERT INTO stack_secrets (stack_name, secret_name, encrypted_value) VALUES (?, ?, ?)", stackName, secretName, encryptedValue)
+	if err != nil {
+		Logger.Error("failed to store secret '%s' of stack '%s': %v", secretName, stackName, err)
+		return fmt.Errorf("failed to store secret")
+	}
+	return nil
+}
+
+func (s *SecretServiceImpl) encrypt(plainText string) (string, error) {
+	nonce := make([]byte, s.gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	cipherText := s.gcm.Seal(nonce, nonce, []byte(plainText), nil)
+	return base64.StdEncoding.EncodeToString(cipherText), nil
+}
+
+func (s *SecretServiceImpl) decrypt(encodedCipherText string) (string, error) {
+	cipherText, err := base64.StdEncoding.DecodeString(encodedCipherText)
+	if err != nil {
+		return "", err
+	}
+	nonceSize := s.gcm.NonceSize()
+	if len(cipherText) < nonceSize {
+		return "", fmt.Errorf("cipher text is too short")
+	}
+	plainText, err := s.gcm.Open(nil, cipherText[:nonceSize], cipherText[nonceSize:], nil)
+	if err != nil {
+		return "", err
+	}
+	return string(plainText), nil
+}
+
+func generateSecret(secretConfig SecretConfig) (string, error) {
+	if !secretNamePattern.MatchString(secretConfig.Name) {
+		return "", fmt.Errorf("invalid secret name '%s', only letters, digits and underscores are allowed", secretConfig.Name)
+	}
+
+	length := secretConfig.Length
+	if length == 0 {
+		length = defaultSecretLength
+	} else if length < 0 || length > maxSecretLength {
+		return "", fmt.Errorf("invalid length %d of secret '%s', at most %d characters are allowed", length, secretConfig.Name, maxSecretLength)
+	}
+
+	characters, err := getSecretCharacters(secretConfig)
+	if err != nil {
+		return "", err
+	}
+
+	maxIndex := big.NewInt(int64(len(characters)))
+	result := make([]byte, length)
+	for i := range result {
+		index, err := rand.Int(rand.Reader, maxIndex)
+		if err != nil {
+			return "", err
+		}
+		result[i] = characters[index.Int64()]
+	}
+	return string(result), nil
+}
+
+func getSecretCharacters(secretConfig SecretConfig) (string, error) {
+	if secretConfig.Characters != "" {
+		return secretConfig.Characters, validateSecretCharacters(secretConfig)
+	}
+	charset := secretConfig.Charset
+	if charset == "" {
+		charset = defaultSecretCharset
+	}
+	characters, ok := secretCharsets[charset]
+	if !ok {
+		return "", fmt.Errorf("unknown charset '%s' of secret '%s'", charset, secretConfig.Name)
+	}
+	return characters, nil
+}
+
+// validateSecretCharacters accepts custom characters of a secret, if they are unique and printable ASCII characters
+// without whitespace. Like for the charsets, "$" and quotes are not allowed.
+func validateSecretCharacters(secretConfig SecretConfig) error {
+	seen := make(map[rune]bool)
+	for _, character := range secretConfig.Characters {
+		if character <= ' ' || character > '~' || strings.ContainsRune("$\"'`", character) {
+			return fmt.Errorf("invalid character %q in the characters of secret '%s', only printable ASCII characters except whitespace, \"$\" and quotes are allowed", character, secretConfig.Name)
+		} else if seen[character] {
+			return fmt.Errorf("character %q appears more than once in the characters of secret '%s'", character, secretConfig.Name)
+		}
+		seen[character] = true
+	}
+	return nil
+}
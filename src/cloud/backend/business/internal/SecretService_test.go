@@ -0,0 +1,128 @@
+package internal
+
+import (
+	"github.com/ocelot-cloud/shared/assert"
+	"ocelot/backend/config"
+	"strings"
+	"testing"
+)
+
+func createSecretService(t *testing.T) (*SecretServiceImpl, string) {
+	dataDir := t.TempDir()
+	database := ProvideDatabase(dataDir)
+	t.Cleanup(func() { database.Close() })
+	return ProvideSecretService(database, dataDir), dataDir
+}
+
+func TestSecretsAreGeneratedOnlyOnce(t *testing.T) {
+	secretService, _ := createSecretService(t)
+	secretConfigs := []SecretConfig{{Name: "DB_PASSWORD"}}
+
+	secrets, err := secretService.GetOrGenerateSecrets(tools.NginxDefault, secretConfigs)
+	assert.Nil(t, err)
+	assert.Equal(t, defaultSecretLength, len(secrets["DB_PASSWORD"]))
+
+	secretsOfSecondInstall, err := secretService.GetOrGenerateSecrets(tools.NginxDefault, secretConfigs)
+	assert.Nil(t, err)
+	assert.Equal(t, secrets["DB_PASSWORD"], secretsOfSecondInstall["DB_PASSWORD"])
+}
+
+func TestSecretsAreStoredEncrypted(t *testing.T) {
+	secretService, dataDir := createSecretService(t)
+	secrets, err := secretService.GetOrGenerateSecrets(tools.NginxDefault, []SecretConfig{{Name: "DB_PASSWORD"}})
+	assert.Nil(t, err)
+
+	var storedValue string
+	err = secretService.db.QueryRow("SELECT encrypted_value FROM stack_secrets WHERE stack_name = ?", tools.NginxDefault).Scan(&storedValue)
+	assert.Nil(t, err)
+	assert.False(t, strings.Contains(storedValue, secrets["DB_PASSWORD"]))
+
+	restartedSecretService := ProvideSecretService(secretService.db, dataDir)
+	secretsAfterRestart, err := restartedSecretService.GetSecrets(tools.NginxDefault)
+	assert.Nil(t, err)
+	assert.Equal(t, secrets, secretsAfterRestart)
+}
+
+func TestSecretsAreSeparatedByStack(t *testing.T) {
+	secretService, _ := createSecretService(t)
+	_, err := secretService.GetOrGenerateSecrets(tools.NginxDefault, []SecretConfig{{Name: "DB_PASSWORD"}})
+	assert.Nil(t, err)
+
+	secrets, err := secretService.GetSecrets(tools.NginxDefault2)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(secrets))
+}
+
+func TestSecretGeneration(t *testing.T) {
+	testCases := []struct {
+		name               string
+		secretConfig       SecretConfig
+		expectedLength     int
+		expectedCharacters string
+	}{
+		{"Default", SecretConfig{Name: "A"}, defaultSecretLength, secretCharsets["alphanumeric"]},
+		{"Custom length", SecretConfig{Name: "A", Length: 64}, 64, secretCharsets["alphanumeric"]},
+		{"Named charset", SecretConfig{Name: "A", Length: 16, Charset: "hex"}, 16, secretCharsets["hex"]},
+		{"Custom characters", SecretConfig{Name: "A", Length: 8, Characters: "xy"}, 8, "xy"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			secret, err := generateSecret(tc.secretConfig)
+			assert.Nil(t, err)
+			assert.Equal(t, tc.expectedLength, len(secret))
+			for _, character := range secret {
+				assert.True(t, strings.ContainsRune(tc.expectedCharacters, character))
+			}
+		})
+	}
+}
+
+func TestInvalidSecretConfigsAreRejected(t *testing.T) {
+	_, err := generateSecret(SecretConfig{Name: "A", Charset: "unknown"})
+	assert.NotNil(t, err)
+	_, err = generateSecret(SecretConfig{Name: "A", Length: -1})
+	assert.NotNil(t, err)
+	_, err = generateSecret(SecretConfig{Name: "INVALID-NAME"})
+	assert.NotNil(t, err)
+	_, err = generateSecret(SecretConfig{Name: "A", Length: maxSecretLength + 1})
+	assert.NotNil(t, err)
+}
+
+func TestInvalidSecretCharactersAreRejected(t *testing.T) {
+	for _, characters := range []string{"aab", "ab c", "ab$", "ab'", "ab\"", "abä", "ab\n"} {
+		_, err := generateSecret(SecretConfig{Name: "A", Characters: characters})
+		assert.NotNil(t, err)
+	}
+	secret, err := generateSecret(SecretConfig{Name: "A", Characters: "!-_", Length: maxSecretLength})
+	assert.Nil(t, err)
+	assert.Equal(t, maxSecretLength, len(secret))
+}
+
+func TestSecretConfigIsReadFromYaml(t *testing.T) {
+	stackConfigService := ProvideStackConfigService(DefaultStackFileDir)
+	secrets := stackConfigService.GetStackConfig(tools.NginxCustomPort).Secrets
+	assert.Equal(t, 1, len(secrets))
+	assert.Equal(t, "SECRET_TOKEN", secrets[0].Name)
+	assert.Equal(t, 24, secrets[0].Length)
+}
+
+func TestLegacySecretIsKeptForExistingInstallations(t *testing.T) {
+	stackService := createStackService()
+	secretConfigs := []SecretConfig{{Name: "DB_PASSWORD", Legacy: "password"}}
+	stackService.StackConfigService = &StackConfigServiceImpl{map[string]StackConfig{
+		tools.NginxDefault:  {UrlPath: "/", Port: "80"},
+		tools.NginxDefault2: {UrlPath: "/", Port: "80"},
+	}}
+	assert.Nil(t, stackService.DeployStack(tools.NginxDefault))
+	stackService.StackConfigService.(*StackConfigServiceImpl).stackConfigs[tools.NginxDefault] = StackConfig{UrlPath: "/", Port: "80", Secrets: secretConfigs}
+	stackService.StackConfigService.(*StackConfigServiceImpl).stackConfigs[tools.NginxDefault2] = StackConfig{UrlPath: "/", Port: "80", Secrets: secretConfigs}
+
+	secrets, err := stackService.provideSecrets(tools.NginxDefault)
+	assert.Nil(t, err)
+	assert.Equal(t, "password", secrets["DB_PASSWORD"])
+
+	secretsOfNewInstallation, err := stackService.provideSecrets(tools.NginxDefault2)
+	assert.Nil(t, err)
+	assert.Equal(t, defaultSecretLength, len(secretsOfNewInstallation["DB_PASSWORD"]))
+}
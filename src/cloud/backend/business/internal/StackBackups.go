@@ -0,0 +1,179 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"time"
+)
+
+const backupTimestampFormat = "20060102-150405"
+const (
+	BackupReasonManual     = "manual"
+	BackupReasonPreUpgrade = "pre-upgrade"
+)
+
+// The id of a backup is the name of its directory, e.g. "20240301-134502-manual".
+var backupIdPattern = regexp.MustCompile(`^(\d{8}-\d{6})-(` + BackupReasonManual + `|` + BackupReasonPreUpgrade + `)$`)
+
+// StackBackup is a snapshot of the volumes of a stack, which is either created on request or before an upgrade.
+type StackBackup struct {
+	Id        string
+	Timestamp time.Time
+	Reason    string
+}
+
+// BackupStack archives the volumes of a stack. A running stack is stopped during the backup, so that the
+// archives are consistent, and deployed again afterward.
+func (sm *StackServiceImpl) BackupStack(stackName string) (StackBackup, error) {
+	stackDetails, ok := sm.GetStackStateInfo()[stackName]
+	if !ok {
+		return StackBackup{}, logAndCreateStackNotFoundError(stackName)
+	}
+	if !sm.markBackupAsStarted(stackName, BackingUp) {
+		return StackBackup{}, newStackError(ErrInvalidState, stackName, "stack '%s' is busy with another operation, try again later", stackName)
+	}
+	defer sm.markBackupAsFinished(stackName)
+
+	timestamp := time.Now()
+	backup := StackBackup{Id: timestamp.Format(backupTimestampFormat) + "-" + BackupReasonManual, Timestamp: timestamp.Truncate(time.Second), Reason: BackupReasonManual}
+	backupDir := getBackupDir(stackName, backup.Id)
+	if err := os.MkdirAll(filepath.Dir(backupDir), 0700); err != nil {
+		return StackBackup{}, err
+	}
+	if err := os.Mkdir(backupDir, 0700); errors.Is(err, os.ErrExist) {
+		return StackBackup{}, newStackError(ErrInvalidState, stackName, "a backup of stack '%s' was just created, try again in a second", stackName)
+	} else if err != nil {
+		return StackBackup{}, err
+	}
+
+	Logger.Info("Backing up stack '%s'", stackName)
+	err := sm.whileStackIsStopped(stackName, stackDetails.State, func() error {
+		return sm.DockerService.BackupStackVolumes(stackName, backupDir)
+	})
+	if err != nil {
+		Logger.Error("backup of stack '%s' failed: %s", stackName, err.Error())
+		if removeErr := os.RemoveAll(backupDir); removeErr != nil {
+			Logger.Warn("failed to remove incomplete backup '%s'", backupDir)
+		}
+		sm.Events.Publish(StackEvent{Type: EventBackupFailed, Stack: stackName, Message: fmt.Sprintf("backup of stack '%s' failed", stackName), Details: map[string]string{"error": err.Error()}})
+		return StackBackup{}, err
+	}
+	sm.Events.Publish(StackEvent{Type: EventBackupSucceeded, Stack: stackName, Message: fmt.Sprintf("backup '%s' of stack '%s' was created", backup.Id, stackName), Details: map[string]string{"backup": backup.Id}})
+	return backup, nil
+}
+
+// GetStackBackups returns the backups of a stack, the newest one first.
+func (sm *StackServiceImpl) GetStackBackups(stackName string) ([]StackBackup, error) {
+	entries, err := os.ReadDir(filepath.Join(DataDir, "backups", stackName))
+	if os.IsNotExist(err) {
+		return []StackBackup{}, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	backups := make([]StackBackup, 0)
+	for _, entry := range entries {
+		if backup, ok := parseBackupId(entry.Name()); ok && entry.IsDir() {
+			backups = append(backups, backup)
+		}
+	}
+	sort.Slice(backups, func(i, j int) bool {
+		return backups[i].Id > backups[j].Id
+	})
+	return backups, nil
+}
+
+// RestoreStack replaces the content of the volumes of a stack by the ones of a backup. A running stack is
+// stopped during the restore and deployed again afterward.
+func (sm *StackServiceImpl) RestoreStack(stackName string, backupId string) error {
+	stackDetails, ok := sm.GetStackStateInfo()[stackName]
+	if !ok {
+		return logAndCreateStackNotFoundError(stackName)
+	}
+	backupDir := getBackupDir(stackName, backupId)
+	if _, ok = parseBackupId(backupId); !ok {
+		return newStackError(ErrNotFound, stackName, "backup '%s' of stack '%s' does not exist", backupId, stackName)
+	} else if _, err := os.Stat(backupDir); err != nil {
+		return newStackError(ErrNotFound, stackName, "backup '%s' of stack '%s' does not exist", backupId, stackName)
+	}
+	if !sm.markBackupAsStarted(stackName, Restoring) {
+		return newStackError(ErrInvalidState, stackName, "stack '%s' is busy with another operation, try again later", stackName)
+	}
+	defer sm.markBackupAsFinished(stackName)
+
+	Logger.Info("Restoring backup '%s' of stack '%s'", backupId, stackName)
+	return sm.whileStackIsStopped(stackName, stackDetails.State, func() error {
+		return sm.DockerService.RestoreStackVolumes(stackName, backupDir)
+	})
+}
+
+// whileStackIsStopped runs the action while the containers of a stack are stopped. A stack which was running
+// before is deployed again, even if the action failed.
+func (sm *StackServiceImpl) whileStackIsStopped(stackName string, state StackState, action func() error) error {
+	wasRunning := state == Starting || isRunningState(state)
+	if wasRunning {
+		if err := sm.DockerService.StopStack(stackName); err != nil {
+			return err
+		}
+	}
+	actionErr := action()
+	if !wasRunning {
+		return actionErr
+	}
+
+	secrets, err := sm.SecretService.GetSecrets(stackName)
+	if err == nil {
+		err = sm.DockerService.DeployStack(stackName, secrets)
+	}
+	sm.Watchdog.Reset(stackName)
+	if err != nil {
+		Logger.Error("failed to deploy stack '%s' again: %s", stackName, err.Error())
+		if actionErr == nil {
+			return err
+		}
+	}
+	return actionErr
+}
+
+func getBackupDir(stackName string, backupId string) string {
+	return filepath.Join(DataDir, "backups", stackName, backupId)
+}
+
+func parseBackupId(backupId string) (StackBackup, bool) {
+	match := backupIdPattern.FindStringSubmatch(backupId)
+	if match == nil {
+		return StackBackup{}, false
+	}
+	timestamp, err := time.ParseInLocation(backupTimestampFormat, match[1], time.Local)
+	if err != nil {
+		return StackBackup{}, false
+	}
+	return StackBackup{Id: backupId, Timestamp: timestamp, Reason: match[2]}, true
+}
+
+func (sm *StackServiceImpl) markBackupAsStarted(stackName string, state StackState) bool {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	if _, ok := sm.backupsInProgress[stackName]; ok || sm.restartsInProgress[stackName] || sm.upgradesInProgress[stackName] {
+		return false
+	}
+	sm.backupsInProgress[stackName] = state
+	return true
+}
+
+func (sm *StackServiceImpl) markBackupAsFinished(stackName string) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	delete(sm.backupsInProgress, stackName)
+}
+
+func (sm *StackServiceImpl) getBackupInProgress(stackName string) (StackState, bool) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	state, ok := sm.backupsInProgress[stackName]
+	return state, ok
+}
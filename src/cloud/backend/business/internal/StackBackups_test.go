@@ -0,0 +1,87 @@
+package internal
+
+import (
+	"errors"
+	"github.com/ocelot-cloud/shared/assert"
+	"ocelot/backend/config"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func createStackServiceForBackups(t *testing.T) *StackServiceImpl {
+	useTemporaryDataDir(t)
+	return createStackService()
+}
+
+func TestBackupOfRunningStack(t *testing.T) {
+	stackService := createStackServiceForBackups(t)
+	dockerServiceMock := stackService.DockerService.(*DockerServiceMock)
+	assert.Nil(t, stackService.DeployStack(tools.NginxDefault))
+
+	backup, err := stackService.BackupStack(tools.NginxDefault)
+	assert.Nil(t, err)
+	assert.Equal(t, BackupReasonManual, backup.Reason)
+	assert.Equal(t, []string{tools.NginxDefault}, dockerServiceMock.backedUpStacks)
+	assertState(t, stackService.GetStackStateInfo(), tools.NginxDefault, Available)
+
+	backups, err := stackService.GetStackBackups(tools.NginxDefault)
+	assert.Nil(t, err)
+	assert.Equal(t, []StackBackup{backup}, backups)
+}
+
+func TestBackupsAreListedNewestFirst(t *testing.T) {
+	stackService := createStackServiceForBackups(t)
+	for _, backupId := range []string{"20240301-120000-manual", "20240302-120000-pre-upgrade", "not-a-backup"} {
+		assert.Nil(t, os.MkdirAll(getBackupDir(tools.NginxDefault, backupId), 0700))
+	}
+
+	backups, err := stackService.GetStackBackups(tools.NginxDefault)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(backups))
+	assert.Equal(t, "20240302-120000-pre-upgrade", backups[0].Id)
+	assert.Equal(t, BackupReasonPreUpgrade, backups[0].Reason)
+	assert.Equal(t, "20240301-120000-manual", backups[1].Id)
+
+	backups, err = stackService.GetStackBackups(tools.NginxDefault2)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(backups))
+}
+
+func TestRestoreOfStoppedStack(t *testing.T) {
+	stackService := createStackServiceForBackups(t)
+	dockerServiceMock := stackService.DockerService.(*DockerServiceMock)
+	backup, err := stackService.BackupStack(tools.NginxDefault)
+	assert.Nil(t, err)
+
+	assert.Nil(t, stackService.RestoreStack(tools.NginxDefault, backup.Id))
+	assert.Equal(t, []string{tools.NginxDefault}, dockerServiceMock.restoredStacks)
+	assertState(t, stackService.GetStackStateInfo(), tools.NginxDefault, Uninitialized)
+}
+
+func TestRestoreOfUnknownBackupFails(t *testing.T) {
+	stackService := createStackServiceForBackups(t)
+	assert.Nil(t, os.MkdirAll(filepath.Join(DataDir, "backups", tools.NginxDefault2, "20240301-120000-manual"), 0700))
+
+	for _, backupId := range []string{"20240301-120000-manual", "../" + tools.NginxDefault2 + "/20240301-120000-manual", "unknown"} {
+		err := stackService.RestoreStack(tools.NginxDefault, backupId)
+		assert.True(t, errors.Is(err, ErrNotFound))
+	}
+	_, err := stackService.BackupStack("not-existing")
+	assert.True(t, errors.Is(err, ErrNotFound))
+}
+
+func TestStackCanNotBeDeployedDuringBackup(t *testing.T) {
+	stackService := createStackServiceForBackups(t)
+	assert.True(t, stackService.markBackupAsStarted(tools.NginxDefault, BackingUp))
+	assertState(t, stackService.GetStackStateInfo(), tools.NginxDefault, BackingUp)
+
+	err := stackService.DeployStack(tools.NginxDefault)
+	assert.True(t, errors.Is(err, ErrInvalidState))
+	_, err = stackService.BackupStack(tools.NginxDefault)
+	assert.True(t, errors.Is(err, ErrInvalidState))
+	assert.NotNil(t, stackService.RestartStack(tools.NginxDefault))
+
+	stackService.markBackupAsFinished(tools.NginxDefault)
+	assert.Nil(t, stackService.DeployStack(tools.NginxDefault))
+}
@@ -0,0 +1,102 @@
+package internal
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// validateStackDependencies ensures that stacks only depend on existing stacks and that there are no cycles,
+// which would make it impossible to decide which stack has to be deployed first.
+func validateStackDependencies(stackConfigs map[string]StackConfig) error {
+	var stackNames []string
+	for stackName := range stackConfigs {
+		stackNames = append(stackNames, stackName)
+	}
+	sort.Strings(stackNames)
+
+	for _, stackName := range stackNames {
+		for _, dependency := range stackConfigs[stackName].DependsOn {
+			if _, ok := stackConfigs[dependency]; !ok {
+				return fmt.Errorf("stack '%s' depends on stack '%s', which does not exist", stackName, dependency)
+			}
+		}
+	}
+
+	isFinished := make(map[string]bool)
+	for _, stackName := range stackNames {
+		if cycle := findDependencyCycle(stackConfigs, stackName, nil, isFinished); cycle != nil {
+			return fmt.Errorf("stacks depend on each other in a cycle: %s", strings.Join(cycle, " -> "))
+		}
+	}
+	return nil
+}
+
+// findDependencyCycle performs a depth-first search and returns the cycle, if the current path leads back to a
+// stack on it.
+func findDependencyCycle(stackConfigs map[string]StackConfig, stackName string, path []string, isFinished map[string]bool) []string {
+	for i, stackOnPath := range path {
+		if stackOnPath == stackName {
+			return append(append([]string{}, path[i:]...), stackName)
+		}
+	}
+	if isFinished[stackName] {
+		return nil
+	}
+	path = append(path, stackName)
+	for _, dependency := range stackConfigs[stackName].DependsOn {
+		if cycle := findDependencyCycle(stackConfigs, dependency, path, isFinished); cycle != nil {
+			return cycle
+		}
+	}
+	isFinished[stackName] = true
+	return nil
+}
+
+// deployDependencies deploys the stacks a stack depends on and waits until they are available. Dependencies of
+// dependencies are handled by DeployStack itself.
+func (sm *StackServiceImpl) deployDependencies(stackName string) error {
+	for _, dependency := range sm.GetStackConfig(stackName).DependsOn {
+		stackDetails, ok := sm.GetStackStateInfo()[dependency]
+		if !ok {
+			return newStackError(ErrNotFound, dependency, "stack '%s' depends on stack '%s', which does not exist", stackName, dependency)
+		} else if stackDetails.State == Available {
+			continue
+		} else if stackDetails.State == Uninitialized {
+			Logger.Info("Deploying stack '%s' first, since stack '%s' depends on it", dependency, stackName)
+			if err := sm.DeployStack(dependency); err != nil {
+				return fmt.Errorf("deploying stack '%s', which stack '%s' depends on, failed: %w", dependency, stackName, err)
+			}
+		}
+		if !sm.waitUntilStackIsAvailable(dependency) {
+			return newStackError(ErrTimeout, dependency, "stack '%s', which stack '%s' depends on, did not become available", dependency, stackName)
+		}
+	}
+	return nil
+}
+
+// getRunningDependents returns the running stacks which depend on the given stack, sorted by name.
+func (sm *StackServiceImpl) getRunningDependents(stackName string) []string {
+	var dependents []string
+	for otherStackName, stackDetails := range sm.GetStackStateInfo() {
+		if stackDetails.State == Uninitialized || stackDetails.State == Stopping {
+			continue
+		}
+		if contains(sm.GetStackConfig(otherStackName).DependsOn, stackName) {
+			dependents = append(dependents, otherStackName)
+		}
+	}
+	sort.Strings(dependents)
+	return dependents
+}
+
+// StopStackWithDependents stops the running stacks depending on a stack before the stack itself.
+func (sm *StackServiceImpl) StopStackWithDependents(stackName string) error {
+	for _, dependent := range sm.getRunningDependents(stackName) {
+		Logger.Info("Stopping stack '%s', since it depends on stack '%s'", dependent, stackName)
+		if err := sm.StopStackWithDependents(dependent); err != nil {
+			return err
+		}
+	}
+	return sm.StopStack(stackName)
+}
@@ -0,0 +1,77 @@
+package internal
+
+import (
+	"github.com/ocelot-cloud/shared/assert"
+	"ocelot/backend/config"
+	"testing"
+	"time"
+)
+
+func TestDependencyValidation(t *testing.T) {
+	assert.Nil(t, validateStackDependencies(map[string]StackConfig{
+		"app":      {DependsOn: []string{"database", "mail"}},
+		"database": {},
+		"mail":     {DependsOn: []string{"database"}},
+	}))
+
+	err := validateStackDependencies(map[string]StackConfig{"app": {DependsOn: []string{"database"}}})
+	assert.NotNil(t, err)
+	assert.Equal(t, "stack 'app' depends on stack 'database', which does not exist", err.Error())
+
+	err = validateStackDependencies(map[string]StackConfig{
+		"app":      {DependsOn: []string{"database"}},
+		"database": {DependsOn: []string{"mail"}},
+		"mail":     {DependsOn: []string{"database"}},
+	})
+	assert.NotNil(t, err)
+	assert.Equal(t, "stacks depend on each other in a cycle: database -> mail -> database", err.Error())
+
+	err = validateStackDependencies(map[string]StackConfig{"app": {DependsOn: []string{"app"}}})
+	assert.NotNil(t, err)
+	assert.Equal(t, "stacks depend on each other in a cycle: app -> app", err.Error())
+}
+
+func createStackServiceWithDependencies() *StackServiceImpl {
+	stackService := createStackService()
+	stackService.pollInterval = time.Millisecond
+	stackService.upgradeTimeout = 50 * time.Millisecond
+	stackService.StackConfigService = &StackConfigServiceImpl{map[string]StackConfig{
+		tools.NginxDefault:   {UrlPath: "/", Port: "80", DependsOn: []string{tools.NginxSlowStart}},
+		tools.NginxDefault2:  {UrlPath: "/", Port: "80", DependsOn: []string{tools.NginxDefault}},
+		tools.NginxSlowStart: {UrlPath: "/", Port: "80"},
+	}}
+	return stackService
+}
+
+func TestDependenciesAreDeployedFirst(t *testing.T) {
+	stackService := createStackServiceWithDependencies()
+
+	assert.Nil(t, stackService.DeployStack(tools.NginxDefault2))
+	stackStateInfo := stackService.GetStackStateInfo()
+	assertState(t, stackStateInfo, tools.NginxSlowStart, Available)
+	assertState(t, stackStateInfo, tools.NginxDefault, Available)
+	assertState(t, stackStateInfo, tools.NginxDefault2, Available)
+}
+
+func TestDeploymentFailsIfDependencyDoesNotBecomeAvailable(t *testing.T) {
+	stackService := createStackServiceWithDependencies()
+	stackService.DockerService.(*DockerServiceMock).neverAvailableStacks[tools.NginxDefault] = true
+
+	assert.NotNil(t, stackService.DeployStack(tools.NginxDefault2))
+	assertState(t, stackService.GetStackStateInfo(), tools.NginxDefault2, Uninitialized)
+}
+
+func TestStackWithRunningDependentsIsOnlyStoppedWithCascade(t *testing.T) {
+	stackService := createStackServiceWithDependencies()
+	assert.Nil(t, stackService.DeployStack(tools.NginxDefault2))
+	assert.Equal(t, []string{tools.NginxDefault}, stackService.getRunningDependents(tools.NginxSlowStart))
+
+	assert.NotNil(t, stackService.StopStack(tools.NginxSlowStart))
+	assertState(t, stackService.GetStackStateInfo(), tools.NginxSlowStart, Available)
+
+	assert.Nil(t, stackService.StopStackWithDependents(tools.NginxSlowStart))
+	stackStateInfo := stackService.GetStackStateInfo()
+	assertState(t, stackStateInfo, tools.NginxSlowStart, Uninitialized)
+	assertState(t, stackStateInfo, tools.NginxDefault, Uninitialized)
+	assertState(t, stackStateInfo, tools.NginxDefault2, Uninitialized)
+}
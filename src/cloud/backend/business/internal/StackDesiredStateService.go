@@ -0,0 +1,54 @@
+package internal
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+type StackDesiredStateServiceImpl struct {
+	db *sql.DB
+}
+
+func ProvideStackDesiredStateService(db *sql.DB) *StackDesiredStateServiceImpl {
+	createTableIfNotExisting(db, "stack_desired_states (stack_name TEXT NOT NULL PRIMARY KEY, is_running INTEGER NOT NULL)")
+	return &StackDesiredStateServiceImpl{db}
+}
+
+func (s *StackDesiredStateServiceImpl) SetDesiredState(stackName string, isRunning bool) error {
+	_, err := s.db.Exec("INSERT INTO stack_desired_states (stack_name, is_running) VALUES (?, ?) ON CONFLICT(stack_name) DO UPDATE SET is_running = excluded.is_running", stackName, isRunning)
+	if err != nil {
+		Logger.Error("failed to store desired state of stack '%s': %v", stackName, err)
+		return fmt.Errorf("failed to store desired state")
+	}
+	return nil
+}
+
+func (s *StackDesiredStateServiceImpl) RemoveDesiredState(stackName string) error {
+	if _, err := s.db.Exec("DELETE FROM stack_desired_states WHERE stack_name = ?", stackName); err != nil {
+		Logger.Error("failed to delete desired state of stack '%s': %v", stackName, err)
+		return fmt.Errorf("failed to delete desired state")
+	}
+	return nil
+}
+
+// GetDesiredStates returns whether the stacks are supposed to run. Stacks which were never deployed or stopped
+// via Ocelot are not contained.
+func (s *StackDesiredStateServiceImpl) GetDesiredStates() (map[string]bool, error) {
+	rows, err := s.db.Query("SELECT stack_name, is_running FROM stack_desired_states")
+	if err != nil {
+		Logger.Error("failed to query desired states: %v", err)
+		return nil, fmt.Errorf("failed to read desired states")
+	}
+	defer rows.Close()
+
+	desiredStates := make(map[string]bool)
+	for rows.Next() {
+		var stackName string
+		var isRunning bool
+		if err := rows.Scan(&stackName, &isRunning); err != nil {
+			return nil, err
+		}
+		desiredStates[stackName] = isRunning
+	}
+	return desiredStates, rows.Err()
+}
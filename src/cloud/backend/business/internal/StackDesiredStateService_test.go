@@ -0,0 +1,26 @@
+package internal
+
+import (
+	"github.com/ocelot-cloud/shared/assert"
+	"ocelot/backend/config"
+	"testing"
+)
+
+func TestStackDesiredStateService(t *testing.T) {
+	database := ProvideDatabase(t.TempDir())
+	defer database.Close()
+	desiredStateService := ProvideStackDesiredStateService(database)
+
+	assert.Nil(t, desiredStateService.SetDesiredState(tools.NginxDefault, true))
+	assert.Nil(t, desiredStateService.SetDesiredState(tools.NginxDefault2, true))
+	assert.Nil(t, desiredStateService.SetDesiredState(tools.NginxDefault2, false))
+
+	desiredStates, err := desiredStateService.GetDesiredStates()
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]bool{tools.NginxDefault: true, tools.NginxDefault2: false}, desiredStates)
+
+	assert.Nil(t, desiredStateService.RemoveDesiredState(tools.NginxDefault))
+	desiredStates, err = desiredStateService.GetDesiredStates()
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]bool{tools.NginxDefault2: false}, desiredStates)
+}
@@ -0,0 +1,63 @@
+package internal
+
+import (
+	"database/sql"
+	"fmt"
+	"regexp"
+)
+
+var domainPattern = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]*[a-z0-9])?(\.[a-z0-9]([a-z0-9-]*[a-z0-9])?)+$`)
+
+type StackDomainServiceImpl struct {
+	db *sql.DB
+}
+
+func ProvideStackDomainService(db *sql.DB) *StackDomainServiceImpl {
+	createTableIfNotExisting(db, "stack_domains (domain TEXT NOT NULL PRIMARY KEY, stack_name TEXT NOT NULL)")
+	return &StackDomainServiceImpl{db}
+}
+
+func (s *StackDomainServiceImpl) AddDomain(domain string, stackName string) error {
+	if stack, ok := s.GetDomains()[domain]; ok {
+		return newStackError(ErrInvalidRequest, stack, "domain '%s' is already assigned to stack '%s'", domain, stack)
+	}
+	_, err := s.db.Exec("INSERT INTO stack_domains (domain, stack_name) VALUES (?, ?)", domain, stackName)
+	if err != nil {
+		Logger.Error("failed to store domain '%s': %v", domain, err)
+		return fmt.Errorf("failed to store domain")
+	}
+	return nil
+}
+
+func (s *StackDomainServiceImpl) RemoveDomain(domain string) error {
+	result, err := s.db.Exec("DELETE FROM stack_domains WHERE domain = ?", domain)
+	if err != nil {
+		Logger.Error("failed to delete domain '%s': %v", domain, err)
+		return fmt.Errorf("failed to delete domain")
+	}
+	if affectedRows, _ := result.RowsAffected(); affectedRows == 0 {
+		return newStackError(ErrNotFound, "", "domain '%s' does not exist", domain)
+	}
+	return nil
+}
+
+// GetDomains returns all custom domains mapped to the names of the stacks they are routed to.
+func (s *StackDomainServiceImpl) GetDomains() map[string]string {
+	domains := make(map[string]string)
+	rows, err := s.db.Query("SELECT domain, stack_name FROM stack_domains")
+	if err != nil {
+		Logger.Error("failed to query domains: %v", err)
+		return domains
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var domain, stackName string
+		if err := rows.Scan(&domain, &stackName); err != nil {
+			Logger.Error("failed to read domain: %v", err)
+			continue
+		}
+		domains[domain] = stackName
+	}
+	return domains
+}
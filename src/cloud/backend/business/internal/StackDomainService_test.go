@@ -0,0 +1,33 @@
+package internal
+
+import (
+	"github.com/ocelot-cloud/shared/assert"
+	"ocelot/backend/config"
+	"testing"
+)
+
+func createStackDomainService(t *testing.T) *StackDomainServiceImpl {
+	database := ProvideDatabase(t.TempDir())
+	t.Cleanup(func() { database.Close() })
+	return ProvideStackDomainService(database)
+}
+
+func TestAddAndRemoveDomain(t *testing.T) {
+	domainService := createStackDomainService(t)
+	assert.Nil(t, domainService.AddDomain("git.example.org", tools.NginxDefault))
+	assert.Equal(t, map[string]string{"git.example.org": tools.NginxDefault}, domainService.GetDomains())
+
+	assert.Nil(t, domainService.RemoveDomain("git.example.org"))
+	assert.Equal(t, 0, len(domainService.GetDomains()))
+}
+
+func TestDomainCanOnlyBeAssignedToOneStack(t *testing.T) {
+	domainService := createStackDomainService(t)
+	assert.Nil(t, domainService.AddDomain("git.example.org", tools.NginxDefault))
+	assert.NotNil(t, domainService.AddDomain("git.example.org", tools.NginxDefault2))
+}
+
+func TestRemovingNotExistingDomainFails(t *testing.T) {
+	domainService := createStackDomainService(t)
+	assert.NotNil(t, domainService.RemoveDomain("git.example.org"))
+}
@@ -1,16 +1,22 @@
 package internal
 
-import "ocelot/backend/config"
+import (
+	"ocelot/backend/config"
+	"sync"
+)
 
 type StackDownloadManagerMock struct {
+	mu             sync.Mutex
 	downloadStates map[string]DownloadState
 }
 
 func ProvideDownloadManagerMock() *StackDownloadManagerMock {
-	return &StackDownloadManagerMock{make(map[string]DownloadState)}
+	return &StackDownloadManagerMock{downloadStates: make(map[string]DownloadState)}
 }
 
 func (s *StackDownloadManagerMock) GetStackDownloadStates() map[string]DownloadState {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 	downloadStatesClone := make(map[string]DownloadState)
 	for key, value := range s.downloadStates {
 		downloadStatesClone[key] = value
@@ -28,6 +34,8 @@ func (s *StackDownloadManagerMock) updateStates() {
 }
 
 func (s *StackDownloadManagerMock) DownloadStack(stackName string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 	if stackName == tools.NginxDownloading {
 		s.downloadStates[stackName] = Ongoing
 	} else {
@@ -1,6 +1,7 @@
 package internal
 
 import (
+	"github.com/prometheus/client_golang/prometheus"
 	"os/exec"
 	"sync"
 )
@@ -75,11 +76,14 @@ type DownloadProcessProviderReal struct{}
 
 func (d *DownloadProcessProviderReal) StartDownloadProcessAndSetStateWhenFinished(stackDownloadState *StackDownloadState) {
 	go func() {
+		defer prometheus.NewTimer(stackOperationDuration.WithLabelValues("download")).ObserveDuration()
 		stackDockerComposePath := StackFileDir + "/" + stackDownloadState.stackName + "/docker-compose.yml"
 		pullCmd := exec.Command("docker", "compose", "-f", stackDockerComposePath, "pull")
 		err := pullCmd.Run()
 		if err != nil {
 			Logger.Error("Error executing command '%s': %v\n", pullCmd.String(), err)
+			dockerCommandErrors.WithLabelValues("compose pull").Inc()
+			stackOperationFailures.WithLabelValues("download").Inc()
 			stackDownloadState.State = Error
 			return
 		}
@@ -91,6 +95,8 @@ func (d *DownloadProcessProviderReal) StartDownloadProcessAndSetStateWhenFinishe
 			stackDownloadState.State = Finished
 		} else {
 			Logger.Error("Error executing command '%s': %v\n", buildCmd.String(), err)
+			dockerCommandErrors.WithLabelValues("compose build").Inc()
+			stackOperationFailures.WithLabelValues("download").Inc()
 			stackDownloadState.State = Error
 		}
 	}()
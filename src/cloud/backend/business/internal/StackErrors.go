@@ -0,0 +1,80 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// The kinds of errors of the stack service. They are wrapped into a StackError carrying the details, so they
+// have to be checked with errors.Is.
+var (
+	ErrInvalidRequest    = errors.New("invalid request")
+	ErrNotFound          = errors.New("not found")
+	ErrInvalidState      = errors.New("invalid stack state")
+	ErrDockerUnavailable = errors.New("docker is unavailable")
+	ErrComposeFailed     = errors.New("docker compose failed")
+	ErrDownloadFailed    = errors.New("download failed")
+	ErrTimeout           = errors.New("timeout")
+)
+
+// StackError describes why an operation on a stack failed. Output is the captured output of docker compose, if
+// the error was caused by a docker command. It is not part of the message, since it may be long and is only
+// meant for admins.
+type StackError struct {
+	Kind    error
+	Stack   string
+	Message string
+	Output  string
+}
+
+func (e *StackError) Error() string {
+	return e.Message
+}
+
+func (e *StackError) Unwrap() error {
+	return e.Kind
+}
+
+func newStackError(kind error, stackName string, format string, args ...any) *StackError {
+	return &StackError{Kind: kind, Stack: stackName, Message: fmt.Sprintf(format, args...)}
+}
+
+func logAndCreateStackNotFoundError(stackName string) error {
+	errorMessage := "Could not find stack: " + stackName
+	Logger.Error(errorMessage)
+	return newStackError(ErrNotFound, stackName, "%s", errorMessage)
+}
+
+// newDockerCommandError classifies a failed docker command. Docker is considered unavailable when the CLI is not
+// installed or the daemon can not be reached, any other failure is attributed to the command itself. The stack
+// name is empty for commands which are not specific to a stack.
+func newDockerCommandError(stackName string, command string, err error, output []byte) *StackError {
+	kind := ErrComposeFailed
+	message := fmt.Sprintf("docker %s failed for stack '%s'", command, stackName)
+	if stackName == "" {
+		message = fmt.Sprintf("docker %s failed", command)
+	}
+	if errors.Is(err, exec.ErrNotFound) || isDaemonUnreachable(string(output)) {
+		kind = ErrDockerUnavailable
+		message = fmt.Sprintf("docker is unavailable, %s of stack '%s' was not possible", command, stackName)
+		if stackName == "" {
+			message = fmt.Sprintf("docker is unavailable, %s was not possible", command)
+		}
+	}
+	return &StackError{Kind: kind, Stack: stackName, Message: message, Output: strings.TrimSpace(string(output))}
+}
+
+func isDaemonUnreachable(output string) bool {
+	return strings.Contains(output, "Cannot connect to the Docker daemon") || strings.Contains(output, "Is the docker daemon running?")
+}
+
+// getStackErrorOutput returns the captured docker output of an error, if there is any.
+func getStackErrorOutput(err error) string {
+	var stackError *StackError
+	if errors.As(err, &stackError) {
+		return stackError.Output
+	}
+	return ""
+}
@@ -0,0 +1,91 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"github.com/gorilla/mux"
+	"github.com/ocelot-cloud/shared/assert"
+	"net/http"
+	"net/http/httptest"
+	"ocelot/backend/config"
+	"ocelot/backend/security"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+func TestDockerCommandErrorsAreClassified(t *testing.T) {
+	err := newDockerCommandError(tools.NginxDefault, "compose up", errors.New("exit status 1"), []byte("Cannot connect to the Docker daemon at unix:///var/run/docker.sock. Is the docker daemon running?\n"))
+	assert.True(t, errors.Is(err, ErrDockerUnavailable))
+
+	err = newDockerCommandError(tools.NginxDefault, "compose up", exec.ErrNotFound, nil)
+	assert.True(t, errors.Is(err, ErrDockerUnavailable))
+
+	err = newDockerCommandError(tools.NginxDefault, "compose up", errors.New("exit status 1"), []byte("port is already allocated\n"))
+	assert.True(t, errors.Is(err, ErrComposeFailed))
+	assert.Equal(t, "docker compose up failed for stack 'nginx-default'", err.Error())
+	assert.Equal(t, "port is already allocated", getStackErrorOutput(fmt.Errorf("deployment failed: %w", err)))
+
+	err = newDockerCommandError("", "compose ls", errors.New("exit status 1"), []byte("Cannot connect to the Docker daemon at unix:///var/run/docker.sock. Is the docker daemon running?\n"))
+	assert.True(t, errors.Is(err, ErrDockerUnavailable))
+	assert.Equal(t, "docker is unavailable, compose ls was not possible", err.Error())
+}
+
+func TestErrorKindsAreMappedToStatus(t *testing.T) {
+	cases := []struct {
+		err    error
+		status int
+		code   string
+	}{
+		{newStackError(ErrInvalidRequest, "", "invalid"), http.StatusBadRequest, errorCodeInvalidRequest},
+		{logAndCreateStackNotFoundError("not-existing-stack"), http.StatusNotFound, errorCodeNotFound},
+		{newStackError(ErrInvalidState, "", "invalid state"), http.StatusConflict, errorCodeInvalidState},
+		{newStackError(ErrDockerUnavailable, "", "unavailable"), http.StatusServiceUnavailable, errorCodeDockerUnavailable},
+		{newStackError(ErrDownloadFailed, "", "download failed"), http.StatusServiceUnavailable, errorCodeDownloadFailed},
+		{fmt.Errorf("wrapped: %w", newStackError(ErrTimeout, "", "timeout")), http.StatusGatewayTimeout, errorCodeTimeout},
+		{newStackError(ErrComposeFailed, "", "compose failed"), http.StatusInternalServerError, errorCodeComposeFailed},
+		{errors.New("failed to read secrets"), http.StatusInternalServerError, errorCodeOperationFailed},
+	}
+	for _, c := range cases {
+		status, code := getErrorStatus(c.err)
+		assert.Equal(t, c.status, status)
+		assert.Equal(t, c.code, code)
+	}
+}
+
+func TestErrorResponseContainsComposeOutputForAdmins(t *testing.T) {
+	err := newDockerCommandError(tools.NginxDefault, "compose up", errors.New("exit status 1"), []byte("pull access denied"))
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeStackError(w, r, err)
+	})
+
+	recorder := httptest.NewRecorder()
+	security.ProvideSecurityModule(mux.NewRouter(), &tools.GlobalConfig{}).ApplyAuthMiddlewares(handler).ServeHTTP(recorder, httptest.NewRequest("POST", "/api/v1/stacks/nginx-default/deploy", nil))
+	assert.Equal(t, http.StatusInternalServerError, recorder.Code)
+	expected := tools.ErrorDto{Code: errorCodeComposeFailed, Message: "docker compose up failed for stack 'nginx-default'", Output: "pull access denied"}
+	assert.Equal(t, expected, decodeApiResponse[tools.ErrorDto](t, recorder))
+
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest("POST", "/api/v1/stacks/nginx-default/deploy", nil))
+	expected.Output = ""
+	assert.Equal(t, expected, decodeApiResponse[tools.ErrorDto](t, recorder))
+}
+
+func TestStackServiceErrorsHaveKinds(t *testing.T) {
+	stackService := createStackService()
+
+	assert.True(t, errors.Is(stackService.DeployStack("not-existing-stack"), ErrNotFound))
+	assert.True(t, errors.Is(stackService.RestartStack(tools.NginxDefault), ErrInvalidState))
+	assert.True(t, errors.Is(stackService.CreateStackInstance("Invalid Name", tools.NginxDefault), ErrInvalidRequest))
+	_, err := stackService.RunBulkOperation("unknown", nil)
+	assert.True(t, errors.Is(err, ErrInvalidRequest))
+}
+
+func TestDeprecatedHandlerAnswersWithMatchingStatus(t *testing.T) {
+	handler := createStopHandler(createStackService())
+
+	recorder := httptest.NewRecorder()
+	handler(recorder, httptest.NewRequest("POST", "/api/stacks/stop", strings.NewReader(`{"name": "not-existing-stack"}`)))
+	assert.Equal(t, http.StatusNotFound, recorder.Code)
+	assert.Equal(t, errorCodeNotFound, decodeApiResponse[tools.ErrorDto](t, recorder).Code)
+}
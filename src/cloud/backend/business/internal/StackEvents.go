@@ -0,0 +1,175 @@
+package internal
+
+import (
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	EventStackStateChanged = "stack.state_changed"
+	// EventStackDown is published when a stack fails or stops, although it is supposed to run.
+	EventStackDown           = "stack.down"
+	EventDeployFailed        = "stack.deploy_failed"
+	EventContainerUnhealthy  = "container.unhealthy"
+	EventBackupSucceeded     = "backup.succeeded"
+	EventBackupFailed        = "backup.failed"
+	EventLoginFailed         = "security.login_failed"
+	EventDiskNearlyFull      = "system.disk_nearly_full"
+	EventCertificateExpiring = "system.certificate_expiring"
+)
+
+var StackEventTypes = []string{EventStackStateChanged, EventStackDown, EventDeployFailed, EventContainerUnhealthy, EventBackupSucceeded, EventBackupFailed, EventLoginFailed, EventDiskNearlyFull, EventCertificateExpiring}
+
+// StackEvent is something that happened to a stack or the server, which may be of interest outside of Ocelot. Stack
+// is empty for events which don't concern a stack, like failed logins.
+type StackEvent struct {
+	Type      string
+	Stack     string
+	Message   string
+	Details   map[string]string
+	Timestamp time.Time
+}
+
+// StackEventBus passes events to the subscribed listeners, e.g. the webhook dispatcher. Listeners are called
+// synchronously by the publisher, so they must not block and have to hand over long-running work to goroutines.
+type StackEventBus struct {
+	mu        sync.Mutex
+	listeners []func(event StackEvent)
+}
+
+func ProvideStackEventBus() *StackEventBus {
+	return &StackEventBus{}
+}
+
+func (b *StackEventBus) Subscribe(listener func(event StackEvent)) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.listeners = append(b.listeners, listener)
+}
+
+func (b *StackEventBus) Publish(event StackEvent) {
+	if event.Timestamp.IsZero() {
+		event.Timestamp = time.Now()
+	}
+	b.mu.Lock()
+	listeners := append([]func(event StackEvent){}, b.listeners...)
+	b.mu.Unlock()
+
+	Logger.Debug("Publishing event '%s': %s", event.Type, event.Message)
+	for _, listener := range listeners {
+		listener(event)
+	}
+}
+
+func (sm *StackServiceImpl) PublishEvent(event StackEvent) {
+	sm.Events.Publish(event)
+}
+
+// LoginFailurePublisher publishes failed logins as events. During a brute force attack only a limited number of
+// events is published, the failures suppressed in between are counted in the next published event.
+type LoginFailurePublisher struct {
+	stackService       StackService
+	mu                 sync.Mutex
+	bucket             *tokenBucket
+	suppressedFailures int
+	now                func() time.Time
+}
+
+func ProvideLoginFailurePublisher(stackService StackService) *LoginFailurePublisher {
+	return &LoginFailurePublisher{stackService: stackService, bucket: newTokenBucket(LoginFailureEventsPerMinute/60, LoginFailureEventBurst, time.Now()), now: time.Now}
+}
+
+func (p *LoginFailurePublisher) Publish(username string, remoteAddress string) {
+	p.mu.Lock()
+	if !p.bucket.allow(p.now()) {
+		p.suppressedFailures++
+		p.mu.Unlock()
+		Logger.Debug("event about failed login of user '%s' was suppressed", username)
+		return
+	}
+	suppressedFailures := p.suppressedFailures
+	p.suppressedFailures = 0
+	p.mu.Unlock()
+
+	details := map[string]string{"username": username, "remoteAddress": remoteAddress}
+	if suppressedFailures > 0 {
+		details["suppressedFailures"] = strconv.Itoa(suppressedFailures)
+	}
+	p.stackService.PublishEvent(StackEvent{
+		Type:    EventLoginFailed,
+		Message: fmt.Sprintf("failed login of user '%s'", username),
+		Details: details,
+	})
+}
+
+// StartStateObservation periodically compares the states of the stacks with the ones of the last check and
+// publishes an event for each change. Changes in between two checks, like a quick restart, are not noticed.
+func (sm *StackServiceImpl) StartStateObservation(interval time.Duration) {
+	go func() {
+		previousStates, _ := sm.getStackStates()
+		for {
+			time.Sleep(interval)
+			previousStates = sm.publishStateChanges(previousStates)
+		}
+	}()
+}
+
+// publishStateChanges publishes the changes since the previous states and returns the current ones. Stacks which
+// were created or deleted in the meantime are ignored. If the states can't be read, the previous ones are kept.
+func (sm *StackServiceImpl) publishStateChanges(previousStates map[string]StackState) map[string]StackState {
+	currentStates, err := sm.getStackStates()
+	if err != nil {
+		Logger.Warn("state changes are not published, since the states of the stacks could not be read: %s", err.Error())
+		return previousStates
+	}
+	desiredStates, err := sm.DesiredStateService.GetDesiredStates()
+	if err != nil {
+		Logger.Warn("desired states of the stacks could not be read, stopped stacks are not reported")
+	}
+	for stackName, state := range currentStates {
+		previousState, ok := previousStates[stackName]
+		if !ok || previousState == state {
+			continue
+		}
+		details := map[string]string{"previousState": previousState.String(), "state": state.String()}
+		sm.Events.Publish(StackEvent{
+			Type:    EventStackStateChanged,
+			Stack:   stackName,
+			Message: fmt.Sprintf("state of stack '%s' changed from %s to %s", stackName, previousState.String(), state.String()),
+			Details: details,
+		})
+		if isStackDown(previousState, state, desiredStates[stackName]) {
+			sm.Events.Publish(StackEvent{
+				Type:    EventStackDown,
+				Stack:   stackName,
+				Message: fmt.Sprintf("stack '%s' is down, its state changed from %s to %s", stackName, previousState.String(), state.String()),
+				Details: details,
+			})
+		}
+	}
+	return currentStates
+}
+
+// isStackDown is true for stacks whose containers stay unhealthy despite restarts and for stacks which stopped
+// running without being stopped by Ocelot, e.g. since they crashed. Such stacks are reported as Starting, if
+// their last action was a deployment.
+func isStackDown(previousState StackState, state StackState, isSupposedToRun bool) bool {
+	if state == Erroneous {
+		return true
+	}
+	return (state == Uninitialized || state == Starting) && isRunningState(previousState) && isSupposedToRun
+}
+
+func (sm *StackServiceImpl) getStackStates() (map[string]StackState, error) {
+	stackStateInfo, err := sm.readStackStateInfo()
+	if err != nil {
+		return nil, err
+	}
+	states := make(map[string]StackState)
+	for stackName, stackDetails := range stackStateInfo {
+		states[stackName] = stackDetails.State
+	}
+	return states, nil
+}
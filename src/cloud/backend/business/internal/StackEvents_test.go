@@ -0,0 +1,129 @@
+package internal
+
+import (
+	"github.com/ocelot-cloud/shared/assert"
+	"ocelot/backend/config"
+	"sync"
+	"testing"
+	"time"
+)
+
+// collectEvents returns a function, which returns the events published by the stack service so far.
+func collectEvents(stackService *StackServiceImpl) func() []StackEvent {
+	var mu sync.Mutex
+	var events []StackEvent
+	stackService.Events.Subscribe(func(event StackEvent) {
+		mu.Lock()
+		defer mu.Unlock()
+		events = append(events, event)
+	})
+	return func() []StackEvent {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]StackEvent{}, events...)
+	}
+}
+
+func TestStateChangesArePublished(t *testing.T) {
+	stackService := createStackService()
+	getEvents := collectEvents(stackService)
+	previousStates, err := stackService.getStackStates()
+	assert.Nil(t, err)
+
+	previousStates = stackService.publishStateChanges(previousStates)
+	assert.Equal(t, 0, len(getEvents()))
+
+	assert.Nil(t, stackService.DeployStack(tools.NginxDefault))
+	stackService.publishStateChanges(previousStates)
+	events := getEvents()
+	assert.Equal(t, 1, len(events))
+	assert.Equal(t, EventStackStateChanged, events[0].Type)
+	assert.Equal(t, tools.NginxDefault, events[0].Stack)
+	assert.Equal(t, "Uninitialized", events[0].Details["previousState"])
+	assert.Equal(t, "Available", events[0].Details["state"])
+	assert.False(t, events[0].Timestamp.IsZero())
+}
+
+func TestStateChangesAreNotPublishedWhileDockerIsUnavailable(t *testing.T) {
+	stackService := createStackService()
+	dockerService := stackService.DockerService.(*DockerServiceMock)
+	getEvents := collectEvents(stackService)
+	assert.Nil(t, stackService.DeployStack(tools.NginxDefault))
+	previousStates, err := stackService.getStackStates()
+	assert.Nil(t, err)
+
+	dockerService.isUnavailable = true
+	previousStates = stackService.publishStateChanges(previousStates)
+	assert.Equal(t, Available, previousStates[tools.NginxDefault])
+	dockerService.isUnavailable = false
+	stackService.publishStateChanges(previousStates)
+	assert.Equal(t, 0, len(getEvents()))
+}
+
+func TestBackupResultIsPublished(t *testing.T) {
+	stackService := createStackServiceForBackups(t)
+	getEvents := collectEvents(stackService)
+
+	backup, err := stackService.BackupStack(tools.NginxDefault)
+	assert.Nil(t, err)
+	events := getEvents()
+	assert.Equal(t, 1, len(events))
+	assert.Equal(t, EventBackupSucceeded, events[0].Type)
+	assert.Equal(t, backup.Id, events[0].Details["backup"])
+}
+
+func TestUnhealthyContainerIsPublished(t *testing.T) {
+	stackService, _, clock := createStackServiceWithUnhealthyContainer(t)
+	getEvents := collectEvents(stackService)
+
+	stackService.Watchdog.CheckStacks()
+	clock.advance(UnhealthyThreshold)
+	stackService.Watchdog.CheckStacks()
+	events := getEvents()
+	assert.Equal(t, 1, len(events))
+	assert.Equal(t, EventContainerUnhealthy, events[0].Type)
+	assert.Equal(t, IncidentUnhealthy, events[0].Details["incident"])
+}
+
+func TestStackDownIsOnlyPublishedIfStackIsSupposedToRun(t *testing.T) {
+	stackService := createStackService()
+	assert.Nil(t, stackService.DeployStack(tools.NginxDefault))
+	assert.Nil(t, stackService.DeployStack(tools.NginxDefault2))
+	getEvents := collectEvents(stackService)
+	previousStates, err := stackService.getStackStates()
+	assert.Nil(t, err)
+
+	assert.Nil(t, stackService.StopStack(tools.NginxDefault))
+	assert.Nil(t, stackService.DockerService.StopStack(tools.NginxDefault2))
+	stackService.publishStateChanges(previousStates)
+
+	var stacksDown []string
+	for _, event := range getEvents() {
+		if event.Type == EventStackDown {
+			stacksDown = append(stacksDown, event.Stack)
+		}
+	}
+	assert.Equal(t, []string{tools.NginxDefault2}, stacksDown)
+}
+
+func TestEventsAboutFailedLoginsAreLimited(t *testing.T) {
+	stackService := createStackService()
+	getEvents := collectEvents(stackService)
+	publisher := ProvideLoginFailurePublisher(stackService)
+	now := time.Now()
+	publisher.now = func() time.Time { return now }
+
+	for i := 0; i < LoginFailureEventBurst+3; i++ {
+		publisher.Publish("admin", "203.0.113.7")
+	}
+	assert.Equal(t, LoginFailureEventBurst, len(getEvents()))
+
+	now = now.Add(time.Minute)
+	publisher.Publish("admin", "203.0.113.7")
+	events := getEvents()
+	assert.Equal(t, LoginFailureEventBurst+1, len(events))
+	lastEvent := events[len(events)-1]
+	assert.Equal(t, EventLoginFailed, lastEvent.Type)
+	assert.Equal(t, "203.0.113.7", lastEvent.Details["remoteAddress"])
+	assert.Equal(t, "3", lastEvent.Details["suppressedFailures"])
+}
@@ -0,0 +1,65 @@
+package internal
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+const (
+	IncidentUnhealthy     = "unhealthy"
+	IncidentRestarted     = "restarted"
+	IncidentRestartFailed = "restart-failed"
+	IncidentGaveUp        = "gave-up"
+	IncidentRecovered     = "recovered"
+)
+
+// StackIncident records an action of the watchdog concerning an unhealthy container of a stack.
+type StackIncident struct {
+	Id        int64
+	Container string
+	Kind      string
+	Message   string
+	Timestamp time.Time
+}
+
+type StackIncidentHistoryImpl struct {
+	db *sql.DB
+}
+
+func ProvideStackIncidentHistory(db *sql.DB) *StackIncidentHistoryImpl {
+	createTableIfNotExisting(db, "stack_incidents (id INTEGER NOT NULL PRIMARY KEY AUTOINCREMENT, stack_name TEXT NOT NULL, container TEXT NOT NULL, kind TEXT NOT NULL, message TEXT NOT NULL, timestamp INTEGER NOT NULL)")
+	return &StackIncidentHistoryImpl{db}
+}
+
+func (s *StackIncidentHistoryImpl) AddIncident(stackName string, incident StackIncident) error {
+	_, err := s.db.Exec("INSERT INTO stack_incidents (stack_name, container, kind, message, timestamp) VALUES (?, ?, ?, ?, ?)",
+		stackName, incident.Container, incident.Kind, incident.Message, incident.Timestamp.Unix())
+	if err != nil {
+		Logger.Error("failed to store incident of stack '%s': %v", stackName, err)
+		return fmt.Errorf("failed to store incident")
+	}
+	return nil
+}
+
+// GetIncidents returns the incidents of a stack, the oldest one first.
+func (s *StackIncidentHistoryImpl) GetIncidents(stackName string) ([]StackIncident, error) {
+	rows, err := s.db.Query("SELECT id, container, kind, message, timestamp FROM stack_incidents WHERE stack_name = ? ORDER BY id", stackName)
+	if err != nil {
+		Logger.Error("failed to query incidents of stack '%s': %v", stackName, err)
+		return nil, fmt.Errorf("failed to read incidents")
+	}
+	defer rows.Close()
+
+	var incidents []StackIncident
+	for rows.Next() {
+		var incident StackIncident
+		var timestamp int64
+		if err := rows.Scan(&incident.Id, &incident.Container, &incident.Kind, &incident.Message, &timestamp); err != nil {
+			return nil, err
+		}
+		incident.Timestamp = time.Unix(timestamp, 0)
+		incidents = append(incidents, incident)
+	}
+	return incidents, rows.Err()
+}
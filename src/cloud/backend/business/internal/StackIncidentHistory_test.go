@@ -0,0 +1,28 @@
+package internal
+
+import (
+	"github.com/ocelot-cloud/shared/assert"
+	"ocelot/backend/config"
+	"testing"
+	"time"
+)
+
+func TestStackIncidentHistory(t *testing.T) {
+	database := ProvideDatabase(t.TempDir())
+	defer database.Close()
+	incidentHistory := ProvideStackIncidentHistory(database)
+
+	assert.Nil(t, incidentHistory.AddIncident(tools.NginxDefault, StackIncident{Container: "nginx-default", Kind: IncidentUnhealthy, Message: "a", Timestamp: time.Now()}))
+	assert.Nil(t, incidentHistory.AddIncident(tools.NginxDefault, StackIncident{Container: "nginx-default", Kind: IncidentRestarted, Message: "b", Timestamp: time.Now()}))
+
+	incidents, err := incidentHistory.GetIncidents(tools.NginxDefault)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(incidents))
+	assert.Equal(t, IncidentUnhealthy, incidents[0].Kind)
+	assert.Equal(t, IncidentRestarted, incidents[1].Kind)
+	assert.Equal(t, "nginx-default", incidents[1].Container)
+
+	otherIncidents, err := incidentHistory.GetIncidents(tools.NginxDefault2)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(otherIncidents))
+}
@@ -0,0 +1,74 @@
+package internal
+
+import (
+	"database/sql"
+	"fmt"
+	"regexp"
+)
+
+var instanceNamePattern = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]*[a-z0-9])?$`)
+
+type StackInstanceServiceImpl struct {
+	db *sql.DB
+}
+
+func ProvideStackInstanceService(db *sql.DB) *StackInstanceServiceImpl {
+	createTableIfNotExisting(db, "stack_instances (instance_name TEXT NOT NULL PRIMARY KEY, template_name TEXT NOT NULL)")
+	return &StackInstanceServiceImpl{db}
+}
+
+func (s *StackInstanceServiceImpl) CreateInstance(instanceName string, templateName string) error {
+	if _, ok := s.GetInstances()[instanceName]; ok {
+		return newStackError(ErrInvalidRequest, instanceName, "instance '%s' does already exist", instanceName)
+	}
+	_, err := s.db.Exec("INSERT INTO stack_instances (instance_name, template_name) VALUES (?, ?)", instanceName, templateName)
+	if err != nil {
+		Logger.Error("failed to store instance '%s': %v", instanceName, err)
+		return fmt.Errorf("failed to store instance")
+	}
+	return nil
+}
+
+func (s *StackInstanceServiceImpl) DeleteInstance(instanceName string) error {
+	result, err := s.db.Exec("DELETE FROM stack_instances WHERE instance_name = ?", instanceName)
+	if err != nil {
+		Logger.Error("failed to delete instance '%s': %v", instanceName, err)
+		return fmt.Errorf("failed to delete instance")
+	}
+	if affectedRows, _ := result.RowsAffected(); affectedRows == 0 {
+		return newStackError(ErrNotFound, instanceName, "instance '%s' does not exist", instanceName)
+	}
+	return nil
+}
+
+// GetInstances returns the names of all instances mapped to the names of their templates.
+func (s *StackInstanceServiceImpl) GetInstances() map[string]string {
+	instances := make(map[string]string)
+	rows, err := s.db.Query("SELECT instance_name, template_name FROM stack_instances")
+	if err != nil {
+		Logger.Error("failed to query instances: %v", err)
+		return instances
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var instanceName, templateName string
+		if err := rows.Scan(&instanceName, &templateName); err != nil {
+			Logger.Error("failed to read instance: %v", err)
+			continue
+		}
+		instances[instanceName] = templateName
+	}
+	return instances
+}
+
+func (s *StackInstanceServiceImpl) GetTemplateName(stackName string) string {
+	return getTemplateNameFrom(s.GetInstances(), stackName)
+}
+
+func getTemplateNameFrom(instances map[string]string, stackName string) string {
+	if templateName, ok := instances[stackName]; ok {
+		return templateName
+	}
+	return stackName
+}
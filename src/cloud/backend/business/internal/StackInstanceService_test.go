@@ -0,0 +1,36 @@
+package internal
+
+import (
+	"github.com/ocelot-cloud/shared/assert"
+	"ocelot/backend/config"
+	"testing"
+)
+
+func createStackInstanceService(t *testing.T) *StackInstanceServiceImpl {
+	database := ProvideDatabase(t.TempDir())
+	t.Cleanup(func() { database.Close() })
+	return ProvideStackInstanceService(database)
+}
+
+func TestCreateAndDeleteInstance(t *testing.T) {
+	instanceService := createStackInstanceService(t)
+	assert.Nil(t, instanceService.CreateInstance("nginx-team-a", tools.NginxDefault))
+
+	assert.Equal(t, map[string]string{"nginx-team-a": tools.NginxDefault}, instanceService.GetInstances())
+	assert.Equal(t, tools.NginxDefault, instanceService.GetTemplateName("nginx-team-a"))
+	assert.Equal(t, tools.NginxDefault2, instanceService.GetTemplateName(tools.NginxDefault2))
+
+	assert.Nil(t, instanceService.DeleteInstance("nginx-team-a"))
+	assert.Equal(t, 0, len(instanceService.GetInstances()))
+}
+
+func TestInstanceNamesAreUnique(t *testing.T) {
+	instanceService := createStackInstanceService(t)
+	assert.Nil(t, instanceService.CreateInstance("nginx-team-a", tools.NginxDefault))
+	assert.NotNil(t, instanceService.CreateInstance("nginx-team-a", tools.NginxDefault2))
+}
+
+func TestDeletingNotExistingInstanceFails(t *testing.T) {
+	instanceService := createStackInstanceService(t)
+	assert.NotNil(t, instanceService.DeleteInstance("not-existing-instance"))
+}
@@ -0,0 +1,28 @@
+package internal
+
+import (
+	"context"
+	"io"
+	"strconv"
+)
+
+// LogOptions select the container logs of a stack. Only the last Tail lines are returned, all lines if it is
+// zero. When following, new lines are written until the context is canceled.
+type LogOptions struct {
+	Follow bool
+	Tail   int
+}
+
+func (o LogOptions) getTailArgument() string {
+	if o.Tail <= 0 {
+		return "all"
+	}
+	return strconv.Itoa(o.Tail)
+}
+
+func (sm *StackServiceImpl) StreamStackLogs(ctx context.Context, stackName string, options LogOptions, output io.Writer) error {
+	if !sm.StackExists(stackName) {
+		return logAndCreateStackNotFoundError(stackName)
+	}
+	return sm.DockerService.StreamStackLogs(ctx, stackName, options, output)
+}
@@ -0,0 +1,57 @@
+package internal
+
+import "time"
+
+// StartReconciliation reconciles the stacks right away, e.g. after a reboot of the host, and then periodically.
+func (sm *StackServiceImpl) StartReconciliation(interval time.Duration) {
+	go func() {
+		for {
+			sm.ReconcileStacks()
+			time.Sleep(interval)
+		}
+	}()
+}
+
+// ReconcileStacks deploys the stacks which are supposed to run but don't and stops the ones which run although
+// they were stopped. Stacks in a transitional state, like Starting or Upgrading, are left alone.
+func (sm *StackServiceImpl) ReconcileStacks() {
+	desiredStates, err := sm.DesiredStateService.GetDesiredStates()
+	if err != nil {
+		Logger.Warn("reconciliation skipped, since the desired states could not be read")
+		return
+	}
+	stackStateInfo, err := sm.readStackStateInfo()
+	if err != nil {
+		Logger.Warn("reconciliation skipped, since the states of the stacks could not be read: %s", err.Error())
+		return
+	}
+
+	for stackName, shouldRun := range desiredStates {
+		stackDetails, ok := stackStateInfo[stackName]
+		if !ok {
+			Logger.Info("Forgetting desired state of stack '%s', since it does not exist anymore", stackName)
+			if err = sm.DesiredStateService.RemoveDesiredState(stackName); err != nil {
+				Logger.Warn("failed to remove desired state of stack '%s'", stackName)
+			}
+			continue
+		}
+
+		if shouldRun && stackDetails.State == Uninitialized {
+			Logger.Warn("Stack '%s' is supposed to run but is stopped, deploying it", stackName)
+			stackReconciliations.WithLabelValues("deploy").Inc()
+			if err = sm.DeployStack(stackName); err != nil {
+				Logger.Error("reconciliation failed to deploy stack '%s': %s", stackName, err.Error())
+			}
+		} else if !shouldRun && isRunningState(stackDetails.State) {
+			Logger.Warn("Stack '%s' is supposed to be stopped but is running, stopping it", stackName)
+			stackReconciliations.WithLabelValues("stop").Inc()
+			if err = sm.StopStack(stackName); err != nil {
+				Logger.Error("reconciliation failed to stop stack '%s': %s", stackName, err.Error())
+			}
+		}
+	}
+}
+
+func isRunningState(state StackState) bool {
+	return state == Available || state == Unhealthy || state == Erroneous
+}
@@ -0,0 +1,73 @@
+package internal
+
+import (
+	"github.com/ocelot-cloud/shared/assert"
+	"testing"
+)
+
+// simulateReboot makes the docker mock forget all running stacks, like the host was restarted without the
+// containers being started again.
+func simulateReboot(stackService *StackServiceImpl) {
+	dockerService := stackService.DockerService.(*DockerServiceMock)
+	dockerService.mu.Lock()
+	defer dockerService.mu.Unlock()
+	for stackName := range dockerService.stackStates {
+		dockerService.stackStates[stackName] = Uninitialized
+	}
+}
+
+func TestStacksWhichShouldRunAreDeployedAgain(t *testing.T) {
+	stackService := createStackService()
+	assert.Nil(t, stackService.DeployStack(stackToDeploy))
+	assert.Nil(t, stackService.DeployStack(stack2ToDeploy))
+	assert.Nil(t, stackService.StopStack(stack2ToDeploy))
+
+	simulateReboot(stackService)
+	stackService.lastActionOnStack = make(map[string]StackAction)
+	assertState(t, stackService.GetStackStateInfo(), stackToDeploy, Uninitialized)
+
+	stackService.ReconcileStacks()
+	stackStateInfo := stackService.GetStackStateInfo()
+	assertState(t, stackStateInfo, stackToDeploy, Available)
+	assertState(t, stackStateInfo, stack2ToDeploy, Uninitialized)
+}
+
+func TestStacksWhichShouldNotRunAreStopped(t *testing.T) {
+	stackService := createStackService()
+	assert.Nil(t, stackService.DeployStack(stackToDeploy))
+	assert.Nil(t, stackService.StopStack(stackToDeploy))
+
+	assert.Nil(t, stackService.DockerService.DeployStack(stackToDeploy, nil))
+	stackService.lastActionOnStack = make(map[string]StackAction)
+	assertState(t, stackService.GetStackStateInfo(), stackToDeploy, Available)
+
+	stackService.ReconcileStacks()
+	assertState(t, stackService.GetStackStateInfo(), stackToDeploy, Uninitialized)
+}
+
+func TestDesiredStateOfRemovedStackIsForgotten(t *testing.T) {
+	stackService := createStackService()
+	assert.Nil(t, stackService.DesiredStateService.SetDesiredState("removed-stack", true))
+
+	stackService.ReconcileStacks()
+	desiredStates, err := stackService.DesiredStateService.GetDesiredStates()
+	assert.Nil(t, err)
+	_, ok := desiredStates["removed-stack"]
+	assert.False(t, ok)
+}
+
+func TestReconciliationIsSkippedWhileDockerIsUnavailable(t *testing.T) {
+	stackService := createStackService()
+	dockerService := stackService.DockerService.(*DockerServiceMock)
+	assert.Nil(t, stackService.DeployStack(stackToDeploy))
+	simulateReboot(stackService)
+	stackService.lastActionOnStack = make(map[string]StackAction)
+
+	dockerService.isUnavailable = true
+	stackService.ReconcileStacks()
+	dockerService.isUnavailable = false
+	assertState(t, stackService.GetStackStateInfo(), stackToDeploy, Uninitialized)
+	desiredStates, err := stackService.DesiredStateService.GetDesiredStates()
+	assert.Nil(t, err)
+	assert.True(t, desiredStates[stackToDeploy])
+}
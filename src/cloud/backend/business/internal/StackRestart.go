@@ -0,0 +1,94 @@
+package internal
+
+import (
+	"fmt"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// RestartStack restarts the containers of a running stack gracefully, the containers themselves are kept.
+func (sm *StackServiceImpl) RestartStack(stackName string) error {
+	secrets, err := sm.prepareRestart(stackName, "restarted")
+	if err != nil {
+		return err
+	}
+	defer sm.markRestartAsFinished(stackName)
+
+	Logger.Info("Restarting stack '%s'", stackName)
+	timer := prometheus.NewTimer(stackOperationDuration.WithLabelValues("restart"))
+	err = sm.DockerService.RestartStack(stackName, secrets)
+	timer.ObserveDuration()
+	if err != nil {
+		stackOperationFailures.WithLabelValues("restart").Inc()
+		return err
+	}
+	sm.Watchdog.Reset(stackName)
+	return nil
+}
+
+// RecreateStack replaces the containers of a running stack by new ones, optionally after pulling the latest
+// images of the tags used in the compose file.
+func (sm *StackServiceImpl) RecreateStack(stackName string, shouldPullImages bool) error {
+	secrets, err := sm.prepareRestart(stackName, "recreated")
+	if err != nil {
+		return err
+	}
+	defer sm.markRestartAsFinished(stackName)
+
+	Logger.Info("Recreating stack '%s'", stackName)
+	defer prometheus.NewTimer(stackOperationDuration.WithLabelValues("recreate")).ObserveDuration()
+	if shouldPullImages {
+		if err = sm.downloadImages(sm.InstanceService.GetTemplateName(stackName)); err != nil {
+			stackOperationFailures.WithLabelValues("recreate").Inc()
+			return err
+		}
+	}
+	if err = sm.DockerService.RecreateStack(stackName, secrets); err != nil {
+		stackOperationFailures.WithLabelValues("recreate").Inc()
+		return err
+	}
+	sm.Watchdog.Reset(stackName)
+	sm.recordDeployedVersion(stackName)
+	return nil
+}
+
+// prepareRestart ensures that the stack is running and not already being restarted, recreated or upgraded.
+func (sm *StackServiceImpl) prepareRestart(stackName string, participle string) (map[string]string, error) {
+	stackDetails, ok := sm.GetStackStateInfo()[stackName]
+	if !ok {
+		return nil, logAndCreateStackNotFoundError(stackName)
+	} else if !(stackDetails.State == Starting || isRunningState(stackDetails.State)) {
+		return nil, newStackError(ErrInvalidState, stackName, "only running stacks can be %s, state of stack '%s' is: %s", participle, stackName, stackDetails.State.String())
+	}
+
+	secrets, err := sm.SecretService.GetSecrets(stackName)
+	if err != nil {
+		Logger.Error("failed to read secrets of stack '%s': %s", stackName, err.Error())
+		return nil, fmt.Errorf("secrets of stack '%s' could not be read: %w", stackName, err)
+	}
+	if !sm.markRestartAsStarted(stackName) {
+		return nil, newStackError(ErrInvalidState, stackName, "stack '%s' is already being restarted", stackName)
+	}
+	return secrets, nil
+}
+
+func (sm *StackServiceImpl) markRestartAsStarted(stackName string) bool {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	if _, ok := sm.backupsInProgress[stackName]; ok || sm.restartsInProgress[stackName] || sm.upgradesInProgress[stackName] {
+		return false
+	}
+	sm.restartsInProgress[stackName] = true
+	return true
+}
+
+func (sm *StackServiceImpl) markRestartAsFinished(stackName string) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	delete(sm.restartsInProgress, stackName)
+}
+
+func (sm *StackServiceImpl) isRestartInProgress(stackName string) bool {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	return sm.restartsInProgress[stackName]
+}
@@ -0,0 +1,56 @@
+package internal
+
+import (
+	"github.com/ocelot-cloud/shared/assert"
+	"ocelot/backend/config"
+	"testing"
+)
+
+func TestOnlyRunningStacksCanBeRestartedOrRecreated(t *testing.T) {
+	stackService := createStackService()
+	assert.NotNil(t, stackService.RestartStack(stackToDeploy))
+	assert.NotNil(t, stackService.RecreateStack(stackToDeploy, false))
+	assert.NotNil(t, stackService.RestartStack("not-existing-stack"))
+
+	assert.Nil(t, stackService.DeployStack(stackToDeploy))
+	assert.Nil(t, stackService.RestartStack(stackToDeploy))
+	assertState(t, stackService.GetStackStateInfo(), stackToDeploy, Available)
+	assert.Nil(t, stackService.RecreateStack(stackToDeploy, false))
+	assertState(t, stackService.GetStackStateInfo(), stackToDeploy, Available)
+}
+
+func TestStackIsReportedAsRestartingWhileItIsRestarted(t *testing.T) {
+	stackService := createStackService()
+	assert.Nil(t, stackService.DeployStack(stackToDeploy))
+
+	assert.True(t, stackService.markRestartAsStarted(stackToDeploy))
+	assertState(t, stackService.GetStackStateInfo(), stackToDeploy, Restarting)
+	assert.NotNil(t, stackService.RestartStack(stackToDeploy))
+
+	stackService.markRestartAsFinished(stackToDeploy)
+	assertState(t, stackService.GetStackStateInfo(), stackToDeploy, Available)
+}
+
+func TestRecreationCanPullImagesFirst(t *testing.T) {
+	stackService := createStackService()
+	assert.Nil(t, stackService.DeployStack(tools.NginxDefault))
+	downloadManager := stackService.StackDownloadManager.(*StackDownloadManagerMock)
+	downloadManager.downloadStates = make(map[string]DownloadState)
+
+	assert.Nil(t, stackService.RecreateStack(tools.NginxDefault, true))
+	_, wasDownloaded := stackService.StackDownloadManager.GetStackDownloadStates()[tools.NginxDefault]
+	assert.True(t, wasDownloaded)
+}
+
+func TestRestartClearsUnhealthyState(t *testing.T) {
+	stackService, dockerService, clock := createStackServiceWithUnhealthyContainer(t)
+	stackService.Watchdog.CheckStacks()
+	clock.advance(UnhealthyThreshold)
+	stackService.Watchdog.CheckStacks()
+	assertState(t, stackService.GetStackStateInfo(), stackToDeploy, Unhealthy)
+
+	assert.Nil(t, stackService.RestartStack(stackToDeploy))
+	stackService.Watchdog.CheckStacks()
+	assertState(t, stackService.GetStackStateInfo(), stackToDeploy, Available)
+	assert.Equal(t, 0, len(dockerService.unhealthyContainers[stackToDeploy]))
+}
@@ -1,24 +1,72 @@
 package internal
 
 import (
-	"errors"
+	"context"
 	"fmt"
+	"github.com/prometheus/client_golang/prometheus"
+	"io"
+	"ocelot/backend/config"
 	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
 )
 
+// StackServiceImpl manages the lifecycle of the stacks. Data about the stacks is persisted via the StackStores,
+// new stores are added there instead of to this struct.
 type StackServiceImpl struct {
+	StackStores
 	DockerService        DockerService
 	StackConfigService   StackConfigService
 	StackDownloadManager StackDownloadManager
+	UpdateChecker        *ImageUpdateChecker
+	StatsCollector       *StackStatsCollector
+	Watchdog             *StackWatchdog
+	EmailNotifier        *EmailNotifier
+	Events               *StackEventBus
+	mu                   sync.Mutex
 	lastActionOnStack    map[string]StackAction
+	upgradesInProgress   map[string]bool
+	restartsInProgress   map[string]bool
+	// backupsInProgress contains the stacks being backed up or restored, mapped to BackingUp or Restoring.
+	backupsInProgress map[string]StackState
+	upgradeTimeout    time.Duration
+	pollInterval      time.Duration
 }
 
 func ProvideStackServiceMocked(stackConfigService StackConfigService) StackService {
-	return &StackServiceImpl{ProvideServiceMock(), stackConfigService, ProvideDownloadManagerMock(), make(map[string]StackAction)}
+	stores := ProvideInMemoryStackStores()
+	emailNotifier := ProvideEmailNotifier(stores.EmailSubscriptionService, tools.SmtpConfig{}, "localhost")
+	return newStackService(ProvideServiceMock(), stackConfigService, ProvideDownloadManagerMock(), ProvideImageRegistryMock(), stores, emailNotifier)
 }
 
-func ProvideStackServiceReal(stackConfigService StackConfigService) StackService {
-	return &StackServiceImpl{&DockerServiceReal{}, stackConfigService, ProvideStackDownloadManagerReal(), make(map[string]StackAction)}
+func ProvideStackServiceReal(stackConfigService StackConfigService, stores StackStores, emailNotifier *EmailNotifier) StackService {
+	dockerService := &DockerServiceReal{stores.InstanceService, stackConfigService}
+	return newStackService(dockerService, stackConfigService, ProvideStackDownloadManagerReal(), ProvideImageRegistryReal(), stores, emailNotifier)
+}
+
+func newStackService(dockerService DockerService, stackConfigService StackConfigService, downloadManager StackDownloadManager, imageRegistry ImageRegistry, stores StackStores, emailNotifier *EmailNotifier) *StackServiceImpl {
+	events := ProvideStackEventBus()
+	events.Subscribe(ProvideWebhookDispatcher(stores.WebhookService).Dispatch)
+	events.Subscribe(emailNotifier.Notify)
+	return &StackServiceImpl{
+		StackStores:          stores,
+		DockerService:        dockerService,
+		StackConfigService:   stackConfigService,
+		StackDownloadManager: downloadManager,
+		UpdateChecker:        ProvideImageUpdateChecker(dockerService, imageRegistry),
+		StatsCollector:       ProvideStackStatsCollector(dockerService),
+		Watchdog:             ProvideStackWatchdog(dockerService, stores.IncidentHistory, events),
+		EmailNotifier:        emailNotifier,
+		Events:               events,
+		lastActionOnStack:    make(map[string]StackAction),
+		upgradesInProgress:   make(map[string]bool),
+		restartsInProgress:   make(map[string]bool),
+		backupsInProgress:    make(map[string]StackState),
+		upgradeTimeout:       defaultUpgradeTimeout,
+		pollInterval:         defaultPollInterval,
+	}
 }
 
 type StackAction int
@@ -31,7 +79,41 @@ const (
 type StackService interface {
 	DeployStack(stackName string) error
 	StopStack(stackName string) error
+	StopStackWithDependents(stackName string) error
+	RestartStack(stackName string) error
+	RecreateStack(stackName string, shouldPullImages bool) error
 	GetStackStateInfo() map[string]StackDetails
+	GetStackSecrets(stackName string) (map[string]string, error)
+	GetStackConfig(stackName string) StackConfig
+	CreateStackInstance(instanceName string, templateName string) error
+	DeleteStackInstance(instanceName string) error
+	GetStackInstances() map[string]string
+	AddStackDomain(domain string, stackName string) error
+	RemoveStackDomain(domain string) error
+	GetStackDomains() map[string]string
+	StackExists(stackName string) bool
+	UpgradeStack(stackName string) error
+	GetStackVersions(stackName string) ([]StackVersion, error)
+	GetAvailableUpdates() map[string][]ImageUpdate
+	GetStackStats(stackName string) (StackStats, error)
+	GetStackStatsHistory(stackName string) []StackStatsSample
+	GetSystemInfo() SystemInfo
+	GetStackIncidents(stackName string) ([]StackIncident, error)
+	RunBulkOperation(operation string, stackNames []string) ([]StackOperationResult, error)
+	BackupStack(stackName string) (StackBackup, error)
+	GetStackBackups(stackName string) ([]StackBackup, error)
+	RestoreStack(stackName string, backupId string) error
+	StreamStackLogs(ctx context.Context, stackName string, options LogOptions, output io.Writer) error
+	CreateWebhook(url string, secret string, events []string) (Webhook, error)
+	GetWebhooks() ([]Webhook, error)
+	DeleteWebhook(id int64) error
+	GetWebhookDeliveries(webhookId int64) ([]WebhookDelivery, error)
+	PublishEvent(event StackEvent)
+	SetEmailSubscription(user string, email string, events []string) error
+	GetEmailSubscriptions() ([]EmailSubscription, error)
+	DeleteEmailSubscription(user string) error
+	SendTestEmail(email string) error
+	StartBackgroundJobs()
 }
 
 type StackDetails struct {
@@ -40,59 +122,388 @@ type StackDetails struct {
 }
 
 type DockerService interface {
-	DeployStack(stackName string) error
+	DeployStack(stackName string, environment map[string]string) error
+	DeployStackWithComposeFile(stackName string, composeFilePath string, environment map[string]string) error
 	StopStack(stackName string) error
+	RestartStack(stackName string, environment map[string]string) error
+	RecreateStack(stackName string, environment map[string]string) error
 	GetRunningStackStateInfo() (map[string]StackDetails, error)
+	BackupStackVolumes(stackName string, backupDir string) error
+	RestoreStackVolumes(stackName string, backupDir string) error
+	GetStackImages(stackName string) ([]StackImage, error)
+	GetStackStats(stackName string) ([]ContainerStats, error)
+	GetDockerInfo() (DockerInfo, error)
+	GetHostDiskUsage(paths []string) ([]DiskUsage, error)
+	GetStackVolumes(stackName string) ([]string, error)
+	RemoveStackVolumes(stackName string) error
+	GetDiskUsagePerStack() (map[string]StackDiskUsage, error)
+	GetUnhealthyContainers(stackName string) ([]string, error)
+	RestartContainer(containerName string) error
+	StreamStackLogs(ctx context.Context, stackName string, options LogOptions, output io.Writer) error
 }
 
 type StackConfigService interface {
 	GetStackConfig(stackName string) StackConfig
 }
 
+type SecretService interface {
+	GetOrGenerateSecrets(stackName string, secretConfigs []SecretConfig) (map[string]string, error)
+	GetSecrets(stackName string) (map[string]string, error)
+	ImportSecret(stackName string, secretName string, value string) error
+	RemoveSecrets(stackName string) error
+}
+
+// StackInstanceService manages stacks which are created from another stack acting as template. This allows
+// running the same app several times, e.g. "gitea-team-a" and "gitea-team-b" based on the template "gitea".
+type StackInstanceService interface {
+	CreateInstance(instanceName string, templateName string) error
+	DeleteInstance(instanceName string) error
+	GetInstances() map[string]string
+	GetTemplateName(stackName string) string
+}
+
+// StackDomainService manages custom host names, e.g. "git.example.org", under which a stack is reachable in
+// addition to its subdomain of the root domain.
+type StackDomainService interface {
+	AddDomain(domain string, stackName string) error
+	RemoveDomain(domain string) error
+	GetDomains() map[string]string
+}
+
+// ImageRegistry provides information about the images available in a registry like Docker Hub.
+type ImageRegistry interface {
+	GetDigest(image ImageReference) (string, error)
+	GetTags(image ImageReference) ([]string, error)
+}
+
+type StackVersionHistory interface {
+	AddVersion(stackName string, version StackVersion) (int64, error)
+	UpdateVersionStatus(id int64, status string) error
+	GetVersions(stackName string) ([]StackVersion, error)
+}
+
+// StackIncidentHistory records what the watchdog did about unhealthy containers.
+type StackIncidentHistory interface {
+	AddIncident(stackName string, incident StackIncident) error
+	GetIncidents(stackName string) ([]StackIncident, error)
+}
+
+// StackDesiredStateService remembers whether stacks are supposed to run, so that this can be restored after the
+// host was rebooted or a stack was started or stopped outside of Ocelot.
+type StackDesiredStateService interface {
+	SetDesiredState(stackName string, isRunning bool) error
+	RemoveDesiredState(stackName string) error
+	GetDesiredStates() (map[string]bool, error)
+}
+
+// WebhookService stores the webhooks which are notified about events and the log of their deliveries.
+type WebhookService interface {
+	AddWebhook(webhook Webhook) (int64, error)
+	RemoveWebhook(id int64) error
+	GetWebhooks() ([]Webhook, error)
+	AddDelivery(delivery WebhookDelivery) error
+	GetDeliveries(webhookId int64) ([]WebhookDelivery, error)
+}
+
+// EmailSubscriptionService stores which users receive which alerts by email.
+type EmailSubscriptionService interface {
+	SetSubscription(subscription EmailSubscription) error
+	RemoveSubscription(user string) error
+	GetSubscriptions() ([]EmailSubscription, error)
+}
+
 type StackDownloadManager interface {
 	GetStackDownloadStates() map[string]DownloadState
 	DownloadStack(stackName string)
 }
 
 func (sm *StackServiceImpl) DeployStack(stackName string) error {
-	sm.lastActionOnStack[stackName] = Deploy
-	sm.StackDownloadManager.DownloadStack(stackName)
-	return sm.DockerService.DeployStack(stackName)
+	if sm.isUpgradeInProgress(stackName) {
+		return newStackError(ErrInvalidState, stackName, "stack '%s' can't be deployed while it is upgraded", stackName)
+	} else if _, ok := sm.getBackupInProgress(stackName); ok {
+		return newStackError(ErrInvalidState, stackName, "stack '%s' can't be deployed while it is backed up or restored", stackName)
+	}
+	if err := sm.deployDependencies(stackName); err != nil {
+		Logger.Warn("refusing deployment: %s", err.Error())
+		return err
+	}
+	if err := sm.checkResourceReservations(stackName); err != nil {
+		Logger.Warn("refusing deployment: %s", err.Error())
+		return err
+	}
+	secrets, err := sm.provideSecrets(stackName)
+	if err != nil {
+		Logger.Error("failed to provide secrets for stack '%s': %s", stackName, err.Error())
+		sm.publishDeployFailure(stackName, "secrets could not be provided")
+		return fmt.Errorf("secrets of stack '%s' could not be provided: %w", stackName, err)
+	}
+	sm.setLastAction(stackName, Deploy)
+	sm.Watchdog.Reset(stackName)
+	sm.StackDownloadManager.DownloadStack(sm.InstanceService.GetTemplateName(stackName))
+	timer := prometheus.NewTimer(stackOperationDuration.WithLabelValues("deploy"))
+	err = sm.DockerService.DeployStack(stackName, secrets)
+	timer.ObserveDuration()
+	if err != nil {
+		stackOperationFailures.WithLabelValues("deploy").Inc()
+		sm.publishDeployFailure(stackName, err.Error())
+		return err
+	}
+	sm.recordDeployedVersion(stackName)
+	sm.setDesiredState(stackName, true)
+	return nil
+}
+
+// provideSecrets returns the secrets of a stack and generates the missing ones. If a missing secret has a legacy
+// value and the stack already has volumes, the stack was installed before the secret was introduced, so the
+// legacy value is kept instead, which the app data was initialized with.
+func (sm *StackServiceImpl) provideSecrets(stackName string) (map[string]string, error) {
+	secretConfigs := sm.GetStackConfig(stackName).Secrets
+	existingSecrets, err := sm.SecretService.GetSecrets(stackName)
+	if err != nil {
+		return nil, err
+	}
+	for _, secretConfig := range secretConfigs {
+		if _, ok := existingSecrets[secretConfig.Name]; ok || secretConfig.Legacy == "" {
+			continue
+		}
+		volumes, err := sm.DockerService.GetStackVolumes(stackName)
+		if err != nil {
+			return nil, err
+		} else if len(volumes) == 0 {
+			continue
+		}
+		if err = sm.SecretService.ImportSecret(stackName, secretConfig.Name, secretConfig.Legacy); err != nil {
+			return nil, err
+		}
+		Logger.Info("Kept legacy value of secret '%s' for existing installation of stack '%s'", secretConfig.Name, stackName)
+	}
+	return sm.SecretService.GetOrGenerateSecrets(stackName, secretConfigs)
+}
+
+func (sm *StackServiceImpl) publishDeployFailure(stackName string, reason string) {
+	sm.Events.Publish(StackEvent{
+		Type:    EventDeployFailed,
+		Stack:   stackName,
+		Message: fmt.Sprintf("deployment of stack '%s' failed", stackName),
+		Details: map[string]string{"error": reason},
+	})
+}
+
+func (sm *StackServiceImpl) setDesiredState(stackName string, isRunning bool) {
+	if err := sm.DesiredStateService.SetDesiredState(stackName, isRunning); err != nil {
+		Logger.Warn("desired state of stack '%s' could not be stored", stackName)
+	}
+}
+
+func (sm *StackServiceImpl) StartBackgroundJobs() {
+	sm.UpdateChecker.StartPeriodicChecks(ImageUpdateCheckInterval)
+	sm.StatsCollector.StartPeriodicSampling(StatsSampleInterval)
+	sm.Watchdog.StartPeriodicChecks(WatchdogCheckInterval)
+	sm.StartReconciliation(ReconciliationInterval)
+	sm.StartStateObservation(StateObservationInterval)
+}
+
+func (sm *StackServiceImpl) GetStackIncidents(stackName string) ([]StackIncident, error) {
+	return sm.IncidentHistory.GetIncidents(stackName)
+}
+
+func (sm *StackServiceImpl) GetAvailableUpdates() map[string][]ImageUpdate {
+	return sm.UpdateChecker.GetAvailableUpdates()
+}
+
+func (sm *StackServiceImpl) GetStackStats(stackName string) (StackStats, error) {
+	return sm.StatsCollector.GetStats(stackName)
+}
+
+func (sm *StackServiceImpl) GetStackStatsHistory(stackName string) []StackStatsSample {
+	return sm.StatsCollector.GetHistory(stackName)
+}
+
+func (sm *StackServiceImpl) setLastAction(stackName string, action StackAction) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	sm.lastActionOnStack[stackName] = action
+}
+
+func (sm *StackServiceImpl) getLastAction(stackName string) (StackAction, bool) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	action, ok := sm.lastActionOnStack[stackName]
+	return action, ok
+}
+
+func (sm *StackServiceImpl) GetStackConfig(stackName string) StackConfig {
+	return sm.StackConfigService.GetStackConfig(sm.InstanceService.GetTemplateName(stackName))
 }
 
+func (sm *StackServiceImpl) CreateStackInstance(instanceName string, templateName string) error {
+	if !instanceNamePattern.MatchString(instanceName) {
+		return newStackError(ErrInvalidRequest, instanceName, "invalid instance name '%s', only lowercase letters, digits and dashes are allowed", instanceName)
+	}
+
+	templateNames, err := sm.stackNamesInDirectory()
+	if err != nil {
+		return err
+	}
+	if instanceName == "ocelot-cloud" || contains(templateNames, instanceName) {
+		return newStackError(ErrInvalidRequest, instanceName, "instance name '%s' is already used by a stack", instanceName)
+	} else if !contains(templateNames, templateName) {
+		return logAndCreateStackNotFoundError(templateName)
+	}
+
+	// the override of an instance can only add port bindings, so the bindings of the template would collide
+	composeFile, err := readComposeFile(getStackPath(templateName))
+	if err != nil {
+		Logger.Error("failed to read compose file of stack '%s': %v", templateName, err)
+		return newStackError(ErrInvalidState, templateName, "compose file of stack '%s' could not be read", templateName)
+	} else if bindings := composeFile.getHostPortBindings(); len(bindings) > 0 {
+		return newStackError(ErrInvalidRequest, instanceName, "stack '%s' can't have instances, since it binds the host ports %s", templateName, strings.Join(bindings, ", "))
+	}
+
+	Logger.Info("Creating instance '%s' of stack '%s'", instanceName, templateName)
+	return sm.InstanceService.CreateInstance(instanceName, templateName)
+}
+
+func (sm *StackServiceImpl) DeleteStackInstance(instanceName string) error {
+	if _, ok := sm.InstanceService.GetInstances()[instanceName]; !ok {
+		return logAndCreateStackNotFoundError(instanceName)
+	}
+	if state := sm.GetStackStateInfo()[instanceName].State; state != Uninitialized {
+		return newStackError(ErrInvalidState, instanceName, "instance '%s' must be stopped before deletion, state is: %s", instanceName, state.String())
+	}
+
+	Logger.Info("Deleting instance '%s'", instanceName)
+	if err := sm.DockerService.RemoveStackVolumes(instanceName); err != nil {
+		return err
+	}
+	for _, dir := range []string{filepath.Dir(getInstanceOverridePath(instanceName)), filepath.Dir(getResourceOverridePath(instanceName)), filepath.Dir(getPinnedComposePath(instanceName)), getBackupDir(instanceName, "")} {
+		if err := os.RemoveAll(dir); err != nil {
+			Logger.Warn("failed to remove files of instance '%s': %v", instanceName, err)
+		}
+	}
+	if err := sm.SecretService.RemoveSecrets(instanceName); err != nil {
+		Logger.Warn("failed to remove secrets of instance '%s': %v", instanceName, err)
+	}
+	for domain, stackName := range sm.DomainService.GetDomains() {
+		if stackName == instanceName {
+			if err := sm.DomainService.RemoveDomain(domain); err != nil {
+				Logger.Warn("failed to remove domain '%s' of instance '%s': %v", domain, instanceName, err)
+			}
+		}
+	}
+	if err := sm.DesiredStateService.RemoveDesiredState(instanceName); err != nil {
+		Logger.Warn("failed to remove desired state of instance '%s': %v", instanceName, err)
+	}
+	return sm.InstanceService.DeleteInstance(instanceName)
+}
+
+func (sm *StackServiceImpl) GetStackInstances() map[string]string {
+	return sm.InstanceService.GetInstances()
+}
+
+func (sm *StackServiceImpl) AddStackDomain(domain string, stackName string) error {
+	domain = strings.ToLower(domain)
+	if !domainPattern.MatchString(domain) {
+		return newStackError(ErrInvalidRequest, stackName, "invalid domain '%s'", domain)
+	} else if !sm.StackExists(stackName) {
+		return logAndCreateStackNotFoundError(stackName)
+	}
+
+	Logger.Info("Routing domain '%s' to stack '%s'", domain, stackName)
+	return sm.DomainService.AddDomain(domain, stackName)
+}
+
+func (sm *StackServiceImpl) RemoveStackDomain(domain string) error {
+	Logger.Info("Removing domain '%s'", domain)
+	return sm.DomainService.RemoveDomain(strings.ToLower(domain))
+}
+
+func (sm *StackServiceImpl) GetStackDomains() map[string]string {
+	return sm.DomainService.GetDomains()
+}
+
+// StackExists is true for all stacks in the stack directory and their instances, whether running or not.
+func (sm *StackServiceImpl) StackExists(stackName string) bool {
+	if _, ok := sm.InstanceService.GetInstances()[stackName]; ok {
+		return true
+	}
+	templateNames, err := sm.stackNamesInDirectory()
+	return err == nil && contains(templateNames, stackName)
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func (sm *StackServiceImpl) GetStackSecrets(stackName string) (map[string]string, error) {
+	return sm.SecretService.GetSecrets(stackName)
+}
+
+// GetStackStateInfo shows all stacks as uninitialized instead of failing, if docker is unavailable.
 func (sm *StackServiceImpl) GetStackStateInfo() map[string]StackDetails {
+	stackStateInfo, _ := sm.readStackStateInfo()
+	return stackStateInfo
+}
+
+// readStackStateInfo returns the states of all stacks. If docker is unavailable, all stacks are uninitialized
+// and the error is returned as well, since the states must not be acted upon.
+func (sm *StackServiceImpl) readStackStateInfo() (map[string]StackDetails, error) {
 	Logger.Trace("Stack state info was requested.")
-	resultInfos, err := sm.DockerService.GetRunningStackStateInfo()
+	resultInfos, dockerErr := sm.DockerService.GetRunningStackStateInfo()
+	if dockerErr != nil {
+		resultInfos = make(map[string]StackDetails)
+	}
 
 	stacksInDir, err := sm.stackNamesInDirectory()
 	if err != nil {
 		Logger.Error("error when reading stack names from directory: %s", err.Error())
-		return nil
+		return nil, err
+	}
+
+	instances := sm.InstanceService.GetInstances()
+	for instanceName := range instances {
+		stacksInDir = append(stacksInDir, instanceName)
 	}
 
 	resultInfos = sm.addUninitializedStacks(resultInfos, stacksInDir)
 	delete(resultInfos, "ocelot-cloud")
 
 	for stackName, stackDetail := range resultInfos {
-		newPath := sm.StackConfigService.GetStackConfig(stackName).UrlPath
+		newPath := sm.StackConfigService.GetStackConfig(getTemplateNameFrom(instances, stackName)).UrlPath
 		resultInfos[stackName] = StackDetails{stackDetail.State, newPath}
 	}
 
 	downloadStates := sm.StackDownloadManager.GetStackDownloadStates()
 	for stackName, stackDetails := range resultInfos {
-		if _, ok := downloadStates[stackName]; ok {
-			if downloadStates[stackName] == Ongoing {
+		downloadName := getTemplateNameFrom(instances, stackName)
+		lastAction, wasActionPerformed := sm.getLastAction(stackName)
+		if _, ok := downloadStates[downloadName]; ok && wasActionPerformed {
+			if downloadStates[downloadName] == Ongoing {
 				resultInfos[stackName] = StackDetails{Downloading, stackDetails.Path}
-			} else if stackDetails.State == Uninitialized && sm.lastActionOnStack[stackName] == Deploy {
+			} else if stackDetails.State == Uninitialized && lastAction == Deploy {
 				resultInfos[stackName] = StackDetails{Starting, stackDetails.Path}
-			} else if stackDetails.State != Uninitialized && sm.lastActionOnStack[stackName] == Stop {
+			} else if stackDetails.State != Uninitialized && lastAction == Stop {
 				resultInfos[stackName] = StackDetails{Stopping, stackDetails.Path}
 			}
 		}
+		if sm.isUpgradeInProgress(stackName) {
+			resultInfos[stackName] = StackDetails{Upgrading, stackDetails.Path}
+		} else if backupState, ok := sm.getBackupInProgress(stackName); ok {
+			resultInfos[stackName] = StackDetails{backupState, stackDetails.Path}
+		} else if sm.isRestartInProgress(stackName) {
+			resultInfos[stackName] = StackDetails{Restarting, stackDetails.Path}
+		} else if watchdogState, ok := sm.Watchdog.GetState(stackName); ok && resultInfos[stackName].State != Stopping {
+			resultInfos[stackName] = StackDetails{watchdogState, stackDetails.Path}
+		}
 	}
 
 	logStackStateInfo(resultInfos)
-	return resultInfos
+	return resultInfos, dockerErr
 }
 
 func logStackStateInfo(info map[string]StackDetails) {
@@ -135,7 +546,11 @@ func (sm *StackServiceImpl) addUninitializedStacks(resultInfos map[string]StackD
 }
 
 func (sm *StackServiceImpl) StopStack(stackToStopName string) error {
-	sm.lastActionOnStack[stackToStopName] = Stop
+	if dependents := sm.getRunningDependents(stackToStopName); len(dependents) > 0 {
+		Logger.Warn("stack '%s' is not stopped, since running stacks depend on it: %s", stackToStopName, strings.Join(dependents, ", "))
+		return newStackError(ErrInvalidState, stackToStopName, "stack '%s' is required by the running stacks %s, stop them first", stackToStopName, strings.Join(dependents, ", "))
+	}
+	sm.setLastAction(stackToStopName, Stop)
 	Logger.Info("Stopping stack: %s", stackToStopName)
 	stackStateInfo := sm.GetStackStateInfo()
 	var doesStackExist = false
@@ -149,26 +564,20 @@ func (sm *StackServiceImpl) StopStack(stackToStopName string) error {
 	}
 	if doesStackExist == false {
 		return logAndCreateStackNotFoundError(stackToStopName)
-	} else if !(existingStack.State == Starting || existingStack.State == Available || existingStack.State == Stopping) {
-		Logger.Warn("only 'Starting' and 'Available' stacks can be stopped. State is: %s", existingStack.State.String())
-		return errors.New("error - stopping stack failed")
+	} else if !(existingStack.State == Starting || existingStack.State == Available || existingStack.State == Stopping || existingStack.State == Unhealthy || existingStack.State == Erroneous) {
+		Logger.Warn("only running stacks can be stopped. State is: %s", existingStack.State.String())
+		return newStackError(ErrInvalidState, stackToStopName, "error - stopping stack failed")
 	} else {
 		Logger.Debug("Stack does exist and is now stopped: %s", stackToStopName)
-		return sm.DockerService.StopStack(stackToStopName)
-	}
-}
-
-func (sm *StackServiceImpl) StopAllStacks() error {
-	stackStateInfo := sm.GetStackStateInfo()
-
-	for stackName, stackDetails := range stackStateInfo {
-		if stackDetails.State == Starting || stackDetails.State == Available {
-			stackName := stackName
-			err := sm.StopStack(stackName)
-			if err != nil {
-				return err
-			}
+		timer := prometheus.NewTimer(stackOperationDuration.WithLabelValues("stop"))
+		err := sm.DockerService.StopStack(stackToStopName)
+		timer.ObserveDuration()
+		if err != nil {
+			stackOperationFailures.WithLabelValues("stop").Inc()
+		} else {
+			sm.Watchdog.Reset(stackToStopName)
+			sm.setDesiredState(stackToStopName, false)
 		}
+		return err
 	}
-	return nil
 }
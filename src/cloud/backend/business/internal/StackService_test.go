@@ -1,6 +1,7 @@
 package internal
 
 import (
+	"errors"
 	"github.com/ocelot-cloud/shared/assert"
 	"ocelot/backend/config"
 	"testing"
@@ -11,7 +12,15 @@ var stack2ToDeploy = tools.NginxDefault2
 
 func createStackService() *StackServiceImpl {
 	StackFileDir = DefaultStackFileDir
-	return &StackServiceImpl{ProvideServiceMock(), ProvideStackConfigService(StackFileDir), ProvideDownloadManagerMock(), make(map[string]StackAction)}
+	return ProvideStackServiceMocked(ProvideStackConfigService(StackFileDir)).(*StackServiceImpl)
+}
+
+// useTemporaryDataDir lets the test write backups, override files and the like to a temporary directory, the
+// original data directory is restored when the test is finished.
+func useTemporaryDataDir(t *testing.T) {
+	originalDataDir := DataDir
+	DataDir = t.TempDir()
+	t.Cleanup(func() { DataDir = originalDataDir })
 }
 
 func TestHappyPathDeployAndStop(t *testing.T) {
@@ -67,6 +76,7 @@ func TestToNotRunningStack(t *testing.T) {
 	err := stackService.StopStack(stackToDeploy)
 	assert.NotNil(t, err)
 	assert.Equal(t, "error - stopping stack failed", err.Error())
+	assert.True(t, errors.Is(err, ErrInvalidState))
 }
 
 func TestIgnoreStackInStackInfo(t *testing.T) {
@@ -143,3 +153,80 @@ func TestDownloadStateHandling(t *testing.T) {
 	api.deploy().assertState(Downloading).assertState(Starting).assertState(Available)
 	api.stop().assertState(Uninitialized)
 }
+
+func TestInstancesAreManagedLikeStacks(t *testing.T) {
+	stackService := createStackService()
+	assert.Nil(t, stackService.CreateStackInstance("nginx-team-a", tools.NginxCustomPath))
+	assertState(t, stackService.GetStackStateInfo(), "nginx-team-a", Uninitialized)
+	assert.Equal(t, "/custom-path", getUrlPathForStack(t, stackService, "nginx-team-a"))
+
+	assert.Nil(t, stackService.DeployStack("nginx-team-a"))
+	assertState(t, stackService.GetStackStateInfo(), "nginx-team-a", Available)
+	assertState(t, stackService.GetStackStateInfo(), tools.NginxCustomPath, Uninitialized)
+
+	assert.NotNil(t, stackService.DeleteStackInstance("nginx-team-a"))
+	assert.Nil(t, stackService.StopStack("nginx-team-a"))
+	assert.Nil(t, stackService.DeleteStackInstance("nginx-team-a"))
+	assert.Equal(t, 0, len(stackService.GetStackInstances()))
+}
+
+func TestDataOfDeletedInstancesIsRemoved(t *testing.T) {
+	stackService := createStackService()
+	assert.Nil(t, stackService.CreateStackInstance("nginx-team-a", tools.NginxCustomPort))
+	assert.Nil(t, stackService.DeployStack("nginx-team-a"))
+	assert.Nil(t, stackService.StopStack("nginx-team-a"))
+	secrets, err := stackService.GetStackSecrets("nginx-team-a")
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(secrets))
+
+	assert.Nil(t, stackService.DeleteStackInstance("nginx-team-a"))
+	secrets, err = stackService.GetStackSecrets("nginx-team-a")
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(secrets))
+	assert.Equal(t, []string{"nginx-team-a_data"}, stackService.DockerService.(*DockerServiceMock).removedVolumes)
+}
+
+func TestInstancesUseTheConfigOfTheirTemplate(t *testing.T) {
+	stackService := createStackService()
+	assert.Nil(t, stackService.CreateStackInstance("nginx-team-a", tools.NginxCustomPort))
+	assert.Equal(t, "3000", stackService.GetStackConfig("nginx-team-a").Port)
+}
+
+func TestInvalidInstancesAreRejected(t *testing.T) {
+	stackService := createStackService()
+	assert.NotNil(t, stackService.CreateStackInstance("nginx-team-a", "not-existing-stack"))
+	assert.NotNil(t, stackService.CreateStackInstance(tools.NginxDefault2, tools.NginxDefault))
+	assert.NotNil(t, stackService.CreateStackInstance("Invalid_Name", tools.NginxDefault))
+	assert.NotNil(t, stackService.CreateStackInstance("ocelot-cloud", tools.NginxDefault))
+}
+
+func TestCustomDomainsAreValidated(t *testing.T) {
+	stackService := createStackService()
+	assert.Nil(t, stackService.AddStackDomain("git.example.org", tools.NginxDefault))
+	assert.NotNil(t, stackService.AddStackDomain("wiki.example.org", "not-existing-stack"))
+	assert.NotNil(t, stackService.AddStackDomain("no_valid domain", tools.NginxDefault))
+	assert.NotNil(t, stackService.AddStackDomain("example", tools.NginxDefault))
+	assert.Equal(t, map[string]string{"git.example.org": tools.NginxDefault}, stackService.GetStackDomains())
+}
+
+func TestDomainsOfDeletedInstancesAreRemoved(t *testing.T) {
+	stackService := createStackService()
+	assert.Nil(t, stackService.CreateStackInstance("nginx-team-a", tools.NginxDefault))
+	assert.Nil(t, stackService.AddStackDomain("team-a.example.org", "nginx-team-a"))
+	assert.Nil(t, stackService.DeleteStackInstance("nginx-team-a"))
+	assert.Equal(t, 0, len(stackService.GetStackDomains()))
+}
+
+func TestStackIsNotStartingIfItsSecretsCannotBeProvided(t *testing.T) {
+	stackService := createStackService()
+	db := ProvideInMemoryDatabase()
+	stackService.SecretService = newSecretService(db, generateSecretKey())
+	assert.Nil(t, db.Close())
+
+	err := stackService.DeployStack(tools.NginxDefault)
+	assert.NotNil(t, err)
+	assert.Equal(t, "secrets of stack 'nginx-default' could not be provided: failed to read secrets", err.Error())
+	_, wasActionPerformed := stackService.getLastAction(tools.NginxDefault)
+	assert.False(t, wasActionPerformed)
+	assertState(t, stackService.GetStackStateInfo(), tools.NginxDefault, Uninitialized)
+}
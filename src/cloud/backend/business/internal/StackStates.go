@@ -9,8 +9,16 @@ const (
 	Available
 	Downloading
 	Stopping
+	Upgrading
+	// Unhealthy stacks have containers which failed their health checks for a while and are being restarted.
+	Unhealthy
+	// Erroneous stacks have containers which stayed unhealthy despite several restarts. It is reported as "Error".
+	Erroneous
+	Restarting
+	BackingUp
+	Restoring
 )
 
 func (s *StackState) String() string {
-	return [...]string{"Uninitialized", "Running", "Starting", "Available", "Downloading", "Stopping"}[*s]
+	return [...]string{"Uninitialized", "Running", "Starting", "Available", "Downloading", "Stopping", "Upgrading", "Unhealthy", "Error", "Restarting", "BackingUp", "Restoring"}[*s]
 }
@@ -0,0 +1,174 @@
+package internal
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ContainerStats is the resource usage of a container. Network and block IO are totals since the container
+// was started.
+type ContainerStats struct {
+	Name             string
+	CpuPercent       float64
+	MemoryUsageBytes uint64
+	MemoryLimitBytes uint64
+	NetworkRxBytes   uint64
+	NetworkTxBytes   uint64
+	BlockReadBytes   uint64
+	BlockWriteBytes  uint64
+}
+
+type StackStats struct {
+	Containers []ContainerStats
+	Total      ContainerStats
+}
+
+// StackStatsSample is an entry of the history of the resource usage of a stack.
+type StackStatsSample struct {
+	Timestamp        time.Time
+	CpuPercent       float64
+	MemoryUsageBytes uint64
+}
+
+func aggregateContainerStats(stackName string, containers []ContainerStats) StackStats {
+	total := ContainerStats{Name: stackName}
+	for _, container := range containers {
+		total.CpuPercent += container.CpuPercent
+		total.MemoryUsageBytes += container.MemoryUsageBytes
+		total.MemoryLimitBytes += container.MemoryLimitBytes
+		total.NetworkRxBytes += container.NetworkRxBytes
+		total.NetworkTxBytes += container.NetworkTxBytes
+		total.BlockReadBytes += container.BlockReadBytes
+		total.BlockWriteBytes += container.BlockWriteBytes
+	}
+	return StackStats{Containers: containers, Total: total}
+}
+
+// StackStatsCollector samples the resource usage of all running stacks periodically and keeps a short history,
+// which is sufficient for sparkline charts in the dashboard.
+type StackStatsCollector struct {
+	dockerService DockerService
+	mu            sync.Mutex
+	history       map[string][]StackStatsSample
+	maxSamples    int
+}
+
+func ProvideStackStatsCollector(dockerService DockerService) *StackStatsCollector {
+	return &StackStatsCollector{dockerService: dockerService, history: make(map[string][]StackStatsSample), maxSamples: StatsHistorySize}
+}
+
+func (c *StackStatsCollector) StartPeriodicSampling(interval time.Duration) {
+	go func() {
+		for {
+			c.SampleRunningStacks()
+			time.Sleep(interval)
+		}
+	}()
+}
+
+func (c *StackStatsCollector) SampleRunningStacks() {
+	stackStateInfo, err := c.dockerService.GetRunningStackStateInfo()
+	if err != nil {
+		Logger.Warn("sampling resource usage failed, since the running stacks could not be determined")
+		return
+	}
+
+	for stackName, stackDetails := range stackStateInfo {
+		if stackDetails.State == Uninitialized {
+			c.clearHistory(stackName)
+			continue
+		}
+		if _, err = c.GetStats(stackName); err != nil {
+			Logger.Debug("sampling resource usage of stack '%s' failed: %v", stackName, err)
+		}
+	}
+}
+
+// GetStats determines the current resource usage of a stack and adds it to its history.
+func (c *StackStatsCollector) GetStats(stackName string) (StackStats, error) {
+	containers, err := c.dockerService.GetStackStats(stackName)
+	if err != nil {
+		return StackStats{}, err
+	}
+	stats := aggregateContainerStats(stackName, containers)
+	if len(containers) > 0 {
+		c.addSample(stackName, StackStatsSample{Timestamp: time.Now(), CpuPercent: stats.Total.CpuPercent, MemoryUsageBytes: stats.Total.MemoryUsageBytes})
+	}
+	return stats, nil
+}
+
+// GetHistory returns the samples of a stack, oldest first.
+func (c *StackStatsCollector) GetHistory(stackName string) []StackStatsSample {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]StackStatsSample{}, c.history[stackName]...)
+}
+
+func (c *StackStatsCollector) addSample(stackName string, sample StackStatsSample) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	samples := append(c.history[stackName], sample)
+	if len(samples) > c.maxSamples {
+		samples = samples[len(samples)-c.maxSamples:]
+	}
+	c.history[stackName] = samples
+}
+
+func (c *StackStatsCollector) clearHistory(stackName string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.history, stackName)
+}
+
+var byteUnits = map[string]float64{
+	"b":   1,
+	"kb":  1e3,
+	"mb":  1e6,
+	"gb":  1e9,
+	"tb":  1e12,
+	"kib": 1 << 10,
+	"mib": 1 << 20,
+	"gib": 1 << 30,
+	"tib": 1 << 40,
+}
+
+// parseByteSize parses sizes as printed by "docker stats", e.g. "1.5MiB" or "648B".
+func parseByteSize(size string) (uint64, error) {
+	size = strings.TrimSpace(size)
+	unitIndex := strings.IndexFunc(size, func(r rune) bool {
+		return (r < '0' || r > '9') && r != '.'
+	})
+	if unitIndex <= 0 {
+		return 0, fmt.Errorf("invalid size '%s'", size)
+	}
+	value, err := strconv.ParseFloat(size[:unitIndex], 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid size '%s'", size)
+	}
+	factor, ok := byteUnits[strings.ToLower(size[unitIndex:])]
+	if !ok {
+		return 0, fmt.Errorf("invalid unit in size '%s'", size)
+	}
+	return uint64(math.Round(value * factor)), nil
+}
+
+// parseByteSizePair parses pairs like "1.2kB / 648B", which "docker stats" uses for memory, network and block IO.
+func parseByteSizePair(pair string) (uint64, uint64, error) {
+	parts := strings.Split(pair, "/")
+	if len(parts) != 2 {
+		return 0, 0, fmt.Errorf("invalid size pair '%s'", pair)
+	}
+	first, err := parseByteSize(parts[0])
+	if err != nil {
+		return 0, 0, err
+	}
+	second, err := parseByteSize(parts[1])
+	if err != nil {
+		return 0, 0, err
+	}
+	return first, second, nil
+}
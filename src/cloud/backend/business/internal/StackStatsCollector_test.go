@@ -0,0 +1,86 @@
+package internal
+
+import (
+	"github.com/ocelot-cloud/shared/assert"
+	"testing"
+)
+
+func TestStatsAreOnlyReportedForRunningStacks(t *testing.T) {
+	stackService := createStackService()
+
+	stats, err := stackService.GetStackStats(stackToDeploy)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(stats.Containers))
+	assert.Equal(t, 0, len(stackService.GetStackStatsHistory(stackToDeploy)))
+
+	assert.Nil(t, stackService.DeployStack(stackToDeploy))
+	stats, err = stackService.GetStackStats(stackToDeploy)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(stats.Containers))
+	assert.Equal(t, stats.Containers[0].MemoryUsageBytes, stats.Total.MemoryUsageBytes)
+	assert.Equal(t, 1, len(stackService.GetStackStatsHistory(stackToDeploy)))
+}
+
+func TestStatsAreAggregatedPerStack(t *testing.T) {
+	containers := []ContainerStats{
+		{Name: "app", CpuPercent: 1.5, MemoryUsageBytes: 100, MemoryLimitBytes: 1000, NetworkRxBytes: 10, NetworkTxBytes: 20, BlockReadBytes: 30, BlockWriteBytes: 40},
+		{Name: "db", CpuPercent: 2.5, MemoryUsageBytes: 200, MemoryLimitBytes: 1000, NetworkRxBytes: 1, NetworkTxBytes: 2, BlockReadBytes: 3, BlockWriteBytes: 4},
+	}
+	stats := aggregateContainerStats("gitea", containers)
+	assert.Equal(t, ContainerStats{Name: "gitea", CpuPercent: 4, MemoryUsageBytes: 300, MemoryLimitBytes: 2000, NetworkRxBytes: 11, NetworkTxBytes: 22, BlockReadBytes: 33, BlockWriteBytes: 44}, stats.Total)
+	assert.Equal(t, 2, len(stats.Containers))
+}
+
+func TestStatsHistoryIsLimitedAndClearedWhenStackStops(t *testing.T) {
+	stackService := createStackService()
+	stackService.StatsCollector.maxSamples = 3
+	assert.Nil(t, stackService.DeployStack(stackToDeploy))
+
+	for i := 0; i < 5; i++ {
+		stackService.StatsCollector.SampleRunningStacks()
+	}
+	history := stackService.GetStackStatsHistory(stackToDeploy)
+	assert.Equal(t, 3, len(history))
+	assert.False(t, history[2].Timestamp.Before(history[0].Timestamp))
+
+	assert.Nil(t, stackService.StopStack(stackToDeploy))
+	stackService.StatsCollector.SampleRunningStacks()
+	assert.Equal(t, 0, len(stackService.GetStackStatsHistory(stackToDeploy)))
+}
+
+func TestParseByteSize(t *testing.T) {
+	sizes := map[string]uint64{
+		"648B":    648,
+		"1.5kB":   1500,
+		"2MB":     2000000,
+		"1GB":     1000000000,
+		"1KiB":    1024,
+		"1.5MiB":  1572864,
+		"2GiB":    2 << 30,
+		" 10MiB ": 10 << 20,
+	}
+	for size, expected := range sizes {
+		actual, err := parseByteSize(size)
+		assert.Nil(t, err)
+		assert.Equal(t, expected, actual)
+	}
+
+	for _, size := range []string{"", "MiB", "12", "12XB", "1.2.3kB"} {
+		_, err := parseByteSize(size)
+		assert.NotNil(t, err)
+	}
+}
+
+func TestParseDockerStatsOutput(t *testing.T) {
+	output := `{"BlockIO":"4.1MB / 0B","CPUPerc":"0.25%","Container":"abc","ID":"abc","MemPerc":"0.31%","MemUsage":"12.5MiB / 4GiB","Name":"gitea-gitea-1","NetIO":"1.2kB / 648B","PIDs":"3"}
+{"BlockIO":"0B / 0B","CPUPerc":"10.00%","Container":"def","ID":"def","MemPerc":"0.10%","MemUsage":"4MiB / 1GiB","Name":"gitea-db-1","NetIO":"0B / 0B","PIDs":"1"}
+`
+	stats, err := parseDockerStatsOutput([]byte(output))
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(stats))
+	assert.Equal(t, ContainerStats{Name: "gitea-gitea-1", CpuPercent: 0.25, MemoryUsageBytes: 12.5 * (1 << 20), MemoryLimitBytes: 4 << 30, NetworkRxBytes: 1200, NetworkTxBytes: 648, BlockReadBytes: 4100000}, stats[0])
+	assert.Equal(t, 10.0, stats[1].CpuPercent)
+
+	_, err = parseDockerStatsOutput([]byte(`{"Name":"x","CPUPerc":"--","MemUsage":"0B / 0B","NetIO":"0B / 0B","BlockIO":"0B / 0B"}`))
+	assert.NotNil(t, err)
+}
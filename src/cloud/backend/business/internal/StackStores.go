@@ -0,0 +1,40 @@
+package internal
+
+import "database/sql"
+
+// StackStores bundles the services persisting data about the stacks and their notifications, so that a new store
+// does not require changes to the constructors of the services using them.
+type StackStores struct {
+	SecretService            SecretService
+	InstanceService          StackInstanceService
+	DomainService            StackDomainService
+	VersionHistory           StackVersionHistory
+	IncidentHistory          StackIncidentHistory
+	DesiredStateService      StackDesiredStateService
+	WebhookService           WebhookService
+	EmailSubscriptionService EmailSubscriptionService
+}
+
+func ProvideStackStores(db *sql.DB, dataDir string) StackStores {
+	return newStackStores(db, ProvideSecretService(db, dataDir))
+}
+
+// ProvideInMemoryStackStores provides stores which start empty and are discarded with the process, which is used
+// when the dependencies are mocked.
+func ProvideInMemoryStackStores() StackStores {
+	db := ProvideInMemoryDatabase()
+	return newStackStores(db, newSecretService(db, generateSecretKey()))
+}
+
+func newStackStores(db *sql.DB, secretService *SecretServiceImpl) StackStores {
+	return StackStores{
+		SecretService:            secretService,
+		InstanceService:          ProvideStackInstanceService(db),
+		DomainService:            ProvideStackDomainService(db),
+		VersionHistory:           ProvideStackVersionHistory(db),
+		IncidentHistory:          ProvideStackIncidentHistory(db),
+		DesiredStateService:      ProvideStackDesiredStateService(db),
+		WebhookService:           ProvideWebhookService(db, secretService),
+		EmailSubscriptionService: ProvideEmailSubscriptionService(db),
+	}
+}
@@ -0,0 +1,239 @@
+package internal
+
+import (
+	"gopkg.in/yaml.v3"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const defaultUpgradeTimeout = 5 * time.Minute
+const defaultPollInterval = 1 * time.Second
+
+// UpgradeStack starts the upgrade of a running stack to the version described by the compose file in its stack
+// directory. The upgrade itself runs in the background, its progress is visible in the version history.
+func (sm *StackServiceImpl) UpgradeStack(stackName string) error {
+	stackDetails, ok := sm.GetStackStateInfo()[stackName]
+	if !ok {
+		return logAndCreateStackNotFoundError(stackName)
+	} else if stackDetails.State != Available {
+		return newStackError(ErrInvalidState, stackName, "only 'Available' stacks can be upgraded, state of stack '%s' is: %s", stackName, stackDetails.State.String())
+	}
+
+	versions, err := sm.VersionHistory.GetVersions(stackName)
+	if err != nil {
+		return err
+	}
+	currentVersion, ok := getCurrentVersion(versions)
+	if !ok {
+		return newStackError(ErrInvalidState, stackName, "the deployed version of stack '%s' is unknown, it must be deployed again before upgrading", stackName)
+	}
+	newComposeFile, err := os.ReadFile(getStackPath(sm.InstanceService.GetTemplateName(stackName)))
+	if err != nil {
+		return err
+	}
+	if string(newComposeFile) == currentVersion.ComposeFile {
+		return newStackError(ErrInvalidState, stackName, "stack '%s' is already up to date", stackName)
+	}
+
+	if !sm.markUpgradeAsStarted(stackName) {
+		return newStackError(ErrInvalidState, stackName, "stack '%s' is already being upgraded", stackName)
+	}
+	newVersion := StackVersion{Version: getVersionOfComposeFile(newComposeFile), ComposeFile: string(newComposeFile), Status: VersionUpgrading, Timestamp: time.Now()}
+	versionId, err := sm.VersionHistory.AddVersion(stackName, newVersion)
+	if err != nil {
+		sm.markUpgradeAsFinished(stackName)
+		return err
+	}
+
+	Logger.Info("Upgrading stack '%s' from '%s' to '%s'", stackName, currentVersion.Version, newVersion.Version)
+	go func() {
+		defer sm.markUpgradeAsFinished(stackName)
+		status := sm.upgrade(stackName, currentVersion)
+		if err := sm.VersionHistory.UpdateVersionStatus(versionId, status); err != nil {
+			Logger.Error("failed to record the result of the upgrade of stack '%s'", stackName)
+		}
+		Logger.Info("Upgrade of stack '%s' finished with status '%s'", stackName, status)
+	}()
+	return nil
+}
+
+func (sm *StackServiceImpl) GetStackVersions(stackName string) ([]StackVersion, error) {
+	return sm.VersionHistory.GetVersions(stackName)
+}
+
+func (sm *StackServiceImpl) upgrade(stackName string, previousVersion StackVersion) string {
+	secrets, err := sm.provideSecrets(stackName)
+	if err != nil {
+		Logger.Error("failed to provide secrets for stack '%s': %s", stackName, err.Error())
+		return VersionFailed
+	}
+
+	if err = sm.downloadImages(sm.InstanceService.GetTemplateName(stackName)); err != nil {
+		Logger.Error("failed to download the images for the upgrade of stack '%s': %s", stackName, err.Error())
+		return VersionFailed
+	}
+
+	backupDir, err := sm.createPreUpgradeBackup(stackName, previousVersion)
+	if err != nil {
+		Logger.Error("failed to create the pre-upgrade backup of stack '%s': %s", stackName, err.Error())
+		if backupDir == "" {
+			return VersionFailed
+		}
+		return sm.redeployPreviousVersion(stackName, previousVersion, secrets)
+	}
+
+	if err = os.RemoveAll(filepath.Dir(getPinnedComposePath(stackName))); err == nil {
+		err = sm.DockerService.DeployStack(stackName, secrets)
+	}
+	if err == nil && sm.waitUntilStackIsAvailable(stackName) {
+		return VersionUpgraded
+	}
+	Logger.Warn("Stack '%s' did not become available after the upgrade, rolling back to version '%s'", stackName, previousVersion.Version)
+	if err = sm.DockerService.StopStack(stackName); err != nil {
+		Logger.Warn("failed to stop stack '%s' before rollback: %s", stackName, err.Error())
+	}
+	if err = sm.DockerService.RestoreStackVolumes(stackName, backupDir); err != nil {
+		Logger.Error("failed to restore the volumes of stack '%s': %s", stackName, err.Error())
+		return VersionFailed
+	}
+	return sm.redeployPreviousVersion(stackName, previousVersion, secrets)
+}
+
+// downloadImages waits until the images of a stack are downloaded, but not longer than an upgrade may take.
+func (sm *StackServiceImpl) downloadImages(templateName string) error {
+	sm.StackDownloadManager.DownloadStack(templateName)
+	deadline := time.Now().Add(sm.upgradeTimeout)
+	for time.Now().Before(deadline) {
+		switch sm.StackDownloadManager.GetStackDownloadStates()[templateName] {
+		case Finished:
+			return nil
+		case Error:
+			return newStackError(ErrDownloadFailed, templateName, "download of stack '%s' failed", templateName)
+		}
+		time.Sleep(sm.pollInterval)
+	}
+	return newStackError(ErrTimeout, templateName, "download of stack '%s' did not finish within %s", templateName, sm.upgradeTimeout)
+}
+
+// createPreUpgradeBackup stores the compose file of the previous version and the volumes of the stack, which
+// have to be stopped to be consistent. The backup directory is returned as soon as the compose file is written.
+func (sm *StackServiceImpl) createPreUpgradeBackup(stackName string, previousVersion StackVersion) (string, error) {
+	backupDir := getBackupDir(stackName, time.Now().Format(backupTimestampFormat)+"-"+BackupReasonPreUpgrade)
+	if err := os.MkdirAll(backupDir, 0700); err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(filepath.Join(backupDir, "docker-compose.yml"), []byte(previousVersion.ComposeFile), 0600); err != nil {
+		return "", err
+	}
+
+	if err := sm.DockerService.StopStack(stackName); err != nil {
+		return backupDir, err
+	}
+	return backupDir, sm.DockerService.BackupStackVolumes(stackName, backupDir)
+}
+
+// redeployPreviousVersion pins the stack to the compose file of the previous version, so that later deployments don't
+// bring back the failed version, which is still in the stack directory.
+func (sm *StackServiceImpl) redeployPreviousVersion(stackName string, previousVersion StackVersion, secrets map[string]string) string {
+	pinnedComposePath := getPinnedComposePath(stackName)
+	err := os.MkdirAll(filepath.Dir(pinnedComposePath), 0700)
+	if err == nil {
+		err = os.WriteFile(pinnedComposePath, []byte(previousVersion.ComposeFile), 0600)
+	}
+	if err == nil {
+		err = sm.DockerService.DeployStackWithComposeFile(stackName, pinnedComposePath, secrets)
+	}
+	if err != nil {
+		Logger.Error("failed to redeploy the previous version of stack '%s': %s", stackName, err.Error())
+		return VersionFailed
+	}
+	return VersionRolledBack
+}
+
+func (sm *StackServiceImpl) waitUntilStackIsAvailable(stackName string) bool {
+	deadline := time.Now().Add(sm.upgradeTimeout)
+	for time.Now().Before(deadline) {
+		stackStateInfo, err := sm.DockerService.GetRunningStackStateInfo()
+		if err == nil && stackStateInfo[stackName].State == Available {
+			return true
+		}
+		time.Sleep(sm.pollInterval)
+	}
+	return false
+}
+
+// getPinnedComposePath returns the path of the compose file of the version a stack was rolled back to. As long as it
+// exists, the stack is deployed with it instead of the compose file in the stack directory, which can then only be
+// deployed by an upgrade.
+func getPinnedComposePath(stackName string) string {
+	return filepath.Join(DataDir, "versions", stackName, "docker-compose.yml")
+}
+
+// getDeployedComposePath returns the compose file a stack is deployed with, see getPinnedComposePath.
+func getDeployedComposePath(stackName string, templateName string) string {
+	if _, err := os.Stat(getPinnedComposePath(stackName)); err == nil {
+		return getPinnedComposePath(stackName)
+	}
+	return getStackPath(templateName)
+}
+
+func (sm *StackServiceImpl) recordDeployedVersion(stackName string) {
+	composeFile, err := os.ReadFile(getDeployedComposePath(stackName, sm.InstanceService.GetTemplateName(stackName)))
+	if err != nil {
+		Logger.Debug("version of stack '%s' is not recorded, since its compose file could not be read", stackName)
+		return
+	}
+	versions, err := sm.VersionHistory.GetVersions(stackName)
+	if err != nil {
+		return
+	}
+	if currentVersion, ok := getCurrentVersion(versions); ok && currentVersion.ComposeFile == string(composeFile) {
+		return
+	}
+	version := StackVersion{Version: getVersionOfComposeFile(composeFile), ComposeFile: string(composeFile), Status: VersionInstalled, Timestamp: time.Now()}
+	if _, err = sm.VersionHistory.AddVersion(stackName, version); err != nil {
+		Logger.Warn("failed to record version of stack '%s'", stackName)
+	}
+}
+
+// getVersionOfComposeFile describes a version by the images used in a compose file, e.g. "gitea/gitea:1.20.2".
+func getVersionOfComposeFile(composeFileContent []byte) string {
+	var composeFile ComposeFile
+	if err := yaml.Unmarshal(composeFileContent, &composeFile); err != nil {
+		return "unknown"
+	}
+	var images []string
+	for _, serviceName := range composeFile.getSortedServiceNames() {
+		if image := composeFile.Services[serviceName].Image; image != "" {
+			images = append(images, image)
+		}
+	}
+	if len(images) == 0 {
+		return "local build"
+	}
+	return strings.Join(images, ", ")
+}
+
+func (sm *StackServiceImpl) markUpgradeAsStarted(stackName string) bool {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	if _, ok := sm.backupsInProgress[stackName]; ok || sm.upgradesInProgress[stackName] {
+		return false
+	}
+	sm.upgradesInProgress[stackName] = true
+	return true
+}
+
+func (sm *StackServiceImpl) markUpgradeAsFinished(stackName string) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	delete(sm.upgradesInProgress, stackName)
+}
+
+func (sm *StackServiceImpl) isUpgradeInProgress(stackName string) bool {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	return sm.upgradesInProgress[stackName]
+}
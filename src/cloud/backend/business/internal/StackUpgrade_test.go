@@ -0,0 +1,162 @@
+package internal
+
+import (
+	"errors"
+	"github.com/ocelot-cloud/shared/assert"
+	"ocelot/backend/config"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+const previousComposeFile = "services:\n  nginx-default:\n    image: nginx:1.24\n"
+
+func createStackServiceForUpgrades(t *testing.T) *StackServiceImpl {
+	useTemporaryDataDir(t)
+
+	stackService := createStackService()
+	stackService.upgradeTimeout = 50 * time.Millisecond
+	stackService.pollInterval = time.Millisecond
+	return stackService
+}
+
+func deployPreviousVersion(t *testing.T, stackService *StackServiceImpl, stackName string) {
+	assert.Nil(t, stackService.DeployStack(stackName))
+	_, err := stackService.VersionHistory.AddVersion(stackName, StackVersion{Version: "nginx:1.24", ComposeFile: previousComposeFile, Status: VersionInstalled, Timestamp: time.Now()})
+	assert.Nil(t, err)
+}
+
+func waitForUpgradeResult(t *testing.T, stackService *StackServiceImpl, stackName string) StackVersion {
+	for attempt := 0; attempt < 1000; attempt++ {
+		versions, err := stackService.GetStackVersions(stackName)
+		assert.Nil(t, err)
+		latestVersion := versions[len(versions)-1]
+		if latestVersion.Status != VersionUpgrading && !stackService.isUpgradeInProgress(stackName) {
+			return latestVersion
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("upgrade of stack '%s' did not finish", stackName)
+	return StackVersion{}
+}
+
+// stuckDownloadManager simulates a download which never finishes once it was started, e.g. due to a stalled registry.
+type stuckDownloadManager struct {
+	isDownloading bool
+}
+
+func (m *stuckDownloadManager) GetStackDownloadStates() map[string]DownloadState {
+	if m.isDownloading {
+		return map[string]DownloadState{tools.NginxDefault: Ongoing}
+	}
+	return map[string]DownloadState{tools.NginxDefault: Finished}
+}
+
+func (m *stuckDownloadManager) DownloadStack(string) {
+	m.isDownloading = true
+}
+
+func TestStuckDownloadTimesOut(t *testing.T) {
+	stackService := createStackServiceForUpgrades(t)
+	assert.Nil(t, stackService.DeployStack(tools.NginxDefault))
+	stackService.StackDownloadManager = &stuckDownloadManager{}
+
+	err := stackService.RecreateStack(tools.NginxDefault, true)
+	assert.True(t, errors.Is(err, ErrTimeout))
+}
+
+func TestSuccessfulUpgrade(t *testing.T) {
+	stackService := createStackServiceForUpgrades(t)
+	dockerServiceMock := stackService.DockerService.(*DockerServiceMock)
+	deployPreviousVersion(t, stackService, tools.NginxDefault)
+
+	assert.Nil(t, stackService.UpgradeStack(tools.NginxDefault))
+
+	assert.Equal(t, VersionUpgraded, waitForUpgradeResult(t, stackService, tools.NginxDefault).Status)
+	assert.Equal(t, []string{tools.NginxDefault}, dockerServiceMock.backedUpStacks)
+	assert.Equal(t, 0, len(dockerServiceMock.restoredStacks))
+	assertState(t, stackService.GetStackStateInfo(), tools.NginxDefault, Available)
+	assert.Equal(t, getStackPath(tools.NginxDefault), getDeployedComposePath(tools.NginxDefault, tools.NginxDefault))
+}
+
+func TestUpgradeIsRolledBackWhenStackDoesNotBecomeAvailable(t *testing.T) {
+	stackService := createStackServiceForUpgrades(t)
+	dockerServiceMock := stackService.DockerService.(*DockerServiceMock)
+	deployPreviousVersion(t, stackService, tools.NginxDefault)
+	dockerServiceMock.neverAvailableStacks[tools.NginxDefault] = true
+
+	assert.Nil(t, stackService.UpgradeStack(tools.NginxDefault))
+
+	assert.Equal(t, VersionRolledBack, waitForUpgradeResult(t, stackService, tools.NginxDefault).Status)
+	assert.Equal(t, []string{tools.NginxDefault}, dockerServiceMock.restoredStacks)
+	assertState(t, stackService.GetStackStateInfo(), tools.NginxDefault, Available)
+
+	backupComposeFiles, err := filepath.Glob(filepath.Join(DataDir, "backups", tools.NginxDefault, "*", "docker-compose.yml"))
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(backupComposeFiles))
+	content, err := os.ReadFile(backupComposeFiles[0])
+	assert.Nil(t, err)
+	assert.Equal(t, previousComposeFile, string(content))
+
+	versions, err := stackService.GetStackVersions(tools.NginxDefault)
+	assert.Nil(t, err)
+	currentVersion, _ := getCurrentVersion(versions)
+	assert.Equal(t, "nginx:1.24", currentVersion.Version)
+}
+
+func TestRolledBackVersionIsKeptByLaterDeployments(t *testing.T) {
+	stackService := createStackServiceForUpgrades(t)
+	dockerServiceMock := stackService.DockerService.(*DockerServiceMock)
+	deployPreviousVersion(t, stackService, tools.NginxDefault)
+	dockerServiceMock.neverAvailableStacks[tools.NginxDefault] = true
+	assert.Nil(t, stackService.UpgradeStack(tools.NginxDefault))
+	assert.Equal(t, VersionRolledBack, waitForUpgradeResult(t, stackService, tools.NginxDefault).Status)
+
+	deployedComposePath := getDeployedComposePath(tools.NginxDefault, tools.NginxDefault)
+	assert.Equal(t, getPinnedComposePath(tools.NginxDefault), deployedComposePath)
+	content, err := os.ReadFile(deployedComposePath)
+	assert.Nil(t, err)
+	assert.Equal(t, previousComposeFile, string(content))
+
+	delete(dockerServiceMock.neverAvailableStacks, tools.NginxDefault)
+	assert.Nil(t, stackService.StopStack(tools.NginxDefault))
+	assert.Nil(t, stackService.DeployStack(tools.NginxDefault))
+	versions, err := stackService.GetStackVersions(tools.NginxDefault)
+	assert.Nil(t, err)
+	currentVersion, _ := getCurrentVersion(versions)
+	assert.Equal(t, "nginx:1.24", currentVersion.Version)
+	assert.Equal(t, VersionRolledBack, versions[len(versions)-1].Status)
+
+	assert.Nil(t, stackService.UpgradeStack(tools.NginxDefault))
+	assert.Equal(t, VersionUpgraded, waitForUpgradeResult(t, stackService, tools.NginxDefault).Status)
+	assert.Equal(t, getStackPath(tools.NginxDefault), getDeployedComposePath(tools.NginxDefault, tools.NginxDefault))
+}
+
+func TestUpgradeOfUpToDateStackIsRejected(t *testing.T) {
+	stackService := createStackServiceForUpgrades(t)
+	assert.Nil(t, stackService.DeployStack(tools.NginxDefault))
+	assert.NotNil(t, stackService.UpgradeStack(tools.NginxDefault))
+}
+
+func TestUpgradeOfNotRunningStackIsRejected(t *testing.T) {
+	stackService := createStackServiceForUpgrades(t)
+	assert.NotNil(t, stackService.UpgradeStack(tools.NginxDefault))
+}
+
+func TestDeploymentsAreRecordedInVersionHistory(t *testing.T) {
+	stackService := createStackServiceForUpgrades(t)
+	assert.Nil(t, stackService.DeployStack(tools.NginxDefault))
+	assert.Nil(t, stackService.StopStack(tools.NginxDefault))
+	assert.Nil(t, stackService.DeployStack(tools.NginxDefault))
+
+	versions, err := stackService.GetStackVersions(tools.NginxDefault)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(versions))
+	assert.Equal(t, VersionInstalled, versions[0].Status)
+	assert.Equal(t, "local build", versions[0].Version)
+}
+
+func TestVersionIsDescribedByImages(t *testing.T) {
+	assert.Equal(t, "sample/app:1.0.0, redis:7, postgres:16, sample/worker:1.0.0", getVersionOfComposeFile([]byte(sampleComposeFile)))
+}
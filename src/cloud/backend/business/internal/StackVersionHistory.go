@@ -0,0 +1,85 @@
+package internal
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+const (
+	VersionInstalled  = "installed"
+	VersionUpgrading  = "upgrading"
+	VersionUpgraded   = "upgraded"
+	VersionRolledBack = "rolled-back"
+	VersionFailed     = "failed"
+)
+
+// StackVersion is a version of a stack, which is identified by the images used in its compose file.
+type StackVersion struct {
+	Id          int64
+	Version     string
+	ComposeFile string
+	Status      string
+	Timestamp   time.Time
+}
+
+type StackVersionHistoryImpl struct {
+	db *sql.DB
+}
+
+func ProvideStackVersionHistory(db *sql.DB) *StackVersionHistoryImpl {
+	createTableIfNotExisting(db, "stack_versions (id INTEGER NOT NULL PRIMARY KEY AUTOINCREMENT, stack_name TEXT NOT NULL, version TEXT NOT NULL, compose_file TEXT NOT NULL, status TEXT NOT NULL, timestamp INTEGER NOT NULL)")
+	return &StackVersionHistoryImpl{db}
+}
+
+func (s *StackVersionHistoryImpl) AddVersion(stackName string, version StackVersion) (int64, error) {
+	result, err := s.db.Exec("INSERT INTO stack_versions (stack_name, version, compose_file, status, timestamp) VALUES (?, ?, ?, ?, ?)",
+		stackName, version.Version, version.ComposeFile, version.Status, version.Timestamp.Unix())
+	if err != nil {
+		Logger.Error("failed to store version of stack '%s': %v", stackName, err)
+		return 0, fmt.Errorf("failed to store version")
+	}
+	return result.LastInsertId()
+}
+
+func (s *StackVersionHistoryImpl) UpdateVersionStatus(id int64, status string) error {
+	_, err := s.db.Exec("UPDATE stack_versions SET status = ? WHERE id = ?", status, id)
+	if err != nil {
+		Logger.Error("failed to update status of version %d: %v", id, err)
+		return fmt.Errorf("failed to update version")
+	}
+	return nil
+}
+
+// GetVersions returns the versions of a stack, the oldest one first.
+func (s *StackVersionHistoryImpl) GetVersions(stackName string) ([]StackVersion, error) {
+	rows, err := s.db.Query("SELECT id, version, compose_file, status, timestamp FROM stack_versions WHERE stack_name = ? ORDER BY id", stackName)
+	if err != nil {
+		Logger.Error("failed to query versions of stack '%s': %v", stackName, err)
+		return nil, fmt.Errorf("failed to read versions")
+	}
+	defer rows.Close()
+
+	var versions []StackVersion
+	for rows.Next() {
+		var version StackVersion
+		var timestamp int64
+		if err := rows.Scan(&version.Id, &version.Version, &version.ComposeFile, &version.Status, &timestamp); err != nil {
+			return nil, err
+		}
+		version.Timestamp = time.Unix(timestamp, 0)
+		versions = append(versions, version)
+	}
+	return versions, rows.Err()
+}
+
+// getCurrentVersion returns the version which is deployed at the moment, which is the latest version that
+// was successfully installed or upgraded to.
+func getCurrentVersion(versions []StackVersion) (StackVersion, bool) {
+	for i := len(versions) - 1; i >= 0; i-- {
+		if versions[i].Status == VersionInstalled || versions[i].Status == VersionUpgraded {
+			return versions[i], true
+		}
+	}
+	return StackVersion{}, false
+}
@@ -0,0 +1,33 @@
+package internal
+
+import (
+	"github.com/ocelot-cloud/shared/assert"
+	"ocelot/backend/config"
+	"testing"
+	"time"
+)
+
+func TestStackVersionHistory(t *testing.T) {
+	database := ProvideDatabase(t.TempDir())
+	defer database.Close()
+	versionHistory := ProvideStackVersionHistory(database)
+
+	_, err := versionHistory.AddVersion(tools.NginxDefault, StackVersion{Version: "nginx:1.24", ComposeFile: "a", Status: VersionInstalled, Timestamp: time.Now()})
+	assert.Nil(t, err)
+	id, err := versionHistory.AddVersion(tools.NginxDefault, StackVersion{Version: "nginx:1.25", ComposeFile: "b", Status: VersionUpgrading, Timestamp: time.Now()})
+	assert.Nil(t, err)
+	assert.Nil(t, versionHistory.UpdateVersionStatus(id, VersionRolledBack))
+
+	versions, err := versionHistory.GetVersions(tools.NginxDefault)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(versions))
+	assert.Equal(t, VersionRolledBack, versions[1].Status)
+
+	currentVersion, ok := getCurrentVersion(versions)
+	assert.True(t, ok)
+	assert.Equal(t, "nginx:1.24", currentVersion.Version)
+
+	otherVersions, err := versionHistory.GetVersions(tools.NginxDefault2)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(otherVersions))
+}
@@ -0,0 +1,172 @@
+package internal
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// containerHealth tracks a container which failed its health check.
+type containerHealth struct {
+	stackName      string
+	unhealthySince time.Time
+	isReported     bool
+	restarts       int
+	nextRestart    time.Time
+	hasGivenUp     bool
+}
+
+// StackWatchdog restarts containers which are unhealthy for longer than UnhealthyThreshold. The delay between
+// restarts doubles each time. When a container is still unhealthy after WatchdogMaxRestarts restarts, the
+// watchdog gives up and the stack is reported as erroneous until it is deployed or stopped again.
+type StackWatchdog struct {
+	dockerService DockerService
+	incidents     StackIncidentHistory
+	events        *StackEventBus
+	mu            sync.Mutex
+	containers    map[string]*containerHealth
+	now           func() time.Time
+}
+
+func ProvideStackWatchdog(dockerService DockerService, incidents StackIncidentHistory, events *StackEventBus) *StackWatchdog {
+	return &StackWatchdog{dockerService: dockerService, incidents: incidents, events: events, containers: make(map[string]*containerHealth), now: time.Now}
+}
+
+func (w *StackWatchdog) StartPeriodicChecks(interval time.Duration) {
+	go func() {
+		for {
+			time.Sleep(interval)
+			w.CheckStacks()
+		}
+	}()
+}
+
+func (w *StackWatchdog) CheckStacks() {
+	stackStateInfo, err := w.dockerService.GetRunningStackStateInfo()
+	if err != nil {
+		Logger.Warn("watchdog could not determine the running stacks")
+		return
+	}
+
+	for stackName, stackDetails := range stackStateInfo {
+		if stackDetails.State == Uninitialized {
+			w.Reset(stackName)
+			continue
+		}
+		unhealthyContainers, err := w.dockerService.GetUnhealthyContainers(stackName)
+		if err != nil {
+			continue
+		}
+		w.checkStack(stackName, unhealthyContainers)
+	}
+}
+
+func (w *StackWatchdog) checkStack(stackName string, unhealthyContainers []string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	now := w.now()
+
+	isUnhealthy := make(map[string]bool)
+	for _, containerName := range unhealthyContainers {
+		isUnhealthy[containerName] = true
+	}
+	for containerName, health := range w.containers {
+		if health.stackName == stackName && !isUnhealthy[containerName] {
+			if health.isReported {
+				w.recordIncident(stackName, containerName, IncidentRecovered, "container is healthy again")
+			}
+			delete(w.containers, containerName)
+		}
+	}
+
+	for _, containerName := range unhealthyContainers {
+		health, ok := w.containers[containerName]
+		if !ok {
+			w.containers[containerName] = &containerHealth{stackName: stackName, unhealthySince: now}
+			continue
+		}
+		if health.hasGivenUp || now.Sub(health.unhealthySince) < UnhealthyThreshold {
+			continue
+		}
+		if !health.isReported {
+			health.isReported = true
+			w.recordIncident(stackName, containerName, IncidentUnhealthy, fmt.Sprintf("container is unhealthy since %s", health.unhealthySince.Format(time.RFC3339)))
+		}
+		if health.restarts >= WatchdogMaxRestarts {
+			health.hasGivenUp = true
+			w.recordIncident(stackName, containerName, IncidentGaveUp, fmt.Sprintf("container is still unhealthy after %d restarts", health.restarts))
+			continue
+		}
+		if now.Before(health.nextRestart) {
+			continue
+		}
+		w.restart(stackName, containerName, health, now)
+	}
+}
+
+func (w *StackWatchdog) restart(stackName string, containerName string, health *containerHealth, now time.Time) {
+	health.restarts++
+	health.nextRestart = now.Add(getRestartBackoff(health.restarts))
+	if err := w.dockerService.RestartContainer(containerName); err != nil {
+		w.recordIncident(stackName, containerName, IncidentRestartFailed, fmt.Sprintf("restart %d of %d failed", health.restarts, WatchdogMaxRestarts))
+		return
+	}
+	Logger.Info("Watchdog restarted unhealthy container '%s' of stack '%s'", containerName, stackName)
+	watchdogRestarts.WithLabelValues(stackName).Inc()
+	w.recordIncident(stackName, containerName, IncidentRestarted, fmt.Sprintf("restart %d of %d", health.restarts, WatchdogMaxRestarts))
+}
+
+// getRestartBackoff returns the delay after the given restart, which doubles with each restart.
+func getRestartBackoff(restarts int) time.Duration {
+	backoff := WatchdogInitialBackoff
+	for i := 1; i < restarts && backoff < WatchdogMaxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > WatchdogMaxBackoff {
+		return WatchdogMaxBackoff
+	}
+	return backoff
+}
+
+func (w *StackWatchdog) recordIncident(stackName string, containerName string, kind string, message string) {
+	incident := StackIncident{Container: containerName, Kind: kind, Message: message, Timestamp: w.now()}
+	if err := w.incidents.AddIncident(stackName, incident); err != nil {
+		Logger.Warn("incident '%s' of container '%s' could not be recorded", kind, containerName)
+	}
+	if kind == IncidentUnhealthy || kind == IncidentGaveUp {
+		w.events.Publish(StackEvent{
+			Type:    EventContainerUnhealthy,
+			Stack:   stackName,
+			Message: fmt.Sprintf("container '%s' of stack '%s': %s", containerName, stackName, message),
+			Details: map[string]string{"container": containerName, "incident": kind},
+		})
+	}
+}
+
+// GetState returns Unhealthy or Erroneous for stacks with containers which are unhealthy for too long.
+func (w *StackWatchdog) GetState(stackName string) (StackState, bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	state, ok := Available, false
+	for _, health := range w.containers {
+		if health.stackName != stackName || !health.isReported {
+			continue
+		}
+		if health.hasGivenUp {
+			return Erroneous, true
+		}
+		state, ok = Unhealthy, true
+	}
+	return state, ok
+}
+
+// Reset forgets the containers of a stack, e.g. when it was deployed again, so that they get new restarts.
+func (w *StackWatchdog) Reset(stackName string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for containerName, health := range w.containers {
+		if health.stackName == stackName {
+			delete(w.containers, containerName)
+		}
+	}
+}
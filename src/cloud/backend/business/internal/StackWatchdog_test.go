@@ -0,0 +1,96 @@
+package internal
+
+import (
+	"github.com/ocelot-cloud/shared/assert"
+	"testing"
+	"time"
+)
+
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) advance(duration time.Duration) {
+	c.now = c.now.Add(duration)
+}
+
+func createStackServiceWithUnhealthyContainer(t *testing.T) (*StackServiceImpl, *DockerServiceMock, *fakeClock) {
+	stackService := createStackService()
+	clock := &fakeClock{now: time.Now()}
+	stackService.Watchdog.now = func() time.Time { return clock.now }
+	assert.Nil(t, stackService.DeployStack(stackToDeploy))
+	dockerService := stackService.DockerService.(*DockerServiceMock)
+	dockerService.unhealthyContainers[stackToDeploy] = []string{stackToDeploy}
+	return stackService, dockerService, clock
+}
+
+func getIncidentKinds(t *testing.T, stackService *StackServiceImpl) []string {
+	incidents, err := stackService.GetStackIncidents(stackToDeploy)
+	assert.Nil(t, err)
+	var kinds []string
+	for _, incident := range incidents {
+		kinds = append(kinds, incident.Kind)
+	}
+	return kinds
+}
+
+func TestUnhealthyContainerIsToleratedUntilThreshold(t *testing.T) {
+	stackService, dockerService, clock := createStackServiceWithUnhealthyContainer(t)
+
+	stackService.Watchdog.CheckStacks()
+	clock.advance(UnhealthyThreshold - time.Second)
+	stackService.Watchdog.CheckStacks()
+
+	assert.Equal(t, 0, len(dockerService.restartedContainers))
+	assertState(t, stackService.GetStackStateInfo(), stackToDeploy, Available)
+	assert.Equal(t, 0, len(getIncidentKinds(t, stackService)))
+}
+
+func TestUnhealthyContainerIsRestartedWithBackoffAndMarkedAsErroneous(t *testing.T) {
+	stackService, dockerService, clock := createStackServiceWithUnhealthyContainer(t)
+
+	stackService.Watchdog.CheckStacks()
+	clock.advance(UnhealthyThreshold)
+	stackService.Watchdog.CheckStacks()
+	assert.Equal(t, []string{stackToDeploy}, dockerService.restartedContainers)
+	assertState(t, stackService.GetStackStateInfo(), stackToDeploy, Unhealthy)
+
+	stackService.Watchdog.CheckStacks()
+	assert.Equal(t, 1, len(dockerService.restartedContainers))
+
+	for restart := 1; restart < WatchdogMaxRestarts; restart++ {
+		clock.advance(getRestartBackoff(restart))
+		stackService.Watchdog.CheckStacks()
+	}
+	assert.Equal(t, WatchdogMaxRestarts, len(dockerService.restartedContainers))
+	assertState(t, stackService.GetStackStateInfo(), stackToDeploy, Unhealthy)
+
+	clock.advance(WatchdogMaxBackoff)
+	stackService.Watchdog.CheckStacks()
+	assert.Equal(t, WatchdogMaxRestarts, len(dockerService.restartedContainers))
+	assertState(t, stackService.GetStackStateInfo(), stackToDeploy, Erroneous)
+	assert.Equal(t, []string{IncidentUnhealthy, IncidentRestarted, IncidentRestarted, IncidentRestarted, IncidentGaveUp}, getIncidentKinds(t, stackService))
+
+	assert.Nil(t, stackService.StopStack(stackToDeploy))
+	assertState(t, stackService.GetStackStateInfo(), stackToDeploy, Uninitialized)
+}
+
+func TestRecoveryOfContainerIsRecorded(t *testing.T) {
+	stackService, dockerService, clock := createStackServiceWithUnhealthyContainer(t)
+
+	stackService.Watchdog.CheckStacks()
+	clock.advance(UnhealthyThreshold)
+	stackService.Watchdog.CheckStacks()
+	dockerService.unhealthyContainers[stackToDeploy] = nil
+	stackService.Watchdog.CheckStacks()
+
+	assertState(t, stackService.GetStackStateInfo(), stackToDeploy, Available)
+	assert.Equal(t, []string{IncidentUnhealthy, IncidentRestarted, IncidentRecovered}, getIncidentKinds(t, stackService))
+}
+
+func TestRestartBackoffDoublesUpToMaximum(t *testing.T) {
+	assert.Equal(t, WatchdogInitialBackoff, getRestartBackoff(1))
+	assert.Equal(t, 2*WatchdogInitialBackoff, getRestartBackoff(2))
+	assert.Equal(t, 4*WatchdogInitialBackoff, getRestartBackoff(3))
+	assert.Equal(t, WatchdogMaxBackoff, getRestartBackoff(20))
+}
@@ -0,0 +1,266 @@
+package internal
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// procDir is the mount point of the proc filesystem, which is replaced in tests.
+var procDir = "/proc"
+
+type DiskUsage struct {
+	Path           string
+	TotalBytes     uint64
+	UsedBytes      uint64
+	AvailableBytes uint64
+	UsedPercent    float64
+}
+
+type MemoryUsage struct {
+	TotalBytes     uint64
+	AvailableBytes uint64
+	UsedPercent    float64
+}
+
+type LoadAverage struct {
+	Load1  float64
+	Load5  float64
+	Load15 float64
+	Cpus   int
+}
+
+type DockerInfo struct {
+	Version        string
+	ComposeVersion string
+	DataRoot       string
+}
+
+// StackDiskUsage is the space occupied by the images and volumes of a stack. Images shared by several stacks
+// are counted for each of them.
+type StackDiskUsage struct {
+	ImageBytes  uint64
+	VolumeBytes uint64
+}
+
+type SystemInfo struct {
+	Disks    []DiskUsage
+	Memory   MemoryUsage
+	Load     LoadAverage
+	Docker   DockerInfo
+	Stacks   map[string]StackDiskUsage
+	Warnings []string
+}
+
+// GetSystemInfo gives an overview of the health of the host. Parts which can't be determined are left empty and
+// reported as warning, so that the overview is still available when e.g. the docker daemon does not respond.
+func (sm *StackServiceImpl) GetSystemInfo() SystemInfo {
+	info := SystemInfo{Stacks: make(map[string]StackDiskUsage)}
+	var err error
+
+	if info.Docker, err = sm.DockerService.GetDockerInfo(); err != nil {
+		info.Warnings = append(info.Warnings, "docker version could not be determined")
+	}
+	diskPaths := []string{"/"}
+	if info.Docker.DataRoot != "" {
+		diskPaths = append(diskPaths, info.Docker.DataRoot)
+	}
+	if info.Disks, err = sm.DockerService.GetHostDiskUsage(diskPaths); err != nil {
+		info.Warnings = append(info.Warnings, "disk usage of the host could not be determined")
+	}
+	if info.Memory, err = getMemoryUsage(); err != nil {
+		Logger.Warn("memory usage could not be determined: %v", err)
+		info.Warnings = append(info.Warnings, "memory usage could not be determined")
+	}
+	if info.Load, err = getLoadAverage(); err != nil {
+		Logger.Warn("load average could not be determined: %v", err)
+		info.Warnings = append(info.Warnings, "load average could not be determined")
+	}
+	if diskUsagePerStack, err := sm.DockerService.GetDiskUsagePerStack(); err != nil {
+		info.Warnings = append(info.Warnings, "disk usage of stacks could not be determined")
+	} else {
+		for stackName := range sm.GetStackStateInfo() {
+			info.Stacks[stackName] = diskUsagePerStack[stackName]
+		}
+	}
+
+	info.Warnings = append(info.Warnings, getThresholdWarnings(info)...)
+	return info
+}
+
+func getThresholdWarnings(info SystemInfo) []string {
+	var warnings []string
+	for _, disk := range info.Disks {
+		if disk.UsedPercent >= DiskUsageWarningPercent {
+			warnings = append(warnings, fmt.Sprintf("disk of '%s' is %.0f%% full", disk.Path, disk.UsedPercent))
+		}
+	}
+	if info.Memory.UsedPercent >= MemoryUsageWarningPercent {
+		warnings = append(warnings, fmt.Sprintf("memory is %.0f%% used", info.Memory.UsedPercent))
+	}
+	if info.Load.Cpus > 0 && info.Load.Load5 >= LoadWarningPerCpu*float64(info.Load.Cpus) {
+		warnings = append(warnings, fmt.Sprintf("load average of %.2f is high for %d CPUs", info.Load.Load5, info.Load.Cpus))
+	}
+	return warnings
+}
+
+// parseDfOutput parses the output of "df -P -k" for the given paths. Paths on the same file system as a previous
+// path are skipped, e.g. the docker data root when it is not on a separate disk.
+func parseDfOutput(output []byte, paths []string) ([]DiskUsage, error) {
+	lines := strings.Split(strings.TrimSpace(string(output)), "\n")
+	if len(lines) != len(paths)+1 {
+		return nil, fmt.Errorf("unexpected df output '%s'", string(output))
+	}
+	var diskUsages []DiskUsage
+	knownFileSystems := make(map[string]bool)
+	for i, line := range lines[1:] {
+		fields := strings.Fields(line)
+		if len(fields) < 5 {
+			return nil, fmt.Errorf("unexpected df output '%s'", line)
+		}
+		var sizes [3]uint64
+		for j := range sizes {
+			size, err := strconv.ParseUint(fields[j+1], 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("unexpected df output '%s'", line)
+			}
+			sizes[j] = size * 1024
+		}
+		if knownFileSystems[fields[0]] {
+			continue
+		}
+		knownFileSystems[fields[0]] = true
+		total, used, available := sizes[0], sizes[1], sizes[2]
+		diskUsages = append(diskUsages, DiskUsage{Path: paths[i], TotalBytes: total, UsedBytes: used, AvailableBytes: available, UsedPercent: getPercentage(used, used+available)})
+	}
+	return diskUsages, nil
+}
+
+func getMemoryUsage() (MemoryUsage, error) {
+	file, err := os.Open(filepath.Join(procDir, "meminfo"))
+	if err != nil {
+		return MemoryUsage{}, err
+	}
+	defer file.Close()
+
+	values := make(map[string]uint64)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 {
+			continue
+		}
+		value, err := strconv.ParseUint(fields[1], 10, 64)
+		if err != nil {
+			continue
+		}
+		values[strings.TrimSuffix(fields[0], ":")] = value * 1024
+	}
+	if err = scanner.Err(); err != nil {
+		return MemoryUsage{}, err
+	}
+
+	total, available := values["MemTotal"], values["MemAvailable"]
+	if total == 0 {
+		return MemoryUsage{}, fmt.Errorf("total memory not found in meminfo")
+	}
+	return MemoryUsage{TotalBytes: total, AvailableBytes: available, UsedPercent: getPercentage(total-available, total)}, nil
+}
+
+func getLoadAverage() (LoadAverage, error) {
+	content, err := os.ReadFile(filepath.Join(procDir, "loadavg"))
+	if err != nil {
+		return LoadAverage{}, err
+	}
+	fields := strings.Fields(string(content))
+	if len(fields) < 3 {
+		return LoadAverage{}, fmt.Errorf("invalid loadavg '%s'", string(content))
+	}
+	var loads [3]float64
+	for i := range loads {
+		if loads[i], err = strconv.ParseFloat(fields[i], 64); err != nil {
+			return LoadAverage{}, fmt.Errorf("invalid loadavg '%s'", string(content))
+		}
+	}
+	return LoadAverage{Load1: loads[0], Load5: loads[1], Load15: loads[2], Cpus: runtime.NumCPU()}, nil
+}
+
+func getPercentage(part uint64, total uint64) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(part) / float64(total) * 100
+}
+
+// dockerDiskUsage is the relevant part of the output of "docker system df -v --format '{{json .}}'".
+type dockerDiskUsage struct {
+	Images []struct {
+		Repository string `json:"Repository"`
+		Tag        string `json:"Tag"`
+		Size       string `json:"Size"`
+	} `json:"Images"`
+	Containers []struct {
+		Image  string `json:"Image"`
+		Labels string `json:"Labels"`
+	} `json:"Containers"`
+	Volumes []struct {
+		Name   string `json:"Name"`
+		Labels string `json:"Labels"`
+		Size   string `json:"Size"`
+	} `json:"Volumes"`
+}
+
+func (u dockerDiskUsage) getUsagePerStack() map[string]StackDiskUsage {
+	imageSizes := make(map[string]uint64)
+	for _, image := range u.Images {
+		if size, err := parseByteSize(image.Size); err == nil {
+			imageSizes[image.Repository+":"+image.Tag] = size
+		}
+	}
+
+	usagePerStack := make(map[string]StackDiskUsage)
+	countedImages := make(map[string]bool)
+	for _, container := range u.Containers {
+		stackName := getComposeProjectOfLabels(container.Labels)
+		image := getImageWithTag(container.Image)
+		if stackName == "" || countedImages[stackName+" "+image] {
+			continue
+		}
+		countedImages[stackName+" "+image] = true
+		usage := usagePerStack[stackName]
+		usage.ImageBytes += imageSizes[image]
+		usagePerStack[stackName] = usage
+	}
+	for _, volume := range u.Volumes {
+		stackName := getComposeProjectOfLabels(volume.Labels)
+		size, err := parseByteSize(volume.Size)
+		if stackName == "" || err != nil {
+			continue
+		}
+		usage := usagePerStack[stackName]
+		usage.VolumeBytes += size
+		usagePerStack[stackName] = usage
+	}
+	return usagePerStack
+}
+
+// getComposeProjectOfLabels extracts the project from labels formatted like "com.docker.compose.project=gitea,...".
+func getComposeProjectOfLabels(labels string) string {
+	for _, label := range strings.Split(labels, ",") {
+		if value, ok := strings.CutPrefix(label, "com.docker.compose.project="); ok {
+			return value
+		}
+	}
+	return ""
+}
+
+func getImageWithTag(image string) string {
+	if strings.LastIndex(image, ":") > strings.LastIndex(image, "/") {
+		return image
+	}
+	return image + ":latest"
+}
@@ -0,0 +1,102 @@
+package internal
+
+import (
+	"encoding/json"
+	"github.com/ocelot-cloud/shared/assert"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func useFakeProcDir(t *testing.T, meminfo string, loadavg string) {
+	originalProcDir := procDir
+	procDir = t.TempDir()
+	t.Cleanup(func() { procDir = originalProcDir })
+	assert.Nil(t, os.WriteFile(filepath.Join(procDir, "meminfo"), []byte(meminfo), 0600))
+	assert.Nil(t, os.WriteFile(filepath.Join(procDir, "loadavg"), []byte(loadavg), 0600))
+}
+
+func TestMemoryUsageAndLoadAreReadFromProc(t *testing.T) {
+	useFakeProcDir(t, "MemTotal:        4000000 kB\nMemFree:          500000 kB\nMemAvailable:    1000000 kB\n", "0.50 1.25 2.00 1/123 4567\n")
+
+	memory, err := getMemoryUsage()
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(4000000*1024), memory.TotalBytes)
+	assert.Equal(t, uint64(1000000*1024), memory.AvailableBytes)
+	assert.Equal(t, 75.0, memory.UsedPercent)
+
+	load, err := getLoadAverage()
+	assert.Nil(t, err)
+	assert.Equal(t, 0.5, load.Load1)
+	assert.Equal(t, 1.25, load.Load5)
+	assert.Equal(t, 2.0, load.Load15)
+	assert.True(t, load.Cpus > 0)
+}
+
+func TestInvalidProcFilesAreRejected(t *testing.T) {
+	useFakeProcDir(t, "MemFree: 500000 kB\n", "high\n")
+	_, err := getMemoryUsage()
+	assert.NotNil(t, err)
+	_, err = getLoadAverage()
+	assert.NotNil(t, err)
+}
+
+func TestDfOutputIsParsed(t *testing.T) {
+	output := `Filesystem           1024-blocks    Used Available Capacity Mounted on
+/dev/sda1              102400000 40960000  61440000  40% /host
+/dev/sda1              102400000 40960000  61440000  40% /host
+/dev/sdb1               10240000  9728000    512000  95% /host/var/lib/docker
+`
+	diskUsages, err := parseDfOutput([]byte(output), []string{"/", "/srv", "/var/lib/docker"})
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(diskUsages))
+	assert.Equal(t, "/", diskUsages[0].Path)
+	assert.Equal(t, uint64(102400000*1024), diskUsages[0].TotalBytes)
+	assert.Equal(t, 40.0, diskUsages[0].UsedPercent)
+	assert.Equal(t, "/var/lib/docker", diskUsages[1].Path)
+	assert.Equal(t, 95.0, diskUsages[1].UsedPercent)
+
+	_, err = parseDfOutput([]byte("df: /host/missing: No such file or directory\n"), []string{"/missing"})
+	assert.NotNil(t, err)
+}
+
+func TestWarningsAreGivenWhenThresholdsAreCrossed(t *testing.T) {
+	info := SystemInfo{
+		Disks:  []DiskUsage{{Path: "/", UsedPercent: 50}, {Path: "/var/lib/docker", UsedPercent: 95}},
+		Memory: MemoryUsage{UsedPercent: 40},
+		Load:   LoadAverage{Load5: 1, Cpus: 2},
+	}
+	assert.Equal(t, []string{"disk of '/var/lib/docker' is 95% full"}, getThresholdWarnings(info))
+
+	info.Memory.UsedPercent = 95
+	info.Load.Load5 = 4
+	assert.Equal(t, 3, len(getThresholdWarnings(info)))
+}
+
+func TestSystemInfoContainsDiskUsageOfStacks(t *testing.T) {
+	useFakeProcDir(t, "MemTotal: 4000000 kB\nMemAvailable: 3000000 kB\n", "0.10 0.10 0.10 1/123 4567\n")
+	stackService := createStackService()
+	assert.Nil(t, stackService.DeployStack(stackToDeploy))
+
+	info := stackService.GetSystemInfo()
+	assert.Equal(t, "25.0.3", info.Docker.Version)
+	assert.True(t, info.Stacks[stackToDeploy].ImageBytes > 0)
+	assert.Equal(t, uint64(0), info.Stacks[stack2ToDeploy].ImageBytes)
+	assert.Equal(t, 25.0, info.Memory.UsedPercent)
+	assert.Equal(t, []string{"/", "/var/lib/docker"}, []string{info.Disks[0].Path, info.Disks[1].Path})
+}
+
+func TestDockerDiskUsageIsAssignedToStacks(t *testing.T) {
+	output := `{"Images":[{"Repository":"gitea/gitea","Tag":"1.20.2","Size":"100MB"},{"Repository":"nginx","Tag":"latest","Size":"50MB"}],
+"Containers":[{"Image":"gitea/gitea:1.20.2","Labels":"com.docker.compose.service=gitea,com.docker.compose.project=gitea"},
+{"Image":"nginx","Labels":"com.docker.compose.project=gitea"},{"Image":"nginx","Labels":"com.docker.compose.project=nginx-default"},
+{"Image":"nginx","Labels":""}],
+"Volumes":[{"Name":"gitea_data","Labels":"com.docker.compose.project=gitea","Size":"2GB"},{"Name":"anonymous","Labels":"","Size":"1GB"}]}`
+	var diskUsage dockerDiskUsage
+	assert.Nil(t, json.Unmarshal([]byte(output), &diskUsage))
+
+	usagePerStack := diskUsage.getUsagePerStack()
+	assert.Equal(t, 2, len(usagePerStack))
+	assert.Equal(t, StackDiskUsage{ImageBytes: 150000000, VolumeBytes: 2000000000}, usagePerStack["gitea"])
+	assert.Equal(t, StackDiskUsage{ImageBytes: 50000000}, usagePerStack["nginx-default"])
+}
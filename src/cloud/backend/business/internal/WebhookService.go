@@ -0,0 +1,117 @@
+package internal
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// WebhookServiceImpl stores the webhooks and their delivery log. The secrets of the webhooks are encrypted with the
+// key of the secret service.
+type WebhookServiceImpl struct {
+	db            *sql.DB
+	secretService *SecretServiceImpl
+}
+
+func ProvideWebhookService(db *sql.DB, secretService *SecretServiceImpl) *WebhookServiceImpl {
+	createTableIfNotExisting(db, "webhooks (id INTEGER NOT NULL PRIMARY KEY AUTOINCREMENT, url TEXT NOT NULL, encrypted_secret TEXT NOT NULL, events TEXT NOT NULL, created_at INTEGER NOT NULL)")
+	createTableIfNotExisting(db, "webhook_deliveries (id INTEGER NOT NULL PRIMARY KEY AUTOINCREMENT, webhook_id INTEGER NOT NULL, delivery_id TEXT NOT NULL, event TEXT NOT NULL, attempt INTEGER NOT NULL, status_code INTEGER NOT NULL, error TEXT NOT NULL, is_successful INTEGER NOT NULL, timestamp INTEGER NOT NULL)")
+	return &WebhookServiceImpl{db, secretService}
+}
+
+func (s *WebhookServiceImpl) AddWebhook(webhook Webhook) (int64, error) {
+	encryptedSecret, err := s.secretService.encrypt(webhook.Secret)
+	if err != nil {
+		return 0, err
+	}
+	result, err := s.db.Exec("INSERT INTO webhooks (url, encrypted_secret, events, created_at) VALUES (?, ?, ?, ?)",
+		webhook.Url, encryptedSecret, strings.Join(webhook.Events, ","), webhook.CreatedAt.Unix())
+	if err != nil {
+		Logger.Error("failed to store webhook: %v", err)
+		return 0, fmt.Errorf("failed to store webhook")
+	}
+	return result.LastInsertId()
+}
+
+func (s *WebhookServiceImpl) RemoveWebhook(id int64) error {
+	result, err := s.db.Exec("DELETE FROM webhooks WHERE id = ?", id)
+	if err != nil {
+		Logger.Error("failed to delete webhook %d: %v", id, err)
+		return fmt.Errorf("failed to delete webhook")
+	}
+	if affectedRows, _ := result.RowsAffected(); affectedRows == 0 {
+		return newStackError(ErrNotFound, "", "webhook %d does not exist", id)
+	}
+	if _, err = s.db.Exec("DELETE FROM webhook_deliveries WHERE webhook_id = ?", id); err != nil {
+		Logger.Warn("failed to delete deliveries of webhook %d", id)
+	}
+	return nil
+}
+
+func (s *WebhookServiceImpl) GetWebhooks() ([]Webhook, error) {
+	rows, err := s.db.Query("SELECT id, url, encrypted_secret, events, created_at FROM webhooks ORDER BY id")
+	if err != nil {
+		Logger.Error("failed to query webhooks: %v", err)
+		return nil, fmt.Errorf("failed to read webhooks")
+	}
+	defer rows.Close()
+
+	webhooks := make([]Webhook, 0)
+	for rows.Next() {
+		var webhook Webhook
+		var encryptedSecret, events string
+		var createdAt int64
+		if err := rows.Scan(&webhook.Id, &webhook.Url, &encryptedSecret, &events, &createdAt); err != nil {
+			return nil, err
+		}
+		if webhook.Secret, err = s.secretService.decrypt(encryptedSecret); err != nil {
+			Logger.Error("failed to decrypt secret of webhook %d: %v", webhook.Id, err)
+			return nil, fmt.Errorf("failed to decrypt webhook secret")
+		}
+		if events != "" {
+			webhook.Events = strings.Split(events, ",")
+		}
+		webhook.CreatedAt = time.Unix(createdAt, 0)
+		webhooks = append(webhooks, webhook)
+	}
+	return webhooks, rows.Err()
+}
+
+// AddDelivery records a delivery attempt. Only the latest WebhookDeliveryLogSize attempts per webhook are kept.
+func (s *WebhookServiceImpl) AddDelivery(delivery WebhookDelivery) error {
+	_, err := s.db.Exec("INSERT INTO webhook_deliveries (webhook_id, delivery_id, event, attempt, status_code, error, is_successful, timestamp) VALUES (?, ?, ?, ?, ?, ?, ?, ?)",
+		delivery.WebhookId, delivery.DeliveryId, delivery.Event, delivery.Attempt, delivery.StatusCode, delivery.Error, delivery.IsSuccessful, delivery.Timestamp.Unix())
+	if err != nil {
+		Logger.Error("failed to store delivery of webhook %d: %v", delivery.WebhookId, err)
+		return fmt.Errorf("failed to store webhook delivery")
+	}
+	_, err = s.db.Exec("DELETE FROM webhook_deliveries WHERE webhook_id = ? AND id NOT IN (SELECT id FROM webhook_deliveries WHERE webhook_id = ? ORDER BY id DESC LIMIT ?)",
+		delivery.WebhookId, delivery.WebhookId, WebhookDeliveryLogSize)
+	if err != nil {
+		Logger.Warn("failed to prune deliveries of webhook %d", delivery.WebhookId)
+	}
+	return nil
+}
+
+// GetDeliveries returns the delivery attempts of a webhook, the newest one first.
+func (s *WebhookServiceImpl) GetDeliveries(webhookId int64) ([]WebhookDelivery, error) {
+	rows, err := s.db.Query("SELECT id, delivery_id, event, attempt, status_code, error, is_successful, timestamp FROM webhook_deliveries WHERE webhook_id = ? ORDER BY id DESC", webhookId)
+	if err != nil {
+		Logger.Error("failed to query deliveries of webhook %d: %v", webhookId, err)
+		return nil, fmt.Errorf("failed to read webhook deliveries")
+	}
+	defer rows.Close()
+
+	deliveries := make([]WebhookDelivery, 0)
+	for rows.Next() {
+		delivery := WebhookDelivery{WebhookId: webhookId}
+		var timestamp int64
+		if err := rows.Scan(&delivery.Id, &delivery.DeliveryId, &delivery.Event, &delivery.Attempt, &delivery.StatusCode, &delivery.Error, &delivery.IsSuccessful, &timestamp); err != nil {
+			return nil, err
+		}
+		delivery.Timestamp = time.Unix(timestamp, 0)
+		deliveries = append(deliveries, delivery)
+	}
+	return deliveries, rows.Err()
+}
@@ -0,0 +1,61 @@
+package internal
+
+import (
+	"github.com/ocelot-cloud/shared/assert"
+	"testing"
+	"time"
+)
+
+func TestWebhookService(t *testing.T) {
+	database := ProvideDatabase(t.TempDir())
+	defer database.Close()
+	webhookService := ProvideWebhookService(database, ProvideSecretService(database, t.TempDir()))
+
+	id, err := webhookService.AddWebhook(Webhook{Url: "https://example.org/hook", Secret: "secret", Events: []string{EventBackupFailed, EventDeployFailed}, CreatedAt: time.Now()})
+	assert.Nil(t, err)
+	_, err = webhookService.AddWebhook(Webhook{Url: "https://example.org/all", Secret: "other", CreatedAt: time.Now()})
+	assert.Nil(t, err)
+
+	webhooks, err := webhookService.GetWebhooks()
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(webhooks))
+	assert.Equal(t, "secret", webhooks[0].Secret)
+	assert.Equal(t, []string{EventBackupFailed, EventDeployFailed}, webhooks[0].Events)
+	assert.Equal(t, 0, len(webhooks[1].Events))
+
+	var encryptedSecret string
+	assert.Nil(t, database.QueryRow("SELECT encrypted_secret FROM webhooks WHERE id = ?", id).Scan(&encryptedSecret))
+	assert.NotEqual(t, "secret", encryptedSecret)
+
+	assert.Nil(t, webhookService.RemoveWebhook(id))
+	assert.NotNil(t, webhookService.RemoveWebhook(id))
+	webhooks, err = webhookService.GetWebhooks()
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(webhooks))
+}
+
+func TestWebhookDeliveryLogIsLimited(t *testing.T) {
+	originalLogSize := WebhookDeliveryLogSize
+	WebhookDeliveryLogSize = 3
+	t.Cleanup(func() { WebhookDeliveryLogSize = originalLogSize })
+	database := ProvideDatabase(t.TempDir())
+	defer database.Close()
+	webhookService := ProvideWebhookService(database, ProvideSecretService(database, t.TempDir()))
+
+	for attempt := 1; attempt <= 5; attempt++ {
+		assert.Nil(t, webhookService.AddDelivery(WebhookDelivery{WebhookId: 1, DeliveryId: "a", Event: EventDeployFailed, Attempt: attempt, StatusCode: 500, IsSuccessful: attempt == 5, Timestamp: time.Now()}))
+	}
+	assert.Nil(t, webhookService.AddDelivery(WebhookDelivery{WebhookId: 2, DeliveryId: "b", Event: EventDeployFailed, Attempt: 1, Timestamp: time.Now()}))
+
+	deliveries, err := webhookService.GetDeliveries(1)
+	assert.Nil(t, err)
+	assert.Equal(t, 3, len(deliveries))
+	assert.Equal(t, 5, deliveries[0].Attempt)
+	assert.True(t, deliveries[0].IsSuccessful)
+	assert.False(t, deliveries[1].IsSuccessful)
+	assert.Equal(t, 3, deliveries[2].Attempt)
+
+	otherDeliveries, err := webhookService.GetDeliveries(2)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(otherDeliveries))
+}
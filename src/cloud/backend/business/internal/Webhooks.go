@@ -0,0 +1,210 @@
+package internal
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"ocelot/backend/config"
+	"time"
+)
+
+const webhookSignatureHeader = "X-Ocelot-Signature"
+
+// Webhook is an URL which is called for the events it subscribed to. An empty list of events subscribes to all
+// events. The payload is signed with the secret, so that the receiver can verify that it was sent by Ocelot.
+type Webhook struct {
+	Id        int64
+	Url       string
+	Secret    string
+	Events    []string
+	CreatedAt time.Time
+}
+
+func (w Webhook) isSubscribedTo(eventType string) bool {
+	return len(w.Events) == 0 || contains(w.Events, eventType)
+}
+
+// WebhookDelivery records an attempt to deliver an event. All attempts of the same event share the delivery id.
+type WebhookDelivery struct {
+	Id           int64
+	WebhookId    int64
+	DeliveryId   string
+	Event        string
+	Attempt      int
+	StatusCode   int
+	Error        string
+	IsSuccessful bool
+	Timestamp    time.Time
+}
+
+// WebhookDispatcher delivers the events to the subscribed webhooks in the background. Failed deliveries are
+// retried up to maxAttempts times, the delay between the attempts doubles each time.
+type WebhookDispatcher struct {
+	webhookService WebhookService
+	client         *http.Client
+	maxAttempts    int
+	initialBackoff time.Duration
+}
+
+func ProvideWebhookDispatcher(webhookService WebhookService) *WebhookDispatcher {
+	return &WebhookDispatcher{webhookService: webhookService, client: &http.Client{Timeout: WebhookTimeout}, maxAttempts: WebhookMaxAttempts, initialBackoff: WebhookInitialBackoff}
+}
+
+func (d *WebhookDispatcher) Dispatch(event StackEvent) {
+	webhooks, err := d.webhookService.GetWebhooks()
+	if err != nil {
+		Logger.Warn("event '%s' is not delivered, since the webhooks could not be read", event.Type)
+		return
+	}
+	for _, webhook := range webhooks {
+		if webhook.isSubscribedTo(event.Type) {
+			go d.deliver(webhook, event)
+		}
+	}
+}
+
+func (d *WebhookDispatcher) deliver(webhook Webhook, event StackEvent) {
+	deliveryId, err := generateDeliveryId()
+	if err != nil {
+		Logger.Error("failed to generate delivery id: %v", err)
+		return
+	}
+	payload, err := json.Marshal(tools.WebhookPayloadDto{Id: deliveryId, Event: event.Type, Stack: event.Stack, Message: event.Message, Details: event.Details, Timestamp: event.Timestamp})
+	if err != nil {
+		Logger.Error("failed to encode event '%s': %v", event.Type, err)
+		return
+	}
+
+	backoff := d.initialBackoff
+	for attempt := 1; attempt <= d.maxAttempts; attempt++ {
+		delivery := d.send(webhook, event.Type, deliveryId, payload)
+		delivery.Attempt = attempt
+		if err = d.webhookService.AddDelivery(delivery); err != nil {
+			Logger.Warn("delivery of event '%s' to webhook %d could not be recorded", event.Type, webhook.Id)
+		}
+		if delivery.IsSuccessful {
+			return
+		} else if !isRetryable(delivery) {
+			break
+		}
+		if attempt < d.maxAttempts {
+			time.Sleep(backoff)
+			backoff = min(backoff*2, WebhookMaxBackoff)
+		}
+	}
+	webhookDeliveryFailures.WithLabelValues(event.Type).Inc()
+	Logger.Warn("giving up to deliver event '%s' to webhook %d", event.Type, webhook.Id)
+}
+
+func (d *WebhookDispatcher) send(webhook Webhook, eventType string, deliveryId string, payload []byte) WebhookDelivery {
+	delivery := WebhookDelivery{WebhookId: webhook.Id, DeliveryId: deliveryId, Event: eventType, Timestamp: time.Now()}
+	request, err := http.NewRequest("POST", webhook.Url, bytes.NewReader(payload))
+	if err != nil {
+		delivery.Error = err.Error()
+		return delivery
+	}
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("User-Agent", "Ocelot-Webhook")
+	request.Header.Set("X-Ocelot-Event", eventType)
+	request.Header.Set("X-Ocelot-Delivery", deliveryId)
+	request.Header.Set(webhookSignatureHeader, signWebhookPayload(webhook.Secret, payload))
+
+	response, err := d.client.Do(request)
+	if err != nil {
+		delivery.Error = err.Error()
+		return delivery
+	}
+	response.Body.Close()
+	delivery.StatusCode = response.StatusCode
+	delivery.IsSuccessful = response.StatusCode >= 200 && response.StatusCode < 300
+	if !delivery.IsSuccessful {
+		delivery.Error = "receiver answered with status " + response.Status
+	}
+	return delivery
+}
+
+// isRetryable returns false for client errors, since the receiver would reject the same payload again. Timeouts
+// and rate limits are retried nevertheless.
+func isRetryable(delivery WebhookDelivery) bool {
+	statusCode := delivery.StatusCode
+	return statusCode == 0 || statusCode >= 500 || statusCode == http.StatusRequestTimeout || statusCode == http.StatusTooManyRequests
+}
+
+// signWebhookPayload returns the HMAC-SHA256 of the payload in the format "sha256=<hex>", like GitHub does.
+func signWebhookPayload(secret string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func generateDeliveryId() (string, error) {
+	randomBytes := make([]byte, 16)
+	if _, err := rand.Read(randomBytes); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(randomBytes), nil
+}
+
+// CreateWebhook registers a webhook. If no secret is given, one is generated.
+func (sm *StackServiceImpl) CreateWebhook(webhookUrl string, secret string, events []string) (Webhook, error) {
+	parsedUrl, err := url.Parse(webhookUrl)
+	if err != nil || (parsedUrl.Scheme != "http" && parsedUrl.Scheme != "https") || parsedUrl.Host == "" {
+		return Webhook{}, newStackError(ErrInvalidRequest, "", "invalid webhook URL '%s', only absolute http and https URLs are allowed", webhookUrl)
+	}
+	for _, event := range events {
+		if !contains(StackEventTypes, event) {
+			return Webhook{}, newStackError(ErrInvalidRequest, "", "unknown event '%s'", event)
+		}
+	}
+	if secret == "" {
+		randomBytes := make([]byte, 32)
+		if _, err = rand.Read(randomBytes); err != nil {
+			return Webhook{}, err
+		}
+		secret = hex.EncodeToString(randomBytes)
+	}
+
+	webhook := Webhook{Url: webhookUrl, Secret: secret, Events: events, CreatedAt: time.Now().Truncate(time.Second)}
+	if webhook.Id, err = sm.WebhookService.AddWebhook(webhook); err != nil {
+		return Webhook{}, err
+	}
+	Logger.Info("Webhook %d for '%s' was created", webhook.Id, parsedUrl.Host)
+	return webhook, nil
+}
+
+func (sm *StackServiceImpl) GetWebhooks() ([]Webhook, error) {
+	return sm.WebhookService.GetWebhooks()
+}
+
+func (sm *StackServiceImpl) DeleteWebhook(id int64) error {
+	if err := sm.WebhookService.RemoveWebhook(id); err != nil {
+		return err
+	}
+	Logger.Info("Webhook %d was deleted", id)
+	return nil
+}
+
+func (sm *StackServiceImpl) GetWebhookDeliveries(webhookId int64) ([]WebhookDelivery, error) {
+	if _, err := sm.getWebhook(webhookId); err != nil {
+		return nil, err
+	}
+	return sm.WebhookService.GetDeliveries(webhookId)
+}
+
+func (sm *StackServiceImpl) getWebhook(id int64) (Webhook, error) {
+	webhooks, err := sm.WebhookService.GetWebhooks()
+	if err != nil {
+		return Webhook{}, err
+	}
+	for _, webhook := range webhooks {
+		if webhook.Id == id {
+			return webhook, nil
+		}
+	}
+	return Webhook{}, newStackError(ErrNotFound, "", "webhook %d does not exist", id)
+}
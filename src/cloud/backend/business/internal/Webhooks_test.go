@@ -0,0 +1,139 @@
+package internal
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/ocelot-cloud/shared/assert"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"ocelot/backend/config"
+	"sync"
+	"testing"
+	"time"
+)
+
+// webhookReceiver records the requests to a webhook and answers with the given status codes one after another.
+type webhookReceiver struct {
+	mu          sync.Mutex
+	statusCodes []int
+	requests    []*http.Request
+	bodies      [][]byte
+}
+
+func (r *webhookReceiver) ServeHTTP(w http.ResponseWriter, request *http.Request) {
+	body, _ := io.ReadAll(request.Body)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.requests = append(r.requests, request)
+	r.bodies = append(r.bodies, body)
+	status := http.StatusOK
+	if len(r.statusCodes) > 0 {
+		status, r.statusCodes = r.statusCodes[0], r.statusCodes[1:]
+	}
+	w.WriteHeader(status)
+}
+
+func createWebhookDispatcher(t *testing.T, receiver *webhookReceiver, events []string) (*WebhookDispatcher, WebhookService, int64) {
+	server := httptest.NewServer(receiver)
+	t.Cleanup(server.Close)
+	webhookService := ProvideInMemoryStackStores().WebhookService
+	id, err := webhookService.AddWebhook(Webhook{Url: server.URL, Secret: "secret", Events: events})
+	assert.Nil(t, err)
+	dispatcher := &WebhookDispatcher{webhookService: webhookService, client: server.Client(), maxAttempts: 3, initialBackoff: time.Millisecond}
+	return dispatcher, webhookService, id
+}
+
+func waitForDeliveries(t *testing.T, webhookService WebhookService, webhookId int64, count int) []WebhookDelivery {
+	for i := 0; i < 100; i++ {
+		deliveries, err := webhookService.GetDeliveries(webhookId)
+		assert.Nil(t, err)
+		if len(deliveries) >= count {
+			return deliveries
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.Fail(t, "webhook deliveries were not recorded in time")
+	return nil
+}
+
+func TestWebhookPayloadIsSigned(t *testing.T) {
+	receiver := &webhookReceiver{}
+	dispatcher, webhookService, id := createWebhookDispatcher(t, receiver, nil)
+
+	dispatcher.Dispatch(StackEvent{Type: EventBackupSucceeded, Stack: tools.NginxDefault, Message: "backup created", Timestamp: time.Now()})
+	deliveries := waitForDeliveries(t, webhookService, id, 1)
+	assert.True(t, deliveries[0].IsSuccessful)
+	assert.Equal(t, 1, deliveries[0].Attempt)
+
+	receiver.mu.Lock()
+	defer receiver.mu.Unlock()
+	request, body := receiver.requests[0], receiver.bodies[0]
+	assert.Equal(t, signWebhookPayload("secret", body), request.Header.Get(webhookSignatureHeader))
+	assert.NotEqual(t, signWebhookPayload("other", body), request.Header.Get(webhookSignatureHeader))
+	assert.Equal(t, EventBackupSucceeded, request.Header.Get("X-Ocelot-Event"))
+	var payload tools.WebhookPayloadDto
+	assert.Nil(t, json.Unmarshal(body, &payload))
+	assert.Equal(t, deliveries[0].DeliveryId, payload.Id)
+	assert.Equal(t, request.Header.Get("X-Ocelot-Delivery"), payload.Id)
+	assert.Equal(t, tools.NginxDefault, payload.Stack)
+}
+
+func TestWebhookDeliveryIsRetriedAfterServerError(t *testing.T) {
+	receiver := &webhookReceiver{statusCodes: []int{http.StatusInternalServerError, http.StatusServiceUnavailable}}
+	dispatcher, webhookService, id := createWebhookDispatcher(t, receiver, nil)
+
+	dispatcher.Dispatch(StackEvent{Type: EventDeployFailed, Stack: tools.NginxDefault})
+	deliveries := waitForDeliveries(t, webhookService, id, 3)
+	assert.Equal(t, 3, len(deliveries))
+	assert.True(t, deliveries[0].IsSuccessful)
+	assert.Equal(t, 3, deliveries[0].Attempt)
+	assert.False(t, deliveries[2].IsSuccessful)
+	assert.Equal(t, http.StatusInternalServerError, deliveries[2].StatusCode)
+	assert.Equal(t, deliveries[0].DeliveryId, deliveries[2].DeliveryId)
+}
+
+func TestWebhookDeliveryIsNotRetriedAfterClientError(t *testing.T) {
+	receiver := &webhookReceiver{statusCodes: []int{http.StatusBadRequest}}
+	dispatcher, webhookService, id := createWebhookDispatcher(t, receiver, nil)
+
+	dispatcher.Dispatch(StackEvent{Type: EventDeployFailed, Stack: tools.NginxDefault})
+	waitForDeliveries(t, webhookService, id, 1)
+	time.Sleep(50 * time.Millisecond)
+	deliveries, err := webhookService.GetDeliveries(id)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(deliveries))
+	assert.Equal(t, http.StatusBadRequest, deliveries[0].StatusCode)
+}
+
+func TestWebhookReceivesOnlySubscribedEvents(t *testing.T) {
+	receiver := &webhookReceiver{}
+	dispatcher, webhookService, id := createWebhookDispatcher(t, receiver, []string{EventBackupFailed})
+
+	dispatcher.Dispatch(StackEvent{Type: EventBackupSucceeded, Stack: tools.NginxDefault})
+	dispatcher.Dispatch(StackEvent{Type: EventBackupFailed, Stack: tools.NginxDefault})
+	deliveries := waitForDeliveries(t, webhookService, id, 1)
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, 1, len(deliveries))
+	assert.Equal(t, EventBackupFailed, deliveries[0].Event)
+}
+
+func TestCreateWebhookValidatesRequest(t *testing.T) {
+	stackService := createStackService()
+
+	_, err := stackService.CreateWebhook("ftp://example.org/hook", "", nil)
+	assert.True(t, errors.Is(err, ErrInvalidRequest))
+	_, err = stackService.CreateWebhook("https://example.org/hook", "", []string{"unknown"})
+	assert.True(t, errors.Is(err, ErrInvalidRequest))
+
+	webhook, err := stackService.CreateWebhook("https://example.org/hook", "", []string{EventLoginFailed})
+	assert.Nil(t, err)
+	assert.Equal(t, 64, len(webhook.Secret))
+	webhooks, err := stackService.GetWebhooks()
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(webhooks))
+
+	assert.Nil(t, stackService.DeleteWebhook(webhook.Id))
+	_, err = stackService.GetWebhookDeliveries(webhook.Id)
+	assert.True(t, errors.Is(err, ErrNotFound))
+}
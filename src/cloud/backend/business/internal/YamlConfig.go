@@ -7,8 +7,76 @@ import (
 )
 
 type StackConfig struct {
-	UrlPath string `yaml:"urlPath"`
-	Port    string `yaml:"port"`
+	UrlPath   string         `yaml:"urlPath"`
+	Port      string         `yaml:"port"`
+	Secrets   []SecretConfig `yaml:"secrets"`
+	Limits    LimitConfig    `yaml:"limits"`
+	Access    AccessConfig   `yaml:"access"`
+	Resources ResourceConfig `yaml:"resources"`
+	// DependsOn lists the stacks which have to be available before this stack is deployed.
+	DependsOn []string `yaml:"dependsOn"`
+}
+
+// ResourceConfig restricts the CPU and memory of each service of a stack. Memory sizes are given like in
+// compose files, e.g. "512m" or "2g". Reservations are guaranteed to the services and therefore have to fit
+// on the host together with the reservations of the other running stacks.
+type ResourceConfig struct {
+	Cpus              float64 `yaml:"cpus"`
+	Memory            string  `yaml:"memory"`
+	CpuReservation    float64 `yaml:"cpuReservation"`
+	MemoryReservation string  `yaml:"memoryReservation"`
+}
+
+// AccessConfig restricts the clients allowed to access an app by IP addresses or CIDR ranges, e.g.
+// "10.0.0.0/8". If an allow list is given, only the listed clients are allowed.
+type AccessConfig struct {
+	Allow []string `yaml:"allow"`
+	Deny  []string `yaml:"deny"`
+}
+
+// LimitConfig protects an app against overload. Values which are not set fall back to the limits configured via CLI,
+// negative values disable the respective limit.
+type LimitConfig struct {
+	RequestsPerSecond       float64 `yaml:"requestsPerSecond"`
+	Burst                   int     `yaml:"burst"`
+	ClientRequestsPerSecond float64 `yaml:"clientRequestsPerSecond"`
+	ClientBurst             int     `yaml:"clientBurst"`
+	MaxRequestBodyBytes     int64   `yaml:"maxRequestBodyBytes"`
+	MaxConcurrentRequests   int     `yaml:"maxConcurrentRequests"`
+}
+
+func (l LimitConfig) withDefaults(defaults LimitConfig) LimitConfig {
+	if l.RequestsPerSecond == 0 {
+		l.RequestsPerSecond = defaults.RequestsPerSecond
+	}
+	if l.Burst == 0 {
+		l.Burst = defaults.Burst
+	}
+	if l.ClientRequestsPerSecond == 0 {
+		l.ClientRequestsPerSecond = defaults.ClientRequestsPerSecond
+	}
+	if l.ClientBurst == 0 {
+		l.ClientBurst = defaults.ClientBurst
+	}
+	if l.MaxRequestBodyBytes == 0 {
+		l.MaxRequestBodyBytes = defaults.MaxRequestBodyBytes
+	}
+	if l.MaxConcurrentRequests == 0 {
+		l.MaxConcurrentRequests = defaults.MaxConcurrentRequests
+	}
+	return l
+}
+
+// SecretConfig declares a secret, which is generated on the first installation of a stack and provided
+// as environment variable to docker compose, so that it can be used in the compose file, e.g. "${DB_PASSWORD}".
+// Legacy is the value which was hardcoded in the compose file before the secret was introduced. It is kept for
+// stacks whose volumes already exist, since the apps initialized their data with it.
+type SecretConfig struct {
+	Name       string `yaml:"name"`
+	Length     int    `yaml:"length"`
+	Charset    string `yaml:"charset"`
+	Characters string `yaml:"characters"`
+	Legacy     string `yaml:"legacy"`
 }
 
 type StackConfigServiceImpl struct {
@@ -20,7 +88,7 @@ func (s *StackConfigServiceImpl) GetStackConfig(stackName string) StackConfig {
 		return stackConfig
 	}
 	Logger.Error("error: StackConfig not found for '%s'", stackName)
-	return StackConfig{"/", "80"}
+	return StackConfig{UrlPath: "/", Port: "80"}
 }
 
 func ProvideStackConfigService(stackDir string) StackConfigService {
@@ -38,6 +106,9 @@ func ProvideStackConfigService(stackDir string) StackConfigService {
 		stackConfigFilePath := filepath.Join(stackDir, file.Name(), "app.yml")
 		stackConfigs[file.Name()] = loadConfig(stackConfigFilePath)
 	}
+	if err := validateStackDependencies(stackConfigs); err != nil {
+		Logger.Fatal("error in stack dependencies of %s: %v", stackDir, err)
+	}
 	return &StackConfigServiceImpl{stackConfigs: stackConfigs}
 }
 
@@ -55,5 +126,11 @@ func loadConfig(configPath string) StackConfig {
 	if err := yaml.Unmarshal(fileContent, &config); err != nil {
 		Logger.Fatal("error when unmarshalling %s: %w", configPath, err)
 	}
+	if _, err := parseIpFilter(config.Access); err != nil {
+		Logger.Fatal("error in access configuration of %s: %v", configPath, err)
+	}
+	if err := config.Resources.validate(); err != nil {
+		Logger.Fatal("error in resource configuration of %s: %v", configPath, err)
+	}
 	return config
 }
@@ -15,6 +15,7 @@ import (
 var logger = shared.ProvideLogger()
 
 const endpoint = "http://localhost:8080/api/stacks/"
+const endpointV1 = "http://localhost:8080/api/v1/"
 const stackOneName = tools.NginxDefault
 const stackTwoName = tools.NginxDefault2
 
@@ -76,11 +77,11 @@ func postJSON(t *testing.T, endpoint string, stackName string) *http.Response {
 }
 
 func TestDeployStackNotExisting(t *testing.T) {
-	postStackAndCheckResponse(t, "deploy", http.StatusInternalServerError)
+	postStackAndCheckResponse(t, "deploy", http.StatusNotFound)
 }
 
 func TestStopStackNotExisting(t *testing.T) {
-	postStackAndCheckResponse(t, "stop", http.StatusInternalServerError)
+	postStackAndCheckResponse(t, "stop", http.StatusNotFound)
 }
 
 func postStackAndCheckResponse(t *testing.T, action string, expectedHttpStatus int) {
@@ -89,7 +90,11 @@ func postStackAndCheckResponse(t *testing.T, action string, expectedHttpStatus i
 	assert.Nil(t, err)
 	resp, err := http.Post(endpoint+action, "application/json", bytes.NewBuffer(jsonData))
 	assert.Nil(t, err)
+	defer resp.Body.Close()
 	assert.Equal(t, expectedHttpStatus, resp.StatusCode)
+	var apiError tools.ErrorDto
+	assert.Nil(t, json.NewDecoder(resp.Body).Decode(&apiError))
+	assert.NotEqual(t, "", apiError.Code)
 }
 
 func TestAbsenceOfCorsPolicyDisablingHeadersInResponse(t *testing.T) {
@@ -186,3 +191,198 @@ func dontExecuteTestForProfile(t *testing.T, profileDisablingTheTest string) {
 		t.Skip()
 	}
 }
+
+// requestV1 sends a request to the versioned API, the body is encoded as JSON if given.
+func requestV1(t *testing.T, method string, path string, body any) *http.Response {
+	var jsonData []byte
+	if body != nil {
+		var err error
+		jsonData, err = json.Marshal(body)
+		assert.Nil(t, err)
+	}
+	request, err := http.NewRequest(method, endpointV1+path, bytes.NewBuffer(jsonData))
+	assert.Nil(t, err)
+	request.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(request)
+	assert.Nil(t, err)
+	return resp
+}
+
+func TestSecretsAreGeneratedOnDeployment(t *testing.T) {
+	postJSON(t, endpoint+"deploy", tools.NginxCustomPort)
+	resp := requestV1(t, http.MethodGet, "stacks/"+tools.NginxCustomPort+"/secrets", nil)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var secrets []tools.StackSecretDto
+	assert.Nil(t, json.NewDecoder(resp.Body).Decode(&secrets))
+	assert.Equal(t, 1, len(secrets))
+	assert.Equal(t, "SECRET_TOKEN", secrets[0].Name)
+	assert.Equal(t, 24, len(secrets[0].Value))
+}
+
+func TestCreateAndDeleteStackInstance(t *testing.T) {
+	instance := tools.StackInstanceDto{Name: "nginx-default-team-a", Template: tools.NginxDefault}
+	requestV1(t, http.MethodDelete, "instances/"+instance.Name, nil).Body.Close()
+
+	resp := requestV1(t, http.MethodPost, "instances", instance)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusCreated, resp.StatusCode)
+	assertState(t, getAndRead(t, endpointV1+"stacks"), instance.Name, "Uninitialized")
+
+	resp = requestV1(t, http.MethodDelete, "instances/"+instance.Name, nil)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+	resp = requestV1(t, http.MethodGet, "instances", nil)
+	defer resp.Body.Close()
+	var instances []tools.StackInstanceDto
+	assert.Nil(t, json.NewDecoder(resp.Body).Decode(&instances))
+	for _, existingInstance := range instances {
+		assert.NotEqual(t, instance.Name, existingInstance.Name)
+	}
+}
+
+func TestAddAndRemoveCustomDomain(t *testing.T) {
+	domain := tools.StackDomainDto{Domain: "nginx.example.org", Stack: stackOneName}
+	requestV1(t, http.MethodDelete, "domains/"+domain.Domain, nil).Body.Close()
+
+	resp := requestV1(t, http.MethodPost, "domains", domain)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusCreated, resp.StatusCode)
+
+	resp = requestV1(t, http.MethodGet, "domains", nil)
+	defer resp.Body.Close()
+	var domains []tools.StackDomainDto
+	assert.Nil(t, json.NewDecoder(resp.Body).Decode(&domains))
+	assert.True(t, containsDomain(domains, domain))
+
+	resp = requestV1(t, http.MethodDelete, "domains/"+domain.Domain, nil)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+}
+
+func containsDomain(domains []tools.StackDomainDto, domain tools.StackDomainDto) bool {
+	for _, existingDomain := range domains {
+		if existingDomain == domain {
+			return true
+		}
+	}
+	return false
+}
+
+func TestUnknownHostIsAnsweredWithNotFound(t *testing.T) {
+	request, err := http.NewRequest(http.MethodGet, "http://localhost:8080/", nil)
+	assert.Nil(t, err)
+	request.Host = "not-existing-stack.localhost"
+	resp, err := http.DefaultClient.Do(request)
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
+
+func TestStatsOfStack(t *testing.T) {
+	resp := requestV1(t, http.MethodGet, "stacks/"+stackOneName+"/stats", nil)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	var stats tools.StackStatsDto
+	assert.Nil(t, json.NewDecoder(resp.Body).Decode(&stats))
+	assert.Equal(t, stackOneName, stats.Stack)
+
+	resp = requestV1(t, http.MethodGet, "stacks/not-existing-stack/stats", nil)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
+
+func TestSystemInfo(t *testing.T) {
+	resp := requestV1(t, http.MethodGet, "system", nil)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	var info tools.SystemInfoDto
+	assert.Nil(t, json.NewDecoder(resp.Body).Decode(&info))
+	assert.True(t, len(info.Disks) > 0)
+	assert.NotEqual(t, "", info.Docker.Version)
+}
+
+func TestBulkOperationReportsResultPerStack(t *testing.T) {
+	resp := requestV1(t, http.MethodPost, "bulk/deploy", tools.BulkOperationDto{Stacks: []string{stackOneName, "not-existing-stack"}})
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var results []tools.BulkOperationResultDto
+	assert.Nil(t, json.NewDecoder(resp.Body).Decode(&results))
+	assert.Equal(t, []tools.BulkOperationResultDto{{Stack: "not-existing-stack", Success: false, Error: "Could not find stack: not-existing-stack"}, {Stack: stackOneName, Success: true}}, results)
+
+	postJSON(t, endpoint+"stop", stackOneName)
+}
+
+func TestRestartAndRecreateStack(t *testing.T) {
+	resp := requestV1(t, http.MethodPost, "stacks/not-existing-stack/restart", nil)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	postJSON(t, endpoint+"deploy", stackOneName)
+	assertWithinLongerTimeRangeThatStackStateBecomesExpectedState(t, stackOneName, "Available")
+	for _, action := range []string{"restart", "recreate"} {
+		resp = requestV1(t, http.MethodPost, "stacks/"+stackOneName+"/"+action, nil)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+		assertWithinLongerTimeRangeThatStackStateBecomesExpectedState(t, stackOneName, "Available")
+	}
+	assertWithinLongerTimeRangeThatStackStateBecomesExpectedState(t, stackOneName, "Available")
+	postJSON(t, endpoint+"stop", stackOneName)
+}
+
+func TestVersionedApiDeploysAndStopsStack(t *testing.T) {
+	resp, err := http.Post(endpointV1+"stacks/"+stackOneName+"/deploy", "application/json", nil)
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+
+	resp, err = http.Get(endpointV1 + "stacks/" + stackOneName)
+	assert.Nil(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	var detail tools.StackDetailDto
+	assert.Nil(t, json.NewDecoder(resp.Body).Decode(&detail))
+	assert.Equal(t, stackOneName, detail.Name)
+
+	resp, err = http.Post(endpointV1+"stacks/"+stackOneName+"/stop", "application/json", nil)
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+}
+
+func TestVersionedApiAnswersWithJsonErrors(t *testing.T) {
+	resp, err := http.Post(endpointV1+"stacks/not-existing-stack/deploy", "application/json", nil)
+	assert.Nil(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	var apiError tools.ErrorDto
+	assert.Nil(t, json.NewDecoder(resp.Body).Decode(&apiError))
+	assert.Equal(t, "not_found", apiError.Code)
+}
+
+func TestOpenApiDocumentIsServed(t *testing.T) {
+	resp, err := http.Get(endpointV1 + "openapi.json")
+	assert.Nil(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	var document map[string]any
+	assert.Nil(t, json.NewDecoder(resp.Body).Decode(&document))
+	assert.Equal(t, "3.0.3", document["openapi"])
+}
+
+func TestStoppingStackWhichIsNotRunningIsAConflict(t *testing.T) {
+	resp, err := http.Post(endpointV1+"stacks/"+stackTwoName+"/stop", "application/json", nil)
+	assert.Nil(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusConflict, resp.StatusCode)
+	var apiError tools.ErrorDto
+	assert.Nil(t, json.NewDecoder(resp.Body).Decode(&apiError))
+	assert.Equal(t, "invalid_state", apiError.Code)
+}
+
+func TestOldEndpointsAreMarkedAsDeprecated(t *testing.T) {
+	resp, err := http.Get(endpoint + "read")
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "true", resp.Header.Get("Deprecation"))
+	assert.Equal(t, `</api/v1/stacks>; rel="successor-version"`, resp.Header.Get("Link"))
+}
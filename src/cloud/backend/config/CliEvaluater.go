@@ -45,6 +45,38 @@ func GenerateGlobalConfiguration() *GlobalConfig {
 	var useDummyStacksCliArgument bool
 	flag.BoolVar(&useDummyStacksCliArgument, "enable-dummy-stacks", false, "disable security, such as authentication via OIDC")
 
+	var routingModeString string
+	flag.StringVar(&routingModeString, "routing-mode", RoutingModeSubdomain, "how requests are routed to the apps. Possible values: "+RoutingModeSubdomain+", "+RoutingModePath)
+
+	var dashboardAllowList, dashboardDenyList, trustedProxies string
+	flag.StringVar(&dashboardAllowList, "dashboard-allowlist", "", "comma separated IPs or CIDR ranges, which are exclusively allowed to access the dashboard")
+	flag.StringVar(&dashboardDenyList, "dashboard-denylist", "", "comma separated IPs or CIDR ranges, which are denied access to the dashboard")
+	flag.StringVar(&trustedProxies, "trusted-proxies", "", "comma separated IPs or CIDR ranges of proxies in front of ocelot, whose X-Forwarded-For header is trusted")
+
+	var isAccessLogPrintedToStdout bool
+	flag.BoolVar(&isAccessLogPrintedToStdout, "access-log-stdout", false, "print the access log of the apps as JSON to stdout in addition to the log file")
+
+	var isTlsEnabled bool
+	var tlsPort, acmeEmail string
+	flag.BoolVar(&isTlsEnabled, "tls", false, "serve HTTPS with certificates from Let's Encrypt for the dashboard, the subdomains of the apps and their custom domains, HTTP requests are redirected to HTTPS")
+	flag.StringVar(&tlsPort, "tls-port", "8443", "port on which HTTPS is served, port 443 of the host has to be forwarded to it")
+	flag.StringVar(&acmeEmail, "acme-email", "", "contact address for the Let's Encrypt account, which is notified about problems with the certificates")
+
+	var smtp SmtpConfig
+	flag.StringVar(&smtp.Host, "smtp-host", "", "host of the SMTP server used to send email alerts, email alerts are disabled if empty")
+	flag.StringVar(&smtp.Port, "smtp-port", "587", "port of the SMTP server")
+	flag.StringVar(&smtp.Username, "smtp-user", "", "user name for the SMTP server, the password is read from the environment variable SMTP_PASSWORD")
+	flag.StringVar(&smtp.From, "smtp-from", "", "sender address of the email alerts")
+	flag.StringVar(&smtp.Security, "smtp-security", SmtpSecurityStartTls, "encryption of the connection to the SMTP server. Possible values: "+SmtpSecurityStartTls+", "+SmtpSecurityTls+", "+SmtpSecurityNone)
+
+	var limits LimitDefaults
+	flag.Float64Var(&limits.RequestsPerSecond, "limit-requests-per-second", 0, "default number of requests per second to an app, disabled if 0")
+	flag.IntVar(&limits.Burst, "limit-burst", 0, "default number of requests to an app, which may exceed the rate in a burst")
+	flag.Float64Var(&limits.ClientRequestsPerSecond, "limit-client-requests-per-second", 0, "default number of requests per second of a single IP to an app, disabled if 0. Behind a proxy, set -trusted-proxies, since all clients share the IP of the proxy otherwise")
+	flag.IntVar(&limits.ClientBurst, "limit-client-burst", 0, "default number of requests of a single IP to an app, which may exceed the rate in a burst")
+	flag.Int64Var(&limits.MaxRequestBodyBytes, "limit-max-request-body-bytes", 0, "default maximum size of request bodies sent to an app, disabled if 0")
+	flag.IntVar(&limits.MaxConcurrentRequests, "limit-max-concurrent-requests", 0, "default maximum number of requests processed by an app at the same time including open WebSocket connections, disabled if 0")
+
 	flag.Parse()
 
 	var backendMode BackendComponentMode
@@ -59,7 +91,67 @@ func GenerateGlobalConfiguration() *GlobalConfig {
 	}
 	var useDummyStacks = shallDummyStacksBeUsed(useDummyStacksCliArgument, backendMode)
 
-	return SetGlobalConfig(backendMode, logLevelStr, !isOidcAuthenticationDisabled, useDummyStacks)
+	config := SetGlobalConfig(backendMode, logLevelStr, !isOidcAuthenticationDisabled, useDummyStacks)
+	config.RoutingMode = EvaluateRoutingMode(routingModeString)
+	if config.RoutingMode == RoutingModePath && config.IsSecurityEnabled {
+		panic("The routing mode '" + RoutingModePath + "' serves the apps from the origin of the dashboard, so that the apps could use the session of the admin. It is therefore only available together with '-disable-security', use the routing mode '" + RoutingModeSubdomain + "' otherwise")
+	}
+	logger.Info("Routing mode is: %s", config.RoutingMode)
+	config.DashboardAllowList = SplitList(dashboardAllowList)
+	config.DashboardDenyList = SplitList(dashboardDenyList)
+	config.TrustedProxies = SplitList(trustedProxies)
+	config.IsAccessLogPrintedToStdout = isAccessLogPrintedToStdout
+	config.MetricsToken = os.Getenv("METRICS_TOKEN")
+	smtp.Security = EvaluateSmtpSecurity(smtp.Security)
+	smtp.Password = os.Getenv("SMTP_PASSWORD")
+	if smtp.Host != "" && smtp.From == "" {
+		panic("The sender address of email alerts is missing, set it with '-smtp-from=alerts@example.org'")
+	}
+	config.Smtp = smtp
+	config.LimitDefaults = limits
+	if isTlsEnabled {
+		config.IsTlsEnabled = true
+		config.Scheme = "https"
+		config.TlsPort = tlsPort
+		config.AcmeEmail = acmeEmail
+		logger.Info("TLS is enabled on port %s", tlsPort)
+	}
+	return config
+}
+
+// SplitList turns a comma separated CLI argument into its trimmed, non-empty elements.
+func SplitList(value string) []string {
+	var elements []string
+	for _, element := range strings.Split(value, ",") {
+		if element = strings.TrimSpace(element); element != "" {
+			elements = append(elements, element)
+		}
+	}
+	return elements
+}
+
+func EvaluateRoutingMode(routingModeStr string) string {
+	switch strings.ToLower(routingModeStr) {
+	case RoutingModeSubdomain:
+		return RoutingModeSubdomain
+	case RoutingModePath:
+		return RoutingModePath
+	default:
+		panic(fmt.Sprintf("Invalid routing mode: %s. Valid values are '-routing-mode=x' with x is one of these values: %s (default), %s", routingModeStr, RoutingModeSubdomain, RoutingModePath))
+	}
+}
+
+func EvaluateSmtpSecurity(smtpSecurityStr string) string {
+	switch strings.ToLower(smtpSecurityStr) {
+	case SmtpSecurityStartTls:
+		return SmtpSecurityStartTls
+	case SmtpSecurityTls:
+		return SmtpSecurityTls
+	case SmtpSecurityNone:
+		return SmtpSecurityNone
+	default:
+		panic(fmt.Sprintf("Invalid SMTP security: %s. Valid values are '-smtp-security=x' with x is one of these values: %s (default), %s, %s", smtpSecurityStr, SmtpSecurityStartTls, SmtpSecurityTls, SmtpSecurityNone))
+	}
 }
 
 func shallDummyStacksBeUsed(useDummyStacksCliArgument bool, backendMode BackendComponentMode) bool {
@@ -101,6 +193,17 @@ func SetGlobalConfig(backendMode BackendComponentMode, logLevelStr string, isOid
 		"http",
 		partialConfig.RootDomain,
 		"8080",
+		RoutingModeSubdomain,
+		nil,
+		nil,
+		nil,
+		false,
+		"",
+		SmtpConfig{},
+		false,
+		"",
+		"",
+		LimitDefaults{},
 	}
 
 	shared.LogLevel = EvaluateLogLevelBasedOn(backendMode, logLevelStr)
@@ -60,3 +60,26 @@ func TestGlobalConfig(t *testing.T) {
 		})
 	}
 }
+
+func TestEvaluateRoutingMode(t *testing.T) {
+	assert.Equal(t, RoutingModeSubdomain, EvaluateRoutingMode("subdomain"))
+	assert.Equal(t, RoutingModePath, EvaluateRoutingMode("Path"))
+	assert.Panics(t, func() {
+		EvaluateRoutingMode("invalid value")
+	})
+	assert.Equal(t, RoutingModeSubdomain, SetGlobalConfig(ProdWithGui, "notSet", false, false).RoutingMode)
+}
+
+func TestEvaluateSmtpSecurity(t *testing.T) {
+	assert.Equal(t, SmtpSecurityStartTls, EvaluateSmtpSecurity("starttls"))
+	assert.Equal(t, SmtpSecurityTls, EvaluateSmtpSecurity("TLS"))
+	assert.Equal(t, SmtpSecurityNone, EvaluateSmtpSecurity("none"))
+	assert.Panics(t, func() {
+		EvaluateSmtpSecurity("ssl")
+	})
+}
+
+func TestSplitList(t *testing.T) {
+	assert.Equal(t, []string{"10.0.0.0/8", "192.168.1.5"}, SplitList(" 10.0.0.0/8, ,192.168.1.5 "))
+	assert.Equal(t, 0, len(SplitList("")))
+}
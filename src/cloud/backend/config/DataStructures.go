@@ -1,11 +1,244 @@
 package tools
 
+import "time"
+
 type ResponsePayloadDto struct {
-	Name    string `json:"name"`
-	State   string `json:"state"`
-	UrlPath string `json:"urlPath"`
+	Name            string `json:"name"`
+	State           string `json:"state"`
+	UrlPath         string `json:"urlPath"`
+	Template        string `json:"template,omitempty"`
+	UpdateAvailable bool   `json:"updateAvailable"`
 }
 
 type StackInfo struct {
 	Name string `json:"name"`
 }
+
+type StackSecretDto struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type StackInstanceDto struct {
+	Name     string `json:"name"`
+	Template string `json:"template"`
+}
+
+type StackVersionDto struct {
+	Version   string    `json:"version"`
+	Status    string    `json:"status"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+type StackUpdateDto struct {
+	Stack         string `json:"stack"`
+	Image         string `json:"image"`
+	CurrentDigest string `json:"currentDigest"`
+	LatestDigest  string `json:"latestDigest,omitempty"`
+	NewerTag      string `json:"newerTag,omitempty"`
+}
+
+type StackDomainDto struct {
+	Domain string `json:"domain"`
+	Stack  string `json:"stack"`
+}
+
+type AccessLogEntryDto struct {
+	Timestamp time.Time `json:"timestamp"`
+	Host      string    `json:"host"`
+	Method    string    `json:"method"`
+	Path      string    `json:"path"`
+	Status    int       `json:"status"`
+	Bytes     int64     `json:"bytes"`
+	LatencyMs float64   `json:"latencyMs"`
+	ClientIp  string    `json:"clientIp"`
+	User      string    `json:"user,omitempty"`
+}
+
+type ContainerStatsDto struct {
+	Name             string  `json:"name"`
+	CpuPercent       float64 `json:"cpuPercent"`
+	MemoryUsageBytes uint64  `json:"memoryUsageBytes"`
+	MemoryLimitBytes uint64  `json:"memoryLimitBytes"`
+	NetworkRxBytes   uint64  `json:"networkRxBytes"`
+	NetworkTxBytes   uint64  `json:"networkTxBytes"`
+	BlockReadBytes   uint64  `json:"blockReadBytes"`
+	BlockWriteBytes  uint64  `json:"blockWriteBytes"`
+}
+
+type StackStatsSampleDto struct {
+	Timestamp        time.Time `json:"timestamp"`
+	CpuPercent       float64   `json:"cpuPercent"`
+	MemoryUsageBytes uint64    `json:"memoryUsageBytes"`
+}
+
+type StackStatsDto struct {
+	Stack      string                `json:"stack"`
+	Containers []ContainerStatsDto   `json:"containers"`
+	Total      ContainerStatsDto     `json:"total"`
+	History    []StackStatsSampleDto `json:"history"`
+}
+
+type DiskUsageDto struct {
+	Path           string  `json:"path"`
+	TotalBytes     uint64  `json:"totalBytes"`
+	UsedBytes      uint64  `json:"usedBytes"`
+	AvailableBytes uint64  `json:"availableBytes"`
+	UsedPercent    float64 `json:"usedPercent"`
+}
+
+type MemoryUsageDto struct {
+	TotalBytes     uint64  `json:"totalBytes"`
+	AvailableBytes uint64  `json:"availableBytes"`
+	UsedPercent    float64 `json:"usedPercent"`
+}
+
+type LoadAverageDto struct {
+	Load1  float64 `json:"load1"`
+	Load5  float64 `json:"load5"`
+	Load15 float64 `json:"load15"`
+	Cpus   int     `json:"cpus"`
+}
+
+type DockerInfoDto struct {
+	Version        string `json:"version"`
+	ComposeVersion string `json:"composeVersion"`
+	DataRoot       string `json:"dataRoot"`
+}
+
+type StackDiskUsageDto struct {
+	Stack       string `json:"stack"`
+	ImageBytes  uint64 `json:"imageBytes"`
+	VolumeBytes uint64 `json:"volumeBytes"`
+}
+
+type SystemInfoDto struct {
+	Disks    []DiskUsageDto      `json:"disks"`
+	Memory   MemoryUsageDto      `json:"memory"`
+	Load     LoadAverageDto      `json:"load"`
+	Docker   DockerInfoDto       `json:"docker"`
+	Stacks   []StackDiskUsageDto `json:"stacks"`
+	Warnings []string            `json:"warnings"`
+}
+
+type StackIncidentDto struct {
+	Container string    `json:"container"`
+	Kind      string    `json:"kind"`
+	Message   string    `json:"message"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// BulkOperationDto selects the stacks of a bulk operation, all stacks are selected if the list is empty.
+type BulkOperationDto struct {
+	Stacks []string `json:"stacks"`
+}
+
+type BulkOperationResultDto struct {
+	Stack   string `json:"stack"`
+	Success bool   `json:"success"`
+	Error   string `json:"error,omitempty"`
+}
+
+type StackDetailDto struct {
+	Name            string   `json:"name"`
+	State           string   `json:"state"`
+	UrlPath         string   `json:"urlPath"`
+	Template        string   `json:"template,omitempty"`
+	UpdateAvailable bool     `json:"updateAvailable"`
+	Domains         []string `json:"domains"`
+	DependsOn       []string `json:"dependsOn"`
+}
+
+// ErrorDto is the body of all error responses of the versioned API. The code is meant for programs, the message
+// for humans. Output contains the output of docker compose, if a compose command failed.
+type ErrorDto struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	Output  string `json:"output,omitempty"`
+}
+
+// ApiTokenDto describes a personal access token without the token itself, which is only shown once on creation.
+// A missing expiry means that the token never expires.
+type ApiTokenDto struct {
+	Id         int64      `json:"id"`
+	Name       string     `json:"name"`
+	Scopes     []string   `json:"scopes"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	ExpiresAt  *time.Time `json:"expiresAt,omitempty"`
+	LastUsedAt *time.Time `json:"lastUsedAt,omitempty"`
+}
+
+// CreateApiTokenDto requests a new token. If ExpiresInDays is zero, the default lifetime is used, if it is
+// negative, the token never expires. At most 3650 days are accepted.
+type CreateApiTokenDto struct {
+	Name          string   `json:"name"`
+	Scopes        []string `json:"scopes"`
+	ExpiresInDays int      `json:"expiresInDays,omitempty"`
+}
+
+type CreatedApiTokenDto struct {
+	ApiTokenDto
+	Token string `json:"token"`
+}
+
+// StackBackupDto describes a backup of the volumes of a stack. The reason is either "manual" or "pre-upgrade".
+type StackBackupDto struct {
+	Id        string    `json:"id"`
+	Timestamp time.Time `json:"timestamp"`
+	Reason    string    `json:"reason"`
+}
+
+// WebhookDto describes a webhook without its secret, which is only shown once on creation. An empty list of events
+// means that the webhook receives all events.
+type WebhookDto struct {
+	Id        int64     `json:"id"`
+	Url       string    `json:"url"`
+	Events    []string  `json:"events"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// CreateWebhookDto requests a new webhook. If no secret is given, one is generated.
+type CreateWebhookDto struct {
+	Url    string   `json:"url"`
+	Secret string   `json:"secret,omitempty"`
+	Events []string `json:"events"`
+}
+
+type CreatedWebhookDto struct {
+	WebhookDto
+	Secret string `json:"secret"`
+}
+
+type WebhookDeliveryDto struct {
+	Id           int64     `json:"id"`
+	DeliveryId   string    `json:"deliveryId"`
+	Event        string    `json:"event"`
+	Attempt      int       `json:"attempt"`
+	StatusCode   int       `json:"statusCode,omitempty"`
+	Error        string    `json:"error,omitempty"`
+	IsSuccessful bool      `json:"isSuccessful"`
+	Timestamp    time.Time `json:"timestamp"`
+}
+
+// WebhookPayloadDto is the body sent to webhooks. The id is the same for all attempts to deliver an event, so that
+// receivers can recognize duplicates.
+type WebhookPayloadDto struct {
+	Id        string            `json:"id"`
+	Event     string            `json:"event"`
+	Stack     string            `json:"stack,omitempty"`
+	Message   string            `json:"message"`
+	Details   map[string]string `json:"details,omitempty"`
+	Timestamp time.Time         `json:"timestamp"`
+}
+
+// EmailSubscriptionDto selects the alerts a user receives by email. An empty list of events means that the user
+// receives all alerts.
+type EmailSubscriptionDto struct {
+	User   string   `json:"user"`
+	Email  string   `json:"email"`
+	Events []string `json:"events"`
+}
+
+type TestEmailDto struct {
+	Email string `json:"email"`
+}
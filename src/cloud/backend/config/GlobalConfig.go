@@ -3,9 +3,48 @@ package tools
 const NginxDefault = "nginx-default"
 const NginxDefault2 = "nginx-default2"
 const NginxCustomPath = "nginx-custom-path"
+const NginxCustomPort = "nginx-custom-port"
 const NginxSlowStart = "nginx-slow-start"
 const NginxDownloading = "nginx-download"
 
+const (
+	// RoutingModeSubdomain routes requests to "<stack>.<RootDomain>", which requires a wildcard DNS entry.
+	RoutingModeSubdomain = "subdomain"
+	// RoutingModePath routes requests to "<RootDomain>/apps/<stack>/", so that a single host name is sufficient.
+	// Since the apps share the origin of the dashboard, it requires the security to be disabled.
+	RoutingModePath = "path"
+)
+
+const (
+	// SmtpSecurityTls connects to the SMTP server via TLS, usually on port 465.
+	SmtpSecurityTls = "tls"
+	// SmtpSecurityStartTls upgrades the connection to the SMTP server via STARTTLS, usually on port 587.
+	SmtpSecurityStartTls = "starttls"
+	// SmtpSecurityNone sends emails unencrypted, which is only meant for a mail relay on the same host.
+	SmtpSecurityNone = "none"
+)
+
+// SmtpConfig describes the server used to send email alerts. Email alerts are disabled if the host is empty.
+type SmtpConfig struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+	From     string
+	Security string // SmtpSecurityTls, SmtpSecurityStartTls or SmtpSecurityNone
+}
+
+// LimitDefaults are the limits of the apps which are not overridden in the app.yml of a stack. A value of zero
+// disables the respective limit, which is the default, since suitable limits depend on the apps and the host.
+type LimitDefaults struct {
+	RequestsPerSecond       float64
+	Burst                   int
+	ClientRequestsPerSecond float64 // the client is identified by its IP, which requires TrustedProxies behind a proxy
+	ClientBurst             int
+	MaxRequestBodyBytes     int64
+	MaxConcurrentRequests   int // WebSocket connections count as requests for as long as they are open
+}
+
 // TODO Make it instead profile based: PROD (default), NATIVE (no docker container); also add ENV variable: ENABLE_DOCKER_MOCK (default false)
 
 type GlobalConfig struct {
@@ -18,7 +57,18 @@ type GlobalConfig struct {
 	BackendMode                      BackendComponentMode
 	WaitForSecurityBeforeOpeningPort bool
 	UseDummyStacks                   bool
-	Scheme                           string // "http" or "https"
-	RootDomain                       string // e.g. "localhost"
-	Port                             string // e.g. "8082"
+	Scheme                           string   // "http" or "https"
+	RootDomain                       string   // e.g. "localhost"
+	Port                             string   // e.g. "8082"
+	RoutingMode                      string   // RoutingModeSubdomain or RoutingModePath
+	DashboardAllowList               []string // IPs or CIDR ranges allowed to access the dashboard, all if empty
+	DashboardDenyList                []string // IPs or CIDR ranges denied access to the dashboard
+	TrustedProxies                   []string // IPs or CIDR ranges whose X-Forwarded-For header is trusted
+	IsAccessLogPrintedToStdout       bool     // the access log of the apps is always written to a file
+	MetricsToken                     string   // bearer token required by the metrics endpoint, disabled if empty
+	Smtp                             SmtpConfig
+	IsTlsEnabled                     bool   // serves HTTPS with certificates from Let's Encrypt
+	TlsPort                          string // e.g. "8443", port 443 of the host has to be forwarded to it
+	AcmeEmail                        string // contact address for the Let's Encrypt account, optional
+	LimitDefaults                    LimitDefaults
 }
@@ -0,0 +1,206 @@
+package internal
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const (
+	ScopeRead  = "read"
+	ScopeWrite = "write"
+)
+
+const apiTokenPrefix = "ocelot_"
+const DefaultTokenLifetime = 90 * 24 * time.Hour
+
+// MaxTokenLifetimeDays limits the lifetime of expiring tokens, which also keeps the lifetime within the range of
+// time.Duration.
+const MaxTokenLifetimeDays = 3650
+
+var ErrInvalidToken = errors.New("invalid or expired token")
+var tokenNamePattern = regexp.MustCompile(`^[A-Za-z0-9 _.-]{1,64}$`)
+
+// sensitivePathPattern matches the endpoints which reveal credentials or configure where data is sent to, which are
+// the secrets of the stacks, the webhooks and the email subscriptions. They require the write scope for all methods.
+var sensitivePathPattern = regexp.MustCompile(`^/api/v1/(stacks/[^/]+/secrets|webhooks|email)(/|$)`)
+
+// ApiToken allows automation like CI pipelines to use the API without a browser session. The token itself is only
+// shown once when it is created, only its SHA-256 hash is stored. A zero ExpiresAt means that it never expires.
+type ApiToken struct {
+	Id         int64
+	Name       string
+	Scopes     []string
+	CreatedAt  time.Time
+	ExpiresAt  time.Time
+	LastUsedAt time.Time
+}
+
+// Allows returns whether the token may be used for requests with the given method and path. Reading requests
+// require the read or write scope, all other requests and all requests to sensitive endpoints the write scope.
+func (t ApiToken) Allows(method string, path string) bool {
+	isReading := (method == "GET" || method == "HEAD") && !sensitivePathPattern.MatchString(path)
+	for _, scope := range t.Scopes {
+		if scope == ScopeWrite || (scope == ScopeRead && isReading) {
+			return true
+		}
+	}
+	return false
+}
+
+type TokenStore struct {
+	db  *sql.DB
+	now func() time.Time
+}
+
+func ProvideTokenStore(db *sql.DB) *TokenStore {
+	_, err := db.Exec("CREATE TABLE IF NOT EXISTS api_tokens (id INTEGER NOT NULL PRIMARY KEY AUTOINCREMENT, name TEXT NOT NULL, hash TEXT NOT NULL UNIQUE, scopes TEXT NOT NULL, created_at INTEGER NOT NULL, expires_at INTEGER NOT NULL, last_used_at INTEGER NOT NULL)")
+	if err != nil {
+		Logger.Fatal("failed to create table: %v", err)
+	}
+	return &TokenStore{db: db, now: time.Now}
+}
+
+// CreateToken generates a new token, which expires after the given lifetime, or never if the lifetime is zero.
+func (s *TokenStore) CreateToken(name string, scopes []string, lifetime time.Duration) (string, ApiToken, error) {
+	if !tokenNamePattern.MatchString(name) {
+		return "", ApiToken{}, fmt.Errorf("invalid token name '%s', only up to 64 letters, digits, spaces, dots, dashes and underscores are allowed", name)
+	} else if len(scopes) == 0 {
+		return "", ApiToken{}, fmt.Errorf("a token needs at least one scope")
+	}
+	for _, scope := range scopes {
+		if scope != ScopeRead && scope != ScopeWrite {
+			return "", ApiToken{}, fmt.Errorf("unknown scope '%s'", scope)
+		}
+	}
+
+	randomBytes := make([]byte, 32)
+	if _, err := rand.Read(randomBytes); err != nil {
+		return "", ApiToken{}, err
+	}
+	token := apiTokenPrefix + hex.EncodeToString(randomBytes)
+	apiToken := ApiToken{Name: name, Scopes: scopes, CreatedAt: s.now()}
+	if lifetime > 0 {
+		apiToken.ExpiresAt = apiToken.CreatedAt.Add(lifetime)
+	}
+
+	result, err := s.db.Exec("INSERT INTO api_tokens (name, hash, scopes, created_at, expires_at, last_used_at) VALUES (?, ?, ?, ?, ?, 0)",
+		name, hashToken(token), strings.Join(scopes, ","), apiToken.CreatedAt.Unix(), toUnix(apiToken.ExpiresAt))
+	if err != nil {
+		Logger.Error("failed to store token '%s': %v", name, err)
+		return "", ApiToken{}, fmt.Errorf("failed to store token")
+	}
+	if apiToken.Id, err = result.LastInsertId(); err != nil {
+		return "", ApiToken{}, err
+	}
+	Logger.Info("API token '%s' was created with scopes %s", name, strings.Join(scopes, ", "))
+	return token, apiToken, nil
+}
+
+// GetTokens returns all tokens including the expired ones, the oldest one first.
+func (s *TokenStore) GetTokens() ([]ApiToken, error) {
+	rows, err := s.db.Query("SELECT id, name, scopes, created_at, expires_at, last_used_at FROM api_tokens ORDER BY id")
+	if err != nil {
+		Logger.Error("failed to query tokens: %v", err)
+		return nil, fmt.Errorf("failed to read tokens")
+	}
+	defer rows.Close()
+
+	var tokens []ApiToken
+	for rows.Next() {
+		token, err := scanToken(rows)
+		if err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, token)
+	}
+	return tokens, rows.Err()
+}
+
+func (s *TokenStore) RevokeToken(id int64) error {
+	result, err := s.db.Exec("DELETE FROM api_tokens WHERE id = ?", id)
+	if err != nil {
+		Logger.Error("failed to delete token %d: %v", id, err)
+		return fmt.Errorf("failed to delete token")
+	}
+	if affectedRows, err := result.RowsAffected(); err == nil && affectedRows == 0 {
+		return fmt.Errorf("token %d does not exist", id)
+	}
+	Logger.Info("API token %d was revoked", id)
+	return nil
+}
+
+// Authenticate returns the stored token matching the given one, if it exists and did not expire. The usage of the
+// token is recorded.
+func (s *TokenStore) Authenticate(token string) (ApiToken, error) {
+	apiToken, err := s.Lookup(token)
+	if err != nil {
+		return ApiToken{}, err
+	}
+
+	now := s.now()
+	if _, err := s.db.Exec("UPDATE api_tokens SET last_used_at = ? WHERE id = ?", now.Unix(), apiToken.Id); err != nil {
+		Logger.Warn("failed to record usage of token '%s': %v", apiToken.Name, err)
+	}
+	apiToken.LastUsedAt = time.Unix(now.Unix(), 0)
+	return apiToken, nil
+}
+
+// Lookup returns the stored token matching the given one like Authenticate, but without recording its usage.
+func (s *TokenStore) Lookup(token string) (ApiToken, error) {
+	if !strings.HasPrefix(token, apiTokenPrefix) {
+		return ApiToken{}, ErrInvalidToken
+	}
+	row := s.db.QueryRow("SELECT id, name, scopes, created_at, expires_at, last_used_at FROM api_tokens WHERE hash = ?", hashToken(token))
+	apiToken, err := scanToken(row)
+	if err != nil {
+		return ApiToken{}, ErrInvalidToken
+	}
+	if !apiToken.ExpiresAt.IsZero() && !s.now().Before(apiToken.ExpiresAt) {
+		return ApiToken{}, ErrInvalidToken
+	}
+	return apiToken, nil
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanToken(row rowScanner) (ApiToken, error) {
+	var token ApiToken
+	var scopes string
+	var createdAt, expiresAt, lastUsedAt int64
+	if err := row.Scan(&token.Id, &token.Name, &scopes, &createdAt, &expiresAt, &lastUsedAt); err != nil {
+		return ApiToken{}, err
+	}
+	token.Scopes = strings.Split(scopes, ",")
+	token.CreatedAt = time.Unix(createdAt, 0)
+	token.ExpiresAt = fromUnix(expiresAt)
+	token.LastUsedAt = fromUnix(lastUsedAt)
+	return token, nil
+}
+
+func hashToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
+
+func toUnix(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.Unix()
+}
+
+func fromUnix(seconds int64) time.Time {
+	if seconds == 0 {
+		return time.Time{}
+	}
+	return time.Unix(seconds, 0)
+}
@@ -0,0 +1,99 @@
+package internal
+
+import (
+	"database/sql"
+	"github.com/ocelot-cloud/shared/assert"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func createTokenStore(t *testing.T) *TokenStore {
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "sqlite.db"))
+	assert.Nil(t, err)
+	t.Cleanup(func() { db.Close() })
+	return ProvideTokenStore(db)
+}
+
+func TestTokenIsStoredHashed(t *testing.T) {
+	store := createTokenStore(t)
+	token, apiToken, err := store.CreateToken("ci", []string{ScopeWrite}, DefaultTokenLifetime)
+	assert.Nil(t, err)
+	assert.True(t, strings.HasPrefix(token, apiTokenPrefix))
+
+	var hash string
+	assert.Nil(t, store.db.QueryRow("SELECT hash FROM api_tokens WHERE id = ?", apiToken.Id).Scan(&hash))
+	assert.Equal(t, hashToken(token), hash)
+	assert.False(t, strings.Contains(hash, strings.TrimPrefix(token, apiTokenPrefix)))
+
+	authenticatedToken, err := store.Authenticate(token)
+	assert.Nil(t, err)
+	assert.Equal(t, "ci", authenticatedToken.Name)
+	assert.False(t, authenticatedToken.LastUsedAt.IsZero())
+	_, err = store.Authenticate(token + "0")
+	assert.Equal(t, ErrInvalidToken, err)
+}
+
+func TestExpiredTokenIsRejected(t *testing.T) {
+	store := createTokenStore(t)
+	now := time.Now()
+	store.now = func() time.Time { return now }
+	token, _, err := store.CreateToken("ci", []string{ScopeRead}, time.Hour)
+	assert.Nil(t, err)
+	neverExpiringToken, _, err := store.CreateToken("monitoring", []string{ScopeRead}, 0)
+	assert.Nil(t, err)
+
+	store.now = func() time.Time { return now.Add(time.Hour) }
+	_, err = store.Authenticate(token)
+	assert.Equal(t, ErrInvalidToken, err)
+	_, err = store.Authenticate(neverExpiringToken)
+	assert.Nil(t, err)
+
+	tokens, err := store.GetTokens()
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(tokens))
+	assert.True(t, tokens[1].ExpiresAt.IsZero())
+}
+
+func TestRevokedTokenIsRejected(t *testing.T) {
+	store := createTokenStore(t)
+	token, apiToken, err := store.CreateToken("ci", []string{ScopeWrite}, DefaultTokenLifetime)
+	assert.Nil(t, err)
+
+	assert.Nil(t, store.RevokeToken(apiToken.Id))
+	_, err = store.Authenticate(token)
+	assert.Equal(t, ErrInvalidToken, err)
+	assert.NotNil(t, store.RevokeToken(apiToken.Id))
+}
+
+func TestTokenCreationIsValidated(t *testing.T) {
+	store := createTokenStore(t)
+	_, _, err := store.CreateToken("", []string{ScopeRead}, 0)
+	assert.NotNil(t, err)
+	_, _, err = store.CreateToken("ci", nil, 0)
+	assert.NotNil(t, err)
+	_, _, err = store.CreateToken("ci", []string{"admin"}, 0)
+	assert.NotNil(t, err)
+}
+
+func TestScopesRestrictMethods(t *testing.T) {
+	readToken := ApiToken{Scopes: []string{ScopeRead}}
+	assert.True(t, readToken.Allows("GET", "/api/v1/stacks"))
+	assert.False(t, readToken.Allows("POST", "/api/v1/stacks/gitea/deploy"))
+	assert.False(t, readToken.Allows("DELETE", "/api/v1/instances/gitea-2"))
+
+	writeToken := ApiToken{Scopes: []string{ScopeWrite}}
+	assert.True(t, writeToken.Allows("GET", "/api/v1/stacks"))
+	assert.True(t, writeToken.Allows("POST", "/api/v1/stacks/gitea/deploy"))
+}
+
+func TestSensitiveEndpointsRequireWriteScope(t *testing.T) {
+	readToken := ApiToken{Scopes: []string{ScopeRead}}
+	writeToken := ApiToken{Scopes: []string{ScopeWrite}}
+	for _, path := range []string{"/api/v1/stacks/gitea/secrets", "/api/v1/webhooks", "/api/v1/webhooks/1/deliveries", "/api/v1/email/subscriptions"} {
+		assert.False(t, readToken.Allows("GET", path), path)
+		assert.True(t, writeToken.Allows("GET", path), path)
+	}
+	assert.True(t, readToken.Allows("GET", "/api/v1/stacks/secrets-manager"))
+}
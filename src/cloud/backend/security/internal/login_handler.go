@@ -10,12 +10,38 @@ type Credentials struct {
 	Password string `json:"password"`
 }
 
+// SessionCookieName is the name of the cookie holding the session of a logged-in user.
+const SessionCookieName = "auth"
+
 // TODO Insecure
 var users = map[string]string{
 	"admin": "password",
 }
 
-func LoginHandler(w http.ResponseWriter, r *http.Request) {
+// CreateLoginHandler returns the handler of the login. onFailure is called with the user name and the IP of the
+// client, when the credentials are wrong.
+func CreateLoginHandler(getClientIp func(r *http.Request) string, onFailure func(username string, remoteAddress string)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		handleLogin(w, r, getClientIp(r), onFailure)
+	}
+}
+
+func UserExists(username string) bool {
+	_, ok := users[username]
+	return ok
+}
+
+// GetSessionUser returns the user of the session of a request, if it has a valid session cookie.
+func GetSessionUser(r *http.Request) (string, bool) {
+	cookie, err := r.Cookie(SessionCookieName)
+	// TODO The cookie does not identify the user yet, which only works as long as there is a single user.
+	if err != nil || cookie.Value != "valid" {
+		return "", false
+	}
+	return "admin", true
+}
+
+func handleLogin(w http.ResponseWriter, r *http.Request, clientIp string, onFailure func(username string, remoteAddress string)) {
 	Logger.Debug("login logic called")
 	var creds Credentials
 	err := json.NewDecoder(r.Body).Decode(&creds)
@@ -28,13 +54,14 @@ func LoginHandler(w http.ResponseWriter, r *http.Request) {
 
 	if !ok || expectedPassword != creds.Password {
 		Logger.Debug("password not matching")
+		onFailure(creds.Username, clientIp)
 		w.WriteHeader(http.StatusUnauthorized)
 		return
 	}
 
 	// TODO Use safe, randomly generated cookies instead. I think gorilla provides some.
 	http.SetCookie(w, &http.Cookie{
-		Name:     "auth",
+		Name:     SessionCookieName,
 		Value:    "valid",
 		Path:     "/",
 		MaxAge:   3600,
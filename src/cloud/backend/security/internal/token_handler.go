@@ -0,0 +1,98 @@
+package internal
+
+import (
+	"encoding/json"
+	"github.com/gorilla/mux"
+	"net/http"
+	"ocelot/backend/config"
+	"strconv"
+	"time"
+)
+
+func CreateTokenListHandler(store *TokenStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		readTokens(w, store)
+	}
+}
+
+func CreateTokenCreationHandler(store *TokenStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		createToken(w, r, store)
+	}
+}
+
+func CreateTokenRevocationHandler(store *TokenStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+		if err != nil {
+			WriteError(w, http.StatusBadRequest, "invalid_request", "Invalid token id: "+mux.Vars(r)["id"])
+			return
+		}
+		if err = store.RevokeToken(id); err != nil {
+			WriteError(w, http.StatusNotFound, "not_found", err.Error())
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func readTokens(w http.ResponseWriter, store *TokenStore) {
+	tokens, err := store.GetTokens()
+	if err != nil {
+		WriteError(w, http.StatusInternalServerError, "operation_failed", err.Error())
+		return
+	}
+	tokenDtos := make([]tools.ApiTokenDto, 0)
+	for _, token := range tokens {
+		tokenDtos = append(tokenDtos, toApiTokenDto(token))
+	}
+	writeJson(w, http.StatusOK, tokenDtos)
+}
+
+func createToken(w http.ResponseWriter, r *http.Request, store *TokenStore) {
+	var request tools.CreateApiTokenDto
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		WriteError(w, http.StatusBadRequest, "invalid_request", "Failed to decode JSON: "+err.Error())
+		return
+	}
+
+	if request.ExpiresInDays > MaxTokenLifetimeDays {
+		WriteError(w, http.StatusBadRequest, "invalid_request", "Tokens can expire in at most "+strconv.Itoa(MaxTokenLifetimeDays)+" days, use a negative value for tokens which never expire")
+		return
+	}
+
+	lifetime := DefaultTokenLifetime
+	if request.ExpiresInDays < 0 {
+		lifetime = 0
+	} else if request.ExpiresInDays > 0 {
+		lifetime = time.Duration(request.ExpiresInDays) * 24 * time.Hour
+	}
+	token, apiToken, err := store.CreateToken(request.Name, request.Scopes, lifetime)
+	if err != nil {
+		WriteError(w, http.StatusBadRequest, "invalid_request", err.Error())
+		return
+	}
+	writeJson(w, http.StatusCreated, tools.CreatedApiTokenDto{ApiTokenDto: toApiTokenDto(apiToken), Token: token})
+}
+
+func toApiTokenDto(token ApiToken) tools.ApiTokenDto {
+	tokenDto := tools.ApiTokenDto{Id: token.Id, Name: token.Name, Scopes: token.Scopes, CreatedAt: token.CreatedAt}
+	if !token.ExpiresAt.IsZero() {
+		tokenDto.ExpiresAt = &token.ExpiresAt
+	}
+	if !token.LastUsedAt.IsZero() {
+		tokenDto.LastUsedAt = &token.LastUsedAt
+	}
+	return tokenDto
+}
+
+// WriteError answers with the same JSON error body as the versioned API of the business module.
+func WriteError(w http.ResponseWriter, status int, code string, message string) {
+	writeJson(w, status, tools.ErrorDto{Code: code, Message: message})
+}
+
+func writeJson(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
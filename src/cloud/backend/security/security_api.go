@@ -1,34 +1,130 @@
 package security
 
 import (
+	"context"
+	"database/sql"
 	"github.com/gorilla/mux"
 	"github.com/ocelot-cloud/shared"
+	"net"
 	"net/http"
 	"ocelot/backend/config"
 	"ocelot/backend/security/internal"
 	"strings"
+	"sync"
 )
 
 var Logger = shared.ProvideLogger()
 
+// SessionCookieName is the cookie of the dashboard session, which must not be passed on to the apps.
+const SessionCookieName = internal.SessionCookieName
+
 type SecurityModule struct {
-	router *mux.Router
-	config *tools.GlobalConfig
+	router     *mux.Router
+	config     *tools.GlobalConfig
+	tokenStore *internal.TokenStore
+	mu         sync.Mutex
+	// loginFailureListeners are notified about failed logins, e.g. to alert the admin of brute force attempts.
+	loginFailureListeners []func(username string, remoteAddress string)
+	// clientIpResolver determines the IP of the client sending a request, the remote address is used if not set.
+	clientIpResolver func(r *http.Request) net.IP
 }
 
 func ProvideSecurityModule(router *mux.Router, config *tools.GlobalConfig) *SecurityModule {
-	router.HandleFunc("/api/login", internal.LoginHandler).Methods("POST")
-	return &SecurityModule{router, config}
+	securityModule := &SecurityModule{router: router, config: config}
+	router.HandleFunc("/api/login", internal.CreateLoginHandler(securityModule.getClientIp, securityModule.notifyLoginFailure)).Methods("POST")
+	return securityModule
+}
+
+// UseClientIpResolver sets how the IP of a client is determined, so that the proxy in front of the dashboard can
+// respect the trusted proxies. The IP is reported to the listeners of failed logins.
+func (s *SecurityModule) UseClientIpResolver(resolver func(r *http.Request) net.IP) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.clientIpResolver = resolver
+}
+
+func (s *SecurityModule) getClientIp(r *http.Request) string {
+	s.mu.Lock()
+	resolver := s.clientIpResolver
+	s.mu.Unlock()
+	if resolver != nil {
+		if clientIp := resolver(r); clientIp != nil {
+			return clientIp.String()
+		}
+	}
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		return host
+	}
+	return r.RemoteAddr
+}
+
+func (s *SecurityModule) AddLoginFailureListener(listener func(username string, remoteAddress string)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.loginFailureListeners = append(s.loginFailureListeners, listener)
+}
+
+func (s *SecurityModule) notifyLoginFailure(username string, remoteAddress string) {
+	s.mu.Lock()
+	listeners := append([]func(username string, remoteAddress string){}, s.loginFailureListeners...)
+	s.mu.Unlock()
+	for _, listener := range listeners {
+		listener(username, remoteAddress)
+	}
+}
+
+// UseDatabase enables the API tokens, which are stored in the given database. It has to be called before the
+// token handlers are requested.
+func (s *SecurityModule) UseDatabase(db *sql.DB) {
+	s.tokenStore = internal.ProvideTokenStore(db)
+}
+
+// TokenHandlers manage the API tokens. They are registered as part of the versioned API of the business module, so
+// that they are described in its OpenAPI document. The caller applies the auth middlewares.
+type TokenHandlers struct {
+	List   http.HandlerFunc
+	Create http.HandlerFunc
+	Revoke http.HandlerFunc
+}
+
+func (s *SecurityModule) GetTokenHandlers() TokenHandlers {
+	return TokenHandlers{
+		List:   s.requireSession(internal.CreateTokenListHandler(s.tokenStore)),
+		Create: s.requireSession(internal.CreateTokenCreationHandler(s.tokenStore)),
+		Revoke: s.requireSession(internal.CreateTokenRevocationHandler(s.tokenStore)),
+	}
 }
 
 func (s *SecurityModule) ApplyAuthMiddlewares(h http.Handler) http.Handler {
 	if s.config.IsSecurityEnabled {
 		return s.applyAuthMiddleware(h)
 	} else {
-		return s.applyCorsPolicy(h)
+		return s.applyCorsPolicy(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			h.ServeHTTP(w, asAdmin(r))
+		}))
 	}
 }
 
+type contextKey int
+
+const adminContextKey contextKey = iota
+
+// IsAdmin returns whether a request passed the auth middleware with the session of a logged-in user or without
+// security. Requests with API tokens are not considered as admin, since tokens are handed to automation.
+func IsAdmin(r *http.Request) bool {
+	isAdmin, _ := r.Context().Value(adminContextKey).(bool)
+	return isAdmin
+}
+
+// UserExists returns whether a user with the given name can log in.
+func UserExists(username string) bool {
+	return internal.UserExists(username)
+}
+
+func asAdmin(r *http.Request) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), adminContextKey, true))
+}
+
 func (s *SecurityModule) applyAuthMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		// TODO Add "Origin" header check to prevent CSRF attacks.
@@ -37,15 +133,18 @@ func (s *SecurityModule) applyAuthMiddleware(next http.Handler) http.Handler {
 		// 3) I think port can be ignored since I used the standard ports.
 		// TODO In Production mode, when security is enabled, there must be a environment variable called "HOST" (aka Origin) of the form http(s)://*(:[0-9]*), so a URL with http or https, with or without port(?) etc. This is for security to fulfill the origin policy to prevent CSRF attacks.
 		if strings.HasPrefix(r.URL.Path, "/api/") {
-			cookie, err := r.Cookie("auth")
+			if token, ok := getBearerToken(r); ok {
+				s.authenticateToken(w, r, next, token)
+				return
+			}
 			// TODO Not secure.
-			if err != nil || cookie.Value != "valid" {
+			if _, ok := internal.GetSessionUser(r); !ok {
 				Logger.Debug("requests cookie is invalid")
 				w.WriteHeader(http.StatusUnauthorized)
 				return
 			} else {
 				Logger.Debug("user has a valid cookie and is allowed to access protected backend functions")
-				next.ServeHTTP(w, r)
+				next.ServeHTTP(w, asAdmin(r))
 			}
 		} else {
 			Logger.Debug("a user requested the frontend resources")
@@ -70,3 +169,57 @@ func (s *SecurityModule) applyCorsPolicy(next http.Handler) http.Handler {
 		return next
 	}
 }
+
+// requireSession protects the token management, which is only allowed with a session of a logged-in user, so that
+// a leaked token can not be used to create further tokens.
+func (s *SecurityModule) requireSession(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := getBearerToken(r); ok {
+			internal.WriteError(w, http.StatusForbidden, "forbidden", "API tokens can not be used to manage API tokens")
+			return
+		}
+		next(w, r)
+	}
+}
+
+func (s *SecurityModule) authenticateToken(w http.ResponseWriter, r *http.Request, next http.Handler, token string) {
+	if s.tokenStore == nil {
+		internal.WriteError(w, http.StatusUnauthorized, "unauthorized", "API tokens are not available")
+		return
+	}
+	apiToken, err := s.tokenStore.Authenticate(token)
+	if err != nil {
+		Logger.Debug("request with invalid or expired API token")
+		w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+		internal.WriteError(w, http.StatusUnauthorized, "unauthorized", "Invalid or expired API token")
+		return
+	}
+	if !apiToken.Allows(r.Method, r.URL.Path) {
+		Logger.Debug("API token '%s' lacks the scope for %s %s", apiToken.Name, r.Method, r.URL.Path)
+		w.Header().Set("WWW-Authenticate", `Bearer error="insufficient_scope", scope="write"`)
+		internal.WriteError(w, http.StatusForbidden, "insufficient_scope", "The API token is not allowed to use "+r.Method+" requests on "+r.URL.Path)
+		return
+	}
+	Logger.Debug("API token '%s' is allowed to access protected backend functions", apiToken.Name)
+	next.ServeHTTP(w, r)
+}
+
+// GetAuthenticatedUser returns who sent a request, which is the user of the session or "token:<name>" for requests
+// with an API token. It is empty for anonymous requests. The usage of the token is not recorded, so that it can be
+// used for logging.
+func (s *SecurityModule) GetAuthenticatedUser(r *http.Request) string {
+	if token, ok := getBearerToken(r); ok && s.tokenStore != nil {
+		if apiToken, err := s.tokenStore.Lookup(token); err == nil {
+			return "token:" + apiToken.Name
+		}
+	}
+	if user, ok := internal.GetSessionUser(r); ok {
+		return user
+	}
+	return ""
+}
+
+func getBearerToken(r *http.Request) (string, bool) {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return strings.TrimSpace(token), ok
+}
@@ -0,0 +1,205 @@
+package security
+
+import (
+	"database/sql"
+	"encoding/json"
+	"github.com/gorilla/mux"
+	"github.com/ocelot-cloud/shared/assert"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"ocelot/backend/config"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func createSecuredRouter(t *testing.T) *mux.Router {
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "sqlite.db"))
+	assert.Nil(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	router := mux.NewRouter()
+	securityModule := ProvideSecurityModule(router, &tools.GlobalConfig{IsSecurityEnabled: true})
+	securityModule.UseDatabase(db)
+	registerTokenHandlers(router, securityModule)
+	router.PathPrefix("/api/").Handler(securityModule.ApplyAuthMiddlewares(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})))
+	return router
+}
+
+// registerTokenHandlers registers the token management like the versioned API of the business module does.
+func registerTokenHandlers(router *mux.Router, securityModule *SecurityModule) {
+	tokenHandlers := securityModule.GetTokenHandlers()
+	router.Handle("/api/v1/tokens", securityModule.ApplyAuthMiddlewares(tokenHandlers.List)).Methods("GET")
+	router.Handle("/api/v1/tokens", securityModule.ApplyAuthMiddlewares(tokenHandlers.Create)).Methods("POST")
+	router.Handle("/api/v1/tokens/{id}", securityModule.ApplyAuthMiddlewares(tokenHandlers.Revoke)).Methods("DELETE")
+}
+
+func sendRequest(router *mux.Router, method string, path string, body string, modify func(r *http.Request)) *httptest.ResponseRecorder {
+	request := httptest.NewRequest(method, path, strings.NewReader(body))
+	modify(request)
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, request)
+	return recorder
+}
+
+func withSession(r *http.Request) {
+	r.AddCookie(&http.Cookie{Name: "auth", Value: "valid"})
+}
+
+func withToken(token string) func(r *http.Request) {
+	return func(r *http.Request) {
+		r.Header.Set("Authorization", "Bearer "+token)
+	}
+}
+
+func createToken(t *testing.T, router *mux.Router, scope string) tools.CreatedApiTokenDto {
+	recorder := sendRequest(router, "POST", "/api/v1/tokens", `{"name": "ci", "scopes": ["`+scope+`"]}`, withSession)
+	assert.Equal(t, http.StatusCreated, recorder.Code)
+	var createdToken tools.CreatedApiTokenDto
+	assert.Nil(t, json.NewDecoder(recorder.Body).Decode(&createdToken))
+	return createdToken
+}
+
+func TestApiTokenIsAcceptedAsBearerToken(t *testing.T) {
+	router := createSecuredRouter(t)
+	createdToken := createToken(t, router, "write")
+	assert.NotNil(t, createdToken.ExpiresAt)
+
+	assert.Equal(t, http.StatusUnauthorized, sendRequest(router, "POST", "/api/v1/stacks/nginx/deploy", "", func(r *http.Request) {}).Code)
+	assert.Equal(t, http.StatusOK, sendRequest(router, "POST", "/api/v1/stacks/nginx/deploy", "", withToken(createdToken.Token)).Code)
+
+	recorder := sendRequest(router, "GET", "/api/v1/stacks", "", withToken("ocelot_invalid"))
+	assert.Equal(t, http.StatusUnauthorized, recorder.Code)
+	assert.Equal(t, `Bearer error="invalid_token"`, recorder.Header().Get("WWW-Authenticate"))
+}
+
+func TestReadScopeForbidsChanges(t *testing.T) {
+	router := createSecuredRouter(t)
+	createdToken := createToken(t, router, "read")
+
+	assert.Equal(t, http.StatusOK, sendRequest(router, "GET", "/api/v1/stacks", "", withToken(createdToken.Token)).Code)
+	assert.Equal(t, http.StatusForbidden, sendRequest(router, "POST", "/api/v1/stacks/nginx/deploy", "", withToken(createdToken.Token)).Code)
+}
+
+func TestReadScopeForbidsSecrets(t *testing.T) {
+	router := createSecuredRouter(t)
+	readToken := createToken(t, router, "read")
+	writeToken := createToken(t, router, "write")
+
+	assert.Equal(t, http.StatusForbidden, sendRequest(router, "GET", "/api/v1/stacks/nginx/secrets", "", withToken(readToken.Token)).Code)
+	assert.Equal(t, http.StatusForbidden, sendRequest(router, "GET", "/api/v1/webhooks", "", withToken(readToken.Token)).Code)
+	assert.Equal(t, http.StatusOK, sendRequest(router, "GET", "/api/v1/stacks/nginx/secrets", "", withToken(writeToken.Token)).Code)
+}
+
+func TestTokensCanOnlyBeManagedWithSession(t *testing.T) {
+	router := createSecuredRouter(t)
+	createdToken := createToken(t, router, "write")
+
+	assert.Equal(t, http.StatusUnauthorized, sendRequest(router, "GET", "/api/v1/tokens", "", func(r *http.Request) {}).Code)
+	assert.Equal(t, http.StatusForbidden, sendRequest(router, "GET", "/api/v1/tokens", "", withToken(createdToken.Token)).Code)
+
+	recorder := sendRequest(router, "GET", "/api/v1/tokens", "", withSession)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.False(t, strings.Contains(recorder.Body.String(), createdToken.Token))
+	var tokens []tools.ApiTokenDto
+	assert.Nil(t, json.NewDecoder(recorder.Body).Decode(&tokens))
+	assert.Equal(t, 1, len(tokens))
+	assert.Equal(t, "ci", tokens[0].Name)
+
+	assert.Equal(t, http.StatusNoContent, sendRequest(router, "DELETE", "/api/v1/tokens/1", "", withSession).Code)
+	assert.Equal(t, http.StatusNotFound, sendRequest(router, "DELETE", "/api/v1/tokens/1", "", withSession).Code)
+	assert.Equal(t, http.StatusUnauthorized, sendRequest(router, "GET", "/api/v1/stacks", "", withToken(createdToken.Token)).Code)
+}
+
+func TestTokenLifetimeIsLimited(t *testing.T) {
+	router := createSecuredRouter(t)
+	createWithLifetime := func(days string) *httptest.ResponseRecorder {
+		return sendRequest(router, "POST", "/api/v1/tokens", `{"name": "ci", "scopes": ["read"], "expiresInDays": `+days+`}`, withSession)
+	}
+
+	assert.Equal(t, http.StatusBadRequest, createWithLifetime("3651").Code)
+	assert.Equal(t, http.StatusBadRequest, createWithLifetime("200000").Code)
+	recorder := createWithLifetime("3650")
+	assert.Equal(t, http.StatusCreated, recorder.Code)
+	var createdToken tools.CreatedApiTokenDto
+	assert.Nil(t, json.NewDecoder(recorder.Body).Decode(&createdToken))
+	assert.NotNil(t, createdToken.ExpiresAt)
+}
+
+func TestAuthenticatedUserIsResolved(t *testing.T) {
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "sqlite.db"))
+	assert.Nil(t, err)
+	defer db.Close()
+	router := mux.NewRouter()
+	securityModule := ProvideSecurityModule(router, &tools.GlobalConfig{IsSecurityEnabled: true})
+	securityModule.UseDatabase(db)
+	registerTokenHandlers(router, securityModule)
+	createdToken := createToken(t, router, "read")
+
+	request := httptest.NewRequest("GET", "/", nil)
+	assert.Equal(t, "", securityModule.GetAuthenticatedUser(request))
+	withSession(request)
+	assert.Equal(t, "admin", securityModule.GetAuthenticatedUser(request))
+
+	request = httptest.NewRequest("GET", "/", nil)
+	withToken(createdToken.Token)(request)
+	assert.Equal(t, "token:ci", securityModule.GetAuthenticatedUser(request))
+	withToken("ocelot_invalid")(request)
+	assert.Equal(t, "", securityModule.GetAuthenticatedUser(request))
+}
+
+func TestOnlySessionsAreAdmin(t *testing.T) {
+	isAdmin := func(config *tools.GlobalConfig, modify func(r *http.Request)) bool {
+		var result bool
+		handler := ProvideSecurityModule(mux.NewRouter(), config).ApplyAuthMiddlewares(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			result = IsAdmin(r)
+		}))
+		request := httptest.NewRequest("GET", "/api/v1/stacks", nil)
+		modify(request)
+		handler.ServeHTTP(httptest.NewRecorder(), request)
+		return result
+	}
+
+	assert.True(t, isAdmin(&tools.GlobalConfig{}, func(r *http.Request) {}))
+	assert.True(t, isAdmin(&tools.GlobalConfig{IsSecurityEnabled: true}, withSession))
+	assert.False(t, IsAdmin(httptest.NewRequest("GET", "/api/v1/stacks", nil)))
+}
+
+func TestFailedLoginsAreReportedToListeners(t *testing.T) {
+	router := mux.NewRouter()
+	securityModule := ProvideSecurityModule(router, &tools.GlobalConfig{IsSecurityEnabled: true})
+	var failedUsernames []string
+	securityModule.AddLoginFailureListener(func(username string, remoteAddress string) {
+		failedUsernames = append(failedUsernames, username)
+		assert.NotEqual(t, "", remoteAddress)
+	})
+
+	assert.Equal(t, http.StatusOK, sendRequest(router, "POST", "/api/login", `{"username": "admin", "password": "password"}`, func(r *http.Request) {}).Code)
+	assert.Equal(t, 0, len(failedUsernames))
+	assert.Equal(t, http.StatusUnauthorized, sendRequest(router, "POST", "/api/login", `{"username": "admin", "password": "wrong"}`, func(r *http.Request) {}).Code)
+	assert.Equal(t, 1, len(failedUsernames))
+	assert.Equal(t, "admin", failedUsernames[0])
+}
+
+func TestFailedLoginsAreReportedWithClientIp(t *testing.T) {
+	router := mux.NewRouter()
+	securityModule := ProvideSecurityModule(router, &tools.GlobalConfig{IsSecurityEnabled: true})
+	var remoteAddresses []string
+	securityModule.AddLoginFailureListener(func(username string, remoteAddress string) {
+		remoteAddresses = append(remoteAddresses, remoteAddress)
+	})
+	login := func(r *http.Request) {
+		r.RemoteAddr = "192.0.2.1:4711"
+		r.Header.Set("X-Forwarded-For", "203.0.113.7")
+	}
+
+	assert.Equal(t, http.StatusUnauthorized, sendRequest(router, "POST", "/api/login", `{"username": "admin", "password": "wrong"}`, login).Code)
+	securityModule.UseClientIpResolver(func(r *http.Request) net.IP {
+		return net.ParseIP(r.Header.Get("X-Forwarded-For"))
+	})
+	assert.Equal(t, http.StatusUnauthorized, sendRequest(router, "POST", "/api/login", `{"username": "admin", "password": "wrong"}`, login).Code)
+	assert.Equal(t, []string{"192.0.2.1", "203.0.113.7"}, remoteAddresses)
+}
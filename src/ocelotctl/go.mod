@@ -0,0 +1,25 @@
+module ocelot/ocelotctl
+
+go 1.21.6
+
+require (
+	github.com/ocelot-cloud/shared v0.0.5
+	github.com/spf13/cobra v1.8.0
+	ocelot/backend v0.0.0
+)
+
+require (
+	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/inconshreveable/mousetrap v1.1.0 // indirect
+	github.com/mattn/go-colorable v0.1.13 // indirect
+	github.com/mattn/go-isatty v0.0.19 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	github.com/rs/zerolog v1.33.0 // indirect
+	github.com/spf13/pflag v1.0.5 // indirect
+	github.com/stretchr/testify v1.9.0 // indirect
+	golang.org/x/sys v0.18.0 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
+
+// The DTOs are shared with the backend, so that the client stays in sync with the server.
+replace ocelot/backend => ../cloud/backend
@@ -0,0 +1,243 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/spf13/cobra"
+	"ocelot/ocelotctl/src"
+	"os"
+	"strings"
+)
+
+var outputFormat string
+var loginUser string
+var loginToken string
+var isPasswordReadFromStdin bool
+var shouldCascade bool
+var shouldFollowLogs bool
+var logTail int
+var shouldListBackups bool
+
+var rootCmd = &cobra.Command{
+	Use:   "ocelotctl",
+	Short: "Ocelot Cloud CLI",
+	Long:  "Ocelot Cloud CLI to manage the stacks of an ocelot server via its API.",
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		return getPrinter(cmd).Validate()
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		cmd.Help()
+	},
+}
+
+var loginCmd = &cobra.Command{
+	Use:   "login <url>",
+	Short: "Log in to an ocelot server",
+	Long:  "Creates an API token with the credentials of a user and stores it together with the URL of the server. Alternatively, an existing token can be stored with --token.",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		settings := src.Settings{Url: strings.TrimSuffix(args[0], "/"), Token: loginToken}
+		if settings.Token == "" {
+			password, err := readPassword(cmd)
+			if err != nil {
+				return err
+			}
+			hostname, _ := os.Hostname()
+			if settings.Token, err = src.ProvideClient(settings).Login(loginUser, password, src.GetDefaultTokenName(hostname)); err != nil {
+				return err
+			}
+		}
+
+		settingsPath, err := src.GetSettingsPath()
+		if err != nil {
+			return err
+		}
+		if err = src.SaveSettings(settingsPath, settings); err != nil {
+			return err
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "Logged in to %s, the token is stored in %s\n", settings.Url, settingsPath)
+		return nil
+	},
+}
+
+var stacksCmd = &cobra.Command{
+	Use:   "stacks",
+	Short: "Inspect stacks",
+}
+
+var stacksListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List all stacks with their state",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		client, err := getClient()
+		if err != nil {
+			return err
+		}
+		stacks, err := client.GetStacks()
+		if err != nil {
+			return err
+		}
+		var rows [][]string
+		for _, stack := range stacks {
+			rows = append(rows, []string{stack.Name, stack.State, stack.Template, formatBool(stack.UpdateAvailable)})
+		}
+		return getPrinter(cmd).Print(stacks, []string{"NAME", "STATE", "TEMPLATE", "UPDATE"}, rows)
+	},
+}
+
+var deployCmd = &cobra.Command{
+	Use:   "deploy <stack>",
+	Short: "Deploy a stack and the stacks it depends on",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		client, err := getClient()
+		if err != nil {
+			return err
+		}
+		if err = client.DeployStack(args[0]); err != nil {
+			return err
+		}
+		return getPrinter(cmd).PrintMessage(args[0], "deployed")
+	},
+}
+
+var stopCmd = &cobra.Command{
+	Use:   "stop <stack>",
+	Short: "Stop a stack",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		client, err := getClient()
+		if err != nil {
+			return err
+		}
+		if err = client.StopStack(args[0], shouldCascade); err != nil {
+			return err
+		}
+		return getPrinter(cmd).PrintMessage(args[0], "stopped")
+	},
+}
+
+var logsCmd = &cobra.Command{
+	Use:   "logs <stack>",
+	Short: "Show the container logs of a stack",
+	Long:  "Shows the container logs of a stack as plain text, regardless of the output format.",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		client, err := getClient()
+		if err != nil {
+			return err
+		}
+		return client.StreamStackLogs(args[0], shouldFollowLogs, logTail, cmd.OutOrStdout())
+	},
+}
+
+var backupCmd = &cobra.Command{
+	Use:   "backup <stack>",
+	Short: "Back up the volumes of a stack",
+	Long:  "Backs up the volumes of a stack, a running stack is stopped meanwhile. With --list, the existing backups are shown instead.",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		client, err := getClient()
+		if err != nil {
+			return err
+		}
+		if shouldListBackups {
+			backups, err := client.GetStackBackups(args[0])
+			if err != nil {
+				return err
+			}
+			var rows [][]string
+			for _, backup := range backups {
+				rows = append(rows, []string{backup.Id, backup.Timestamp.Format("2006-01-02 15:04:05"), backup.Reason})
+			}
+			return getPrinter(cmd).Print(backups, []string{"ID", "CREATED", "REASON"}, rows)
+		}
+
+		backup, err := client.BackupStack(args[0])
+		if err != nil {
+			return err
+		}
+		return getPrinter(cmd).PrintMessage(args[0], "backup "+backup.Id+" created")
+	},
+}
+
+var restoreCmd = &cobra.Command{
+	Use:   "restore <stack> <backup>",
+	Short: "Restore the volumes of a stack from a backup",
+	Long:  "Replaces the content of the volumes of a stack by the ones of a backup, a running stack is stopped meanwhile. The backups are listed by 'ocelotctl backup <stack> --list'.",
+	Args:  cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		client, err := getClient()
+		if err != nil {
+			return err
+		}
+		if err = client.RestoreStack(args[0], args[1]); err != nil {
+			return err
+		}
+		return getPrinter(cmd).PrintMessage(args[0], "backup "+args[1]+" restored")
+	},
+}
+
+func main() {
+	rootCmd.Root().CompletionOptions.DisableDefaultCmd = true
+	rootCmd.SilenceUsage = true
+	rootCmd.SilenceErrors = true
+	rootCmd.PersistentFlags().StringVarP(&outputFormat, "output", "o", src.OutputTable, "Output format, 'table' or 'json'")
+
+	loginCmd.Flags().StringVarP(&loginUser, "user", "u", "admin", "Name of the user")
+	loginCmd.Flags().StringVar(&loginToken, "token", "", "Store an existing API token instead of creating one")
+	loginCmd.Flags().BoolVar(&isPasswordReadFromStdin, "password-stdin", false, "Read the password from stdin without prompting")
+	stopCmd.Flags().BoolVar(&shouldCascade, "cascade", false, "Stop the running stacks depending on this stack first")
+	logsCmd.Flags().BoolVarP(&shouldFollowLogs, "follow", "f", false, "Keep streaming new log lines")
+	logsCmd.Flags().IntVarP(&logTail, "tail", "n", 0, "Number of lines to show from the end of the logs, all lines if 0")
+	backupCmd.Flags().BoolVarP(&shouldListBackups, "list", "l", false, "List the backups of the stack instead of creating one")
+
+	stacksCmd.AddCommand(stacksListCmd)
+	rootCmd.AddCommand(loginCmd)
+	rootCmd.AddCommand(stacksCmd)
+	rootCmd.AddCommand(deployCmd)
+	rootCmd.AddCommand(stopCmd)
+	rootCmd.AddCommand(logsCmd)
+	rootCmd.AddCommand(backupCmd)
+	rootCmd.AddCommand(restoreCmd)
+
+	if err := rootCmd.Execute(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s\n", err.Error())
+		os.Exit(1)
+	}
+}
+
+func getClient() (*src.Client, error) {
+	settingsPath, err := src.GetSettingsPath()
+	if err != nil {
+		return nil, err
+	}
+	settings, err := src.LoadSettings(settingsPath)
+	if err != nil {
+		return nil, err
+	}
+	return src.ProvideClient(settings), nil
+}
+
+func getPrinter(cmd *cobra.Command) src.Printer {
+	return src.Printer{Format: outputFormat, Output: cmd.OutOrStdout()}
+}
+
+func readPassword(cmd *cobra.Command) (string, error) {
+	if !isPasswordReadFromStdin {
+		fmt.Fprintf(cmd.OutOrStdout(), "Password for %s: ", loginUser)
+	}
+	password, err := bufio.NewReader(cmd.InOrStdin()).ReadString('\n')
+	if err != nil && password == "" {
+		return "", fmt.Errorf("failed to read the password: %w", err)
+	}
+	return strings.TrimRight(password, "\r\n"), nil
+}
+
+func formatBool(value bool) string {
+	if value {
+		return "yes"
+	}
+	return ""
+}
@@ -0,0 +1,186 @@
+package src
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"ocelot/backend/config"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const apiV1Prefix = "/api/v1"
+
+// Client talks to the versioned API of an ocelot server. All requests except the login are authenticated with an
+// API token, which is sent as bearer token.
+type Client struct {
+	baseUrl    string
+	token      string
+	httpClient *http.Client
+}
+
+func ProvideClient(settings Settings) *Client {
+	return &Client{baseUrl: strings.TrimSuffix(settings.Url, "/"), token: settings.Token, httpClient: &http.Client{}}
+}
+
+// Login creates an API token for this client with the credentials of a user. The session cookie of the login is
+// only used to create the token and is discarded afterward.
+func (c *Client) Login(username string, password string, tokenName string) (string, error) {
+	credentials, err := json.Marshal(map[string]string{"username": username, "password": password})
+	if err != nil {
+		return "", err
+	}
+	response, err := c.httpClient.Post(c.baseUrl+"/api/login", "application/json", bytes.NewReader(credentials))
+	if err != nil {
+		return "", fmt.Errorf("failed to reach the server: %w", err)
+	}
+	response.Body.Close()
+	if response.StatusCode == http.StatusUnauthorized {
+		return "", fmt.Errorf("login failed, wrong username or password")
+	} else if response.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("login failed with status %d", response.StatusCode)
+	}
+
+	request := tools.CreateApiTokenDto{Name: tokenName, Scopes: []string{"read", "write"}}
+	var createdToken tools.CreatedApiTokenDto
+	err = c.send("POST", "/tokens", request, &createdToken, func(r *http.Request) {
+		for _, cookie := range response.Cookies() {
+			r.AddCookie(cookie)
+		}
+	})
+	if err != nil {
+		return "", err
+	}
+	c.token = createdToken.Token
+	return createdToken.Token, nil
+}
+
+func (c *Client) GetStacks() ([]tools.ResponsePayloadDto, error) {
+	var stacks []tools.ResponsePayloadDto
+	err := c.send("GET", "/stacks", nil, &stacks, c.authenticate)
+	return stacks, err
+}
+
+func (c *Client) DeployStack(stackName string) error {
+	return c.send("POST", "/stacks/"+url.PathEscape(stackName)+"/deploy", nil, nil, c.authenticate)
+}
+
+func (c *Client) StopStack(stackName string, shouldCascade bool) error {
+	return c.send("POST", "/stacks/"+url.PathEscape(stackName)+"/stop?cascade="+strconv.FormatBool(shouldCascade), nil, nil, c.authenticate)
+}
+
+func (c *Client) BackupStack(stackName string) (tools.StackBackupDto, error) {
+	var backup tools.StackBackupDto
+	err := c.send("POST", "/stacks/"+url.PathEscape(stackName)+"/backups", nil, &backup, c.authenticate)
+	return backup, err
+}
+
+func (c *Client) GetStackBackups(stackName string) ([]tools.StackBackupDto, error) {
+	var backups []tools.StackBackupDto
+	err := c.send("GET", "/stacks/"+url.PathEscape(stackName)+"/backups", nil, &backups, c.authenticate)
+	return backups, err
+}
+
+func (c *Client) RestoreStack(stackName string, backupId string) error {
+	return c.send("POST", "/stacks/"+url.PathEscape(stackName)+"/backups/"+url.PathEscape(backupId)+"/restore", nil, nil, c.authenticate)
+}
+
+// StreamStackLogs copies the logs of a stack to the output. When following, it only returns when the server closes
+// the connection.
+func (c *Client) StreamStackLogs(stackName string, follow bool, tail int, output io.Writer) error {
+	path := fmt.Sprintf("/stacks/%s/logs?follow=%t", url.PathEscape(stackName), follow)
+	if tail > 0 {
+		path += "&tail=" + strconv.Itoa(tail)
+	}
+	response, err := c.do("GET", path, nil, c.authenticate)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	_, err = io.Copy(output, response.Body)
+	return err
+}
+
+func (c *Client) authenticate(r *http.Request) {
+	r.Header.Set("Authorization", "Bearer "+c.token)
+}
+
+// send encodes the request body as JSON, if there is one, and decodes the response into the result, if given.
+func (c *Client) send(method string, path string, body any, result any, authenticate func(r *http.Request)) error {
+	response, err := c.do(method, path, body, authenticate)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	if result == nil {
+		return nil
+	}
+	if err = json.NewDecoder(response.Body).Decode(result); err != nil {
+		return fmt.Errorf("failed to decode the response of the server: %w", err)
+	}
+	return nil
+}
+
+func (c *Client) do(method string, path string, body any, authenticate func(r *http.Request)) (*http.Response, error) {
+	var requestBody io.Reader
+	if body != nil {
+		encodedBody, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		requestBody = bytes.NewReader(encodedBody)
+	}
+	request, err := http.NewRequest(method, c.baseUrl+apiV1Prefix+path, requestBody)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		request.Header.Set("Content-Type", "application/json")
+	}
+	authenticate(request)
+
+	response, err := c.httpClient.Do(request)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reach the server: %w", err)
+	}
+	if response.StatusCode >= 300 {
+		defer response.Body.Close()
+		return nil, toApiError(response)
+	}
+	return response, nil
+}
+
+// ApiError is an error response of the server.
+type ApiError struct {
+	Status  int
+	Code    string
+	Message string
+}
+
+func (e *ApiError) Error() string {
+	if e.Status == http.StatusUnauthorized {
+		return "not logged in or the API token expired, run 'ocelotctl login' first"
+	}
+	return fmt.Sprintf("%s (%s)", e.Message, e.Code)
+}
+
+func toApiError(response *http.Response) error {
+	var errorDto tools.ErrorDto
+	if err := json.NewDecoder(response.Body).Decode(&errorDto); err != nil || errorDto.Code == "" {
+		errorDto = tools.ErrorDto{Code: "unknown", Message: "request failed with status " + strconv.Itoa(response.StatusCode)}
+	}
+	return &ApiError{Status: response.StatusCode, Code: errorDto.Code, Message: errorDto.Message}
+}
+
+// GetDefaultTokenName names the tokens created by logins, so that they can be told apart in the token list. The
+// host name is shortened, since token names are limited to 64 characters.
+func GetDefaultTokenName(hostname string) string {
+	if len(hostname) > 40 {
+		hostname = hostname[:40]
+	}
+	return "ocelotctl " + hostname + " " + time.Now().Format("2006-01-02")
+}
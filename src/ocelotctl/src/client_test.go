@@ -0,0 +1,80 @@
+package src
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/ocelot-cloud/shared/assert"
+	"net/http"
+	"net/http/httptest"
+	"ocelot/backend/config"
+	"testing"
+)
+
+func createTestServer(t *testing.T) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/api/login":
+			http.SetCookie(w, &http.Cookie{Name: "auth", Value: "valid"})
+		case r.URL.Path == "/api/v1/tokens":
+			if cookie, err := r.Cookie("auth"); err != nil || cookie.Value != "valid" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			w.WriteHeader(http.StatusCreated)
+			json.NewEncoder(w).Encode(tools.CreatedApiTokenDto{Token: "ocelot_secret"})
+		case r.Header.Get("Authorization") != "Bearer ocelot_secret":
+			w.WriteHeader(http.StatusUnauthorized)
+		case r.URL.Path == "/api/v1/stacks":
+			json.NewEncoder(w).Encode([]tools.ResponsePayloadDto{{Name: tools.NginxDefault, State: "Available"}})
+		case r.URL.Path == "/api/v1/stacks/nginx-default/logs":
+			w.Write([]byte("nginx-default-1  | ready\n"))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(tools.ErrorDto{Code: "not_found", Message: "Stack not found: unknown"})
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestLoginCreatesToken(t *testing.T) {
+	client := ProvideClient(Settings{Url: createTestServer(t).URL})
+
+	token, err := client.Login("admin", "password", GetDefaultTokenName("laptop"))
+	assert.Nil(t, err)
+	assert.Equal(t, "ocelot_secret", token)
+
+	stacks, err := client.GetStacks()
+	assert.Nil(t, err)
+	assert.Equal(t, []tools.ResponsePayloadDto{{Name: tools.NginxDefault, State: "Available"}}, stacks)
+}
+
+func TestErrorsOfServerAreReported(t *testing.T) {
+	server := createTestServer(t)
+
+	err := ProvideClient(Settings{Url: server.URL, Token: "ocelot_secret"}).DeployStack("unknown")
+	assert.Equal(t, "Stack not found: unknown (not_found)", err.Error())
+	_, err = ProvideClient(Settings{Url: server.URL, Token: "ocelot_expired"}).GetStacks()
+	assert.Equal(t, http.StatusUnauthorized, err.(*ApiError).Status)
+}
+
+func TestLogsAreCopied(t *testing.T) {
+	client := ProvideClient(Settings{Url: createTestServer(t).URL, Token: "ocelot_secret"})
+
+	var output bytes.Buffer
+	assert.Nil(t, client.StreamStackLogs(tools.NginxDefault, false, 10, &output))
+	assert.Equal(t, "nginx-default-1  | ready\n", output.String())
+}
+
+func TestTableAndJsonOutput(t *testing.T) {
+	var output bytes.Buffer
+	printer := Printer{Format: OutputTable, Output: &output}
+	assert.Nil(t, printer.Print(nil, []string{"NAME", "STATE"}, [][]string{{tools.NginxDefault, "Available"}}))
+	assert.Equal(t, "NAME            STATE\nnginx-default   Available\n", output.String())
+
+	output.Reset()
+	printer.Format = OutputJson
+	assert.Nil(t, printer.Print([]string{tools.NginxDefault}, nil, nil))
+	assert.Equal(t, "[\n  \"nginx-default\"\n]\n", output.String())
+	assert.NotNil(t, Printer{Format: "yaml"}.Validate())
+}
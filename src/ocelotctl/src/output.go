@@ -0,0 +1,53 @@
+package src
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+)
+
+const (
+	OutputTable = "table"
+	OutputJson  = "json"
+)
+
+// Printer writes results either as aligned table for humans or as JSON for scripts. The JSON output is the one of
+// the server, so that it can be processed with the same tools as the API.
+type Printer struct {
+	Format string
+	Output io.Writer
+}
+
+func (p Printer) Validate() error {
+	if p.Format != OutputTable && p.Format != OutputJson {
+		return fmt.Errorf("unknown output format '%s', use '%s' or '%s'", p.Format, OutputTable, OutputJson)
+	}
+	return nil
+}
+
+// Print writes the JSON representation of the result or a table with one row per entry.
+func (p Printer) Print(result any, header []string, rows [][]string) error {
+	if p.Format == OutputJson {
+		encoder := json.NewEncoder(p.Output)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(result)
+	}
+
+	writer := tabwriter.NewWriter(p.Output, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(writer, strings.Join(header, "\t"))
+	for _, row := range rows {
+		fmt.Fprintln(writer, strings.Join(row, "\t"))
+	}
+	return writer.Flush()
+}
+
+// PrintMessage writes a confirmation for humans, which is replaced by a small JSON object for scripts.
+func (p Printer) PrintMessage(stackName string, message string) error {
+	if p.Format == OutputJson {
+		return json.NewEncoder(p.Output).Encode(map[string]string{"stack": stackName, "result": message})
+	}
+	_, err := fmt.Fprintf(p.Output, "%s: %s\n", stackName, message)
+	return err
+}
@@ -0,0 +1,63 @@
+package src
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Settings are stored in the user's config directory, e.g. "~/.config/ocelotctl/settings.json". The file is only
+// readable by the user, since it contains the API token.
+type Settings struct {
+	Url   string `json:"url"`
+	Token string `json:"token"`
+}
+
+// GetSettingsPath can be overridden with the environment variable OCELOTCTL_SETTINGS, e.g. for CI jobs.
+func GetSettingsPath() (string, error) {
+	if path := os.Getenv("OCELOTCTL_SETTINGS"); path != "" {
+		return path, nil
+	}
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(configDir, "ocelotctl", "settings.json"), nil
+}
+
+// LoadSettings reads the stored settings. The environment variables OCELOT_URL and OCELOT_TOKEN take precedence, so
+// that CI pipelines can use the client without logging in.
+func LoadSettings(path string) (Settings, error) {
+	var settings Settings
+	content, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return Settings{}, err
+	} else if err == nil {
+		if err = json.Unmarshal(content, &settings); err != nil {
+			return Settings{}, fmt.Errorf("failed to read settings file '%s': %w", path, err)
+		}
+	}
+
+	if url := os.Getenv("OCELOT_URL"); url != "" {
+		settings.Url = url
+	}
+	if token := os.Getenv("OCELOT_TOKEN"); token != "" {
+		settings.Token = token
+	}
+	if settings.Url == "" || settings.Token == "" {
+		return Settings{}, fmt.Errorf("not logged in, run 'ocelotctl login' first")
+	}
+	return settings, nil
+}
+
+func SaveSettings(path string, settings Settings) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	content, err := json.MarshalIndent(settings, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, content, 0600)
+}